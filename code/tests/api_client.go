@@ -2,9 +2,11 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -15,12 +17,14 @@ import (
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+	adminToken string
 }
 
 func NewAPIClient(cfg config.Config) *Client {
 	return &Client{
 		httpClient: &http.Client{Timeout: 5 * time.Second},
 		baseURL:    normalizeBaseURL(cfg.ListenAddr),
+		adminToken: cfg.Admin.Token,
 	}
 }
 
@@ -36,8 +40,169 @@ func (c *Client) CreateOrder(req handler.CreateOrderRequest) (*http.Response, er
 	return c.post("/api/v1/orders", req)
 }
 
+func (c *Client) AdjustStock(id string, req handler.AdjustStockRequest) (*http.Response, error) {
+	return c.post("/api/v1/products/"+id+"/stock", req)
+}
+
+func (c *Client) UpdateOrderStatus(id string, req handler.UpdateOrderStatusRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/api/v1/orders/"+id+"/status", req, "")
+}
+
+func (c *Client) GetOrder(id string) (*http.Response, error) {
+	return c.get("/api/v1/orders/" + id)
+}
+
+func (c *Client) CreateReturn(orderID string, req handler.CreateReturnRequest) (*http.Response, error) {
+	return c.post("/api/v1/orders/"+orderID+"/returns", req)
+}
+
+func (c *Client) ListReturns(orderID string) (*http.Response, error) {
+	return c.get("/api/v1/orders/" + orderID + "/returns")
+}
+
+func (c *Client) GetReturn(id string) (*http.Response, error) {
+	return c.get("/api/v1/returns/" + id)
+}
+
+func (c *Client) UpdateReturnStatus(id string, req handler.UpdateReturnStatusRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/api/v1/returns/"+id+"/status", req, "")
+}
+
+func (c *Client) SetBackorderPolicy(id string, req handler.BackorderPolicyRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/api/v1/products/"+id+"/backorder-policy", req, "")
+}
+
+func (c *Client) SetReorderPolicy(id string, req handler.ReorderPolicyRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/api/v1/products/"+id+"/reorder-policy", req, "")
+}
+
+func (c *Client) ListLowStockAlerts(status string) (*http.Response, error) {
+	return c.get("/api/v1/alerts/low-stock?status=" + url.QueryEscape(status))
+}
+
+func (c *Client) AcknowledgeLowStockAlert(id string, req handler.AcknowledgeAlertRequest) (*http.Response, error) {
+	return c.post("/api/v1/alerts/low-stock/"+id+"/ack", req)
+}
+
+func (c *Client) CreateSupplier(req handler.SupplierRequest) (*http.Response, error) {
+	return c.post("/api/v1/suppliers", req)
+}
+
+func (c *Client) UpdateSupplier(id string, req handler.SupplierRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/api/v1/suppliers/"+id, req, "")
+}
+
+func (c *Client) GetSupplier(id string) (*http.Response, error) {
+	return c.get("/api/v1/suppliers/" + id)
+}
+
+func (c *Client) CreatePurchaseOrder(req handler.CreatePurchaseOrderRequest) (*http.Response, error) {
+	return c.post("/api/v1/purchase-orders", req)
+}
+
+func (c *Client) GetPurchaseOrder(id string) (*http.Response, error) {
+	return c.get("/api/v1/purchase-orders/" + id)
+}
+
+func (c *Client) ListPurchaseOrders(status string) (*http.Response, error) {
+	return c.get("/api/v1/purchase-orders?status=" + url.QueryEscape(status))
+}
+
+func (c *Client) UpdatePurchaseOrderStatus(id string, req handler.UpdatePurchaseOrderStatusRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/api/v1/purchase-orders/"+id+"/status", req, "")
+}
+
+func (c *Client) ReceivePurchaseOrder(id string, req handler.ReceivePurchaseOrderRequest) (*http.Response, error) {
+	return c.post("/api/v1/purchase-orders/"+id+"/receipts", req)
+}
+
+func (c *Client) ListPurchaseReceipts(id string) (*http.Response, error) {
+	return c.get("/api/v1/purchase-orders/" + id + "/receipts")
+}
+
+func (c *Client) ListReorderSuggestions(windowDays, format string) (*http.Response, error) {
+	q := url.Values{}
+	if windowDays != "" {
+		q.Set("window_days", windowDays)
+	}
+	if format != "" {
+		q.Set("format", format)
+	}
+	return c.get("/api/v1/inventory/reorder-suggestions?" + q.Encode())
+}
+
 func (c *Client) GetProduct(id string) (*http.Response, error) {
-	fullURL := fmt.Sprintf("%s/api/v1/products/%s", c.baseURL, strings.TrimLeft(id, "/"))
+	return c.get(fmt.Sprintf("/api/v1/products/%s", strings.TrimLeft(id, "/")))
+}
+
+func (c *Client) Healthz() (*http.Response, error) {
+	return c.get("/healthz")
+}
+
+func (c *Client) Readyz() (*http.Response, error) {
+	return c.get("/readyz")
+}
+
+func (c *Client) Version() (*http.Response, error) {
+	return c.get("/version")
+}
+
+func (c *Client) SetMaintenance(req handler.MaintenanceRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/admin/maintenance", req, c.adminToken)
+}
+
+func (c *Client) CreateWebhook(req handler.WebhookRequest) (*http.Response, error) {
+	return c.do(http.MethodPost, "/admin/webhooks", req, c.adminToken)
+}
+
+func (c *Client) ListWebhooks() (*http.Response, error) {
+	return c.do(http.MethodGet, "/admin/webhooks", nil, c.adminToken)
+}
+
+func (c *Client) GetWebhook(id string) (*http.Response, error) {
+	return c.do(http.MethodGet, "/admin/webhooks/"+id, nil, c.adminToken)
+}
+
+func (c *Client) UpdateWebhook(id string, req handler.WebhookRequest) (*http.Response, error) {
+	return c.do(http.MethodPut, "/admin/webhooks/"+id, req, c.adminToken)
+}
+
+func (c *Client) DeleteWebhook(id string) (*http.Response, error) {
+	return c.do(http.MethodDelete, "/admin/webhooks/"+id, nil, c.adminToken)
+}
+
+func (c *Client) ListWebhookDeliveries(id string) (*http.Response, error) {
+	return c.do(http.MethodGet, "/admin/webhooks/"+id+"/deliveries", nil, c.adminToken)
+}
+
+func (c *Client) GetWebhookDelivery(id string) (*http.Response, error) {
+	return c.do(http.MethodGet, "/admin/webhook-deliveries/"+id, nil, c.adminToken)
+}
+
+func (c *Client) ReplayWebhookDelivery(id string) (*http.Response, error) {
+	return c.do(http.MethodPost, "/admin/webhook-deliveries/"+id+"/replay", nil, c.adminToken)
+}
+
+// StreamStock opens the stock event stream. It is not bound by the client
+// timeout; cancel ctx to close it.
+func (c *Client) StreamStock(ctx context.Context, productIDs []string, lastEventID string) (*http.Response, error) {
+	u := c.baseURL + "/api/v1/stream/stock?product_ids=" + url.QueryEscape(strings.Join(productIDs, ","))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) get(path string) (*http.Response, error) {
+	fullURL := c.baseURL + path
 	httpReq, err := http.NewRequest(http.MethodGet, fullURL, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("build request: %w", err)
@@ -52,16 +217,23 @@ func (c *Client) GetProduct(id string) (*http.Response, error) {
 }
 
 func (c *Client) post(path string, body any) (*http.Response, error) {
+	return c.do(http.MethodPost, path, body, "")
+}
+
+func (c *Client) do(method, path string, body any, token string) (*http.Response, error) {
 	fullURL := c.baseURL + path
 	data, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("marshal body: %w", err)
 	}
-	req, err := http.NewRequest(http.MethodPost, fullURL, bytes.NewReader(data))
+	req, err := http.NewRequest(method, fullURL, bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -13,8 +13,6 @@ import (
 	"stockpilot/internal/config"
 )
 
-var TestSuite *tests.Suite
-
 func Test_SpecConcurrency(t *testing.T) {
 	cfg := baseCfg(t)
 	cfg.ListenAddr = fmt.Sprintf(":%d", ports.ConcurrencyHTTPPort)
@@ -0,0 +1,102 @@
+package concurrency
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Crossed multi-product orders", Ordered, func() {
+	const (
+		productQuantity = 10
+		totalRequests   = 40
+	)
+
+	var (
+		user     handler.UserResponse
+		products [3]handler.ProductResponse
+	)
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     fmt.Sprintf("crossed-%d@example.com", time.Now().UnixNano()),
+			FirstName: "Crossed",
+			LastName:  "Runner",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		for i := range products {
+			resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{
+				Description: fmt.Sprintf("Crossed product %d", i),
+				Tags:        []string{"load"},
+				Quantity:    productQuantity,
+				Price:       "5.00",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(decodeBody(resp, &products[i])).To(Succeed())
+			resp.Body.Close()
+		}
+	})
+
+	It("neither deadlocks nor oversells when orders lock products in opposite order", func() {
+		a, b, c := products[0].ID, products[1].ID, products[2].ID
+		orders := [][]handler.CreateOrderItemBody{
+			{{ProductID: a, Quantity: 1}, {ProductID: b, Quantity: 1}, {ProductID: c, Quantity: 1}},
+			{{ProductID: c, Quantity: 1}, {ProductID: b, Quantity: 1}, {ProductID: a, Quantity: 1}},
+			{{ProductID: b, Quantity: 1}, {ProductID: a, Quantity: 1}, {ProductID: c, Quantity: 1}},
+			{{ProductID: c, Quantity: 1}, {ProductID: a, Quantity: 1}, {ProductID: b, Quantity: 1}},
+		}
+
+		var successCount, conflictCount atomic.Int32
+		var wg sync.WaitGroup
+		wg.Add(totalRequests)
+		for i := 0; i < totalRequests; i++ {
+			go func(idx int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				resp, err := TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{
+					UserID: user.ID,
+					Items:  orders[idx%len(orders)],
+				})
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+
+				switch resp.StatusCode {
+				case http.StatusCreated:
+					successCount.Add(1)
+				case http.StatusConflict:
+					conflictCount.Add(1)
+				default:
+					Fail(fmt.Sprintf("unexpected status code %d at request %d", resp.StatusCode, idx))
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(int(successCount.Load())).To(Equal(productQuantity))
+		Expect(int(conflictCount.Load())).To(Equal(totalRequests - productQuantity))
+
+		for _, p := range products {
+			resp, err := TestSuite.ApiClient.GetProduct(p.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			var after handler.ProductResponse
+			Expect(decodeBody(resp, &after)).To(Succeed())
+			resp.Body.Close()
+			Expect(after.Quantity).To(BeZero())
+		}
+	})
+})
@@ -0,0 +1,6 @@
+package concurrency
+
+import "stockpilot/code/tests"
+
+// TestSuite is set by the test entry point, in-memory or e2e, before specs run.
+var TestSuite *tests.Suite
@@ -4,16 +4,33 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ApplyMigrations runs a single .sql file or, for a directory, every .sql
+// file in it in name order.
 func ApplyMigrations(ctx context.Context, connString, migrationsPath string) error {
-	sqlData, err := os.ReadFile(migrationsPath)
-	if err != nil {
-		return fmt.Errorf("read migrations: %w", err)
+	files := []string{migrationsPath}
+	if info, err := os.Stat(migrationsPath); err == nil && info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(migrationsPath, "*.sql"))
+		if err != nil {
+			return fmt.Errorf("list migrations: %w", err)
+		}
+		sort.Strings(files)
+	}
+
+	var sqlData []byte
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read migrations: %w", err)
+		}
+		sqlData = append(append(sqlData, data...), ';')
 	}
 
 	migrationCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
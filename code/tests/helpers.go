@@ -64,7 +64,7 @@ func EnsureWithRetry(ensure func() error) error {
 }
 
 func WaitHTTPOKAtAddr(addr string, duration time.Duration) error {
-	target := normalizeBaseURL(addr) + "/api/v1/products/unknown-ready-check"
+	target := normalizeBaseURL(addr) + "/healthz"
 	interval := duration / 20 //nolint:gomnd
 	okChan := make(chan struct{})
 	timer := time.NewTimer(duration)
@@ -74,7 +74,7 @@ func WaitHTTPOKAtAddr(addr string, duration time.Duration) error {
 	go func() {
 		for i := 0; i < 20; i++ {
 			r, err := http.Get(target) //nolint:noctx
-			if err == nil && r.StatusCode < http.StatusInternalServerError {
+			if err == nil && r.StatusCode == http.StatusOK {
 				_ = r.Body.Close()
 				close(okChan)
 				return
@@ -14,8 +14,6 @@ import (
 	"stockpilot/internal/config"
 )
 
-var TestSuite *tests.Suite
-
 func Test_SpecStockpilot(t *testing.T) {
 	cfg := baseCfg(t)
 	cfg.ListenAddr = fmt.Sprintf(":%d", ports.BaseHTTPPort)
@@ -0,0 +1,183 @@
+package mainspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Backorders", Ordered, func() {
+	var (
+		user                 handler.UserResponse
+		product              handler.ProductResponse
+		first, second, third handler.OrderResponse
+	)
+
+	createOrder := func(quantity int) *http.Response {
+		resp, err := TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{
+			UserID: user.ID,
+			Items:  []handler.CreateOrderItemBody{{ProductID: product.ID, Quantity: quantity}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	order := func(quantity int) handler.OrderResponse {
+		resp := createOrder(quantity)
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var o handler.OrderResponse
+		Expect(decodeBody(resp, &o)).To(Succeed())
+		return o
+	}
+
+	getOrder := func(id string) handler.OrderResponse {
+		resp, err := TestSuite.ApiClient.GetOrder(id)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var o handler.OrderResponse
+		Expect(decodeBody(resp, &o)).To(Succeed())
+		return o
+	}
+
+	stock := func() int {
+		resp, err := TestSuite.ApiClient.GetProduct(product.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p.Quantity
+	}
+
+	adjust := func(delta int) {
+		resp, err := TestSuite.ApiClient.AdjustStock(product.ID, handler.AdjustStockRequest{Delta: delta, Reason: "delivery"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     fmt.Sprintf("backorder-%d@example.com", time.Now().UnixNano()),
+			FirstName: "Back",
+			LastName:  "Order",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		resp, err = TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{Description: "Preorder item", Quantity: 2, Price: "4.00"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &product)).To(Succeed())
+		Expect(product.AllowBackorder).To(BeFalse())
+	})
+
+	It("rejects orders beyond stock unless the product allows backorders", func() {
+		resp := createOrder(3)
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		resp, err := TestSuite.ApiClient.SetBackorderPolicy(product.ID, handler.BackorderPolicyRequest{AllowBackorder: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(decodeBody(resp, &product)).To(Succeed())
+		Expect(product.AllowBackorder).To(BeTrue())
+	})
+
+	It("backorders the short part of an order", func() {
+		first = order(3)
+		Expect(first.FulfillmentStatus).To(Equal("backordered"))
+		Expect(first.Items[0].Quantity).To(Equal(3))
+		Expect(first.Items[0].BackorderedQuantity).To(Equal(1))
+		Expect(first.TotalPrice).To(Equal("12.00"))
+		Expect(stock()).To(BeZero())
+
+		second = order(2)
+		Expect(second.Items[0].BackorderedQuantity).To(Equal(2))
+	})
+
+	It("does not ship a backordered order", func() {
+		for _, status := range []string{"paid", "shipped"} {
+			resp, err := TestSuite.ApiClient.UpdateOrderStatus(first.ID, handler.UpdateOrderStatusRequest{Status: status})
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			if status == "paid" {
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			} else {
+				Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+			}
+		}
+	})
+
+	It("fills backorders first-in-first-out from incoming stock", func() {
+		adjust(2)
+		Expect(stock()).To(BeZero())
+		Expect(getOrder(first.ID).FulfillmentStatus).To(Equal("fulfilled"))
+		o := getOrder(second.ID)
+		Expect(o.FulfillmentStatus).To(Equal("backordered"))
+		Expect(o.Items[0].BackorderedQuantity).To(Equal(1))
+
+		resp, err := TestSuite.ApiClient.UpdateOrderStatus(first.ID, handler.UpdateOrderStatusRequest{Status: "shipped"})
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("cancels backorders with their order and restocks only what was allocated", func() {
+		third = order(1)
+		Expect(third.Items[0].BackorderedQuantity).To(Equal(1))
+
+		resp, err := TestSuite.ApiClient.UpdateOrderStatus(second.ID, handler.UpdateOrderStatusRequest{Status: "cancelled"})
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		// The one allocated unit of the cancelled order goes to the next backorder.
+		Expect(stock()).To(BeZero())
+		Expect(getOrder(second.ID).FulfillmentStatus).To(Equal("fulfilled"))
+		Expect(getOrder(third.ID).FulfillmentStatus).To(Equal("fulfilled"))
+
+		adjust(1)
+		Expect(stock()).To(Equal(1))
+	})
+
+	It("records order.fulfilled for orders whose backorders were filled", func() {
+		if TestSuite.Repo == nil {
+			Skip("outbox events are only visible with the in-memory repository")
+		}
+		var fulfilled []string
+		for _, e := range TestSuite.Repo.Events() {
+			if e.Type == domain.EventOrderFulfilled {
+				var payload domain.OrderFulfilledEvent
+				Expect(json.Unmarshal(e.Payload, &payload)).To(Succeed())
+				Expect(payload.OrderID).To(Equal(e.AggregateID))
+				fulfilled = append(fulfilled, payload.OrderID)
+			}
+		}
+		// The cancelled order's backorder was cancelled, not filled.
+		Expect(fulfilled).To(ContainElements(first.ID, third.ID))
+		Expect(fulfilled).NotTo(ContainElement(second.ID))
+	})
+
+	It("returns 404 for an unknown order", func() {
+		resp, err := TestSuite.ApiClient.GetOrder("00000000-0000-0000-0000-000000000000")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,47 @@
+package mainspec
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Service health", func() {
+	It("reports liveness", func() {
+		resp, err := TestSuite.ApiClient.Healthz()
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var health handler.HealthResponse
+		Expect(decodeBody(resp, &health)).To(Succeed())
+		Expect(health.Status).To(Equal("ok"))
+	})
+
+	It("reports readiness with database state", func() {
+		resp, err := TestSuite.ApiClient.Readyz()
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var ready handler.ReadinessResponse
+		Expect(decodeBody(resp, &ready)).To(Succeed())
+		Expect(ready.Status).To(Equal("ready"))
+		Expect(ready.Database).To(Equal("ok"))
+		Expect(ready.Locked).To(BeFalse())
+		Expect(ready.ShuttingDown).To(BeFalse())
+	})
+
+	It("returns build info", func() {
+		resp, err := TestSuite.ApiClient.Version()
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var info handler.BuildInfo
+		Expect(decodeBody(resp, &info)).To(Succeed())
+	})
+})
@@ -0,0 +1,181 @@
+package mainspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Low-stock alerts", Ordered, func() {
+	var (
+		product handler.ProductResponse
+		alert   handler.LowStockAlertResponse
+	)
+
+	createProduct := func(quantity, reorderPoint, safetyStock int) handler.ProductResponse {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{
+			Description:  "Reordered product",
+			Quantity:     quantity,
+			Price:        "2.00",
+			ReorderPoint: reorderPoint,
+			SafetyStock:  safetyStock,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p
+	}
+
+	adjust := func(id string, delta int) {
+		resp, err := TestSuite.ApiClient.AdjustStock(id, handler.AdjustStockRequest{Delta: delta, Reason: "alerts"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	// alertsFor returns the alerts of one product, since other specs may
+	// leave alerts behind.
+	alertsFor := func(productID, status string) []handler.LowStockAlertResponse {
+		resp, err := TestSuite.ApiClient.ListLowStockAlerts(status)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var all []handler.LowStockAlertResponse
+		Expect(decodeBody(resp, &all)).To(Succeed())
+		var result []handler.LowStockAlertResponse
+		for _, a := range all {
+			if a.ProductID == productID {
+				result = append(result, a)
+			}
+		}
+		return result
+	}
+
+	acknowledge := func(id string) *http.Response {
+		resp, err := TestSuite.ApiClient.AcknowledgeLowStockAlert(id, handler.AcknowledgeAlertRequest{AcknowledgedBy: "buyer@example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		return resp
+	}
+
+	BeforeAll(func() {
+		product = createProduct(10, 5, 2)
+		Expect(product.ReorderPoint).To(Equal(5))
+		Expect(product.SafetyStock).To(Equal(2))
+	})
+
+	It("rejects invalid reorder policies", func() {
+		resp, err := TestSuite.ApiClient.SetReorderPolicy(product.ID, handler.ReorderPolicyRequest{ReorderPoint: 2, SafetyStock: 3})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err = TestSuite.ApiClient.SetReorderPolicy("00000000-0000-0000-0000-000000000000", handler.ReorderPolicyRequest{ReorderPoint: 2})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("does not alert when stock dips and recovers within the debounce window", func() {
+		flapping := createProduct(10, 5, 0)
+		adjust(flapping.ID, -8)
+		adjust(flapping.ID, 8)
+		Consistently(func() []handler.LowStockAlertResponse {
+			return alertsFor(flapping.ID, "all")
+		}).WithTimeout(2 * time.Second).Should(BeEmpty())
+	})
+
+	It("raises one alert when stock falls below the reorder point", func() {
+		adjust(product.ID, -4)
+		adjust(product.ID, -3)
+		Eventually(func() []handler.LowStockAlertResponse {
+			return alertsFor(product.ID, "open")
+		}).WithTimeout(5 * time.Second).Should(HaveLen(1))
+
+		alert = alertsFor(product.ID, "open")[0]
+		Expect(alert.Severity).To(Equal("low"))
+		Expect(alert.Quantity).To(Equal(3))
+		Expect(alert.ReorderPoint).To(Equal(5))
+		Expect(alert.AcknowledgedAt).To(BeNil())
+
+		adjust(product.ID, -1)
+		Consistently(func() []handler.LowStockAlertResponse {
+			return alertsFor(product.ID, "all")
+		}).WithTimeout(2 * time.Second).Should(HaveLen(1))
+
+		if TestSuite.Alerts != nil {
+			Expect(TestSuite.Alerts.Alerts()).To(ContainElement(HaveField("ID", alert.ID)))
+		}
+	})
+
+	It("records raised alerts in the outbox", func() {
+		if TestSuite.Repo == nil {
+			Skip("outbox events are only visible with the in-memory repository")
+		}
+		var raised []domain.StockLowEvent
+		for _, e := range TestSuite.Repo.Events() {
+			if e.Type == domain.EventStockLow && e.AggregateID == product.ID {
+				var payload domain.StockLowEvent
+				Expect(json.Unmarshal(e.Payload, &payload)).To(Succeed())
+				raised = append(raised, payload)
+			}
+		}
+		Expect(raised).To(HaveLen(1))
+		Expect(raised[0].AlertID).To(Equal(alert.ID))
+		Expect(raised[0].Severity).To(Equal(domain.AlertLow))
+		Expect(raised[0].Quantity).To(Equal(3))
+	})
+
+	It("acknowledges an alert once", func() {
+		resp := acknowledge(alert.ID)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var acked handler.LowStockAlertResponse
+		Expect(decodeBody(resp, &acked)).To(Succeed())
+		Expect(acked.AcknowledgedAt).NotTo(BeNil())
+		Expect(acked.AcknowledgedBy).To(Equal("buyer@example.com"))
+		Expect(alertsFor(product.ID, "open")).To(BeEmpty())
+
+		Expect(acknowledge(alert.ID).StatusCode).To(Equal(http.StatusConflict))
+		Expect(acknowledge("00000000-0000-0000-0000-000000000000").StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("does not alert again while stock stays below the reorder point", func() {
+		adjust(product.ID, -1)
+		Consistently(func() []handler.LowStockAlertResponse {
+			return alertsFor(product.ID, "open")
+		}).WithTimeout(2 * time.Second).Should(BeEmpty())
+	})
+
+	It("alerts again after stock recovers and falls, as critical at safety stock", func() {
+		adjust(product.ID, 6)
+		Consistently(func() []handler.LowStockAlertResponse {
+			return alertsFor(product.ID, "open")
+		}).WithTimeout(2 * time.Second).Should(BeEmpty())
+
+		adjust(product.ID, -6)
+		Eventually(func() []handler.LowStockAlertResponse {
+			return alertsFor(product.ID, "open")
+		}).WithTimeout(5 * time.Second).Should(HaveLen(1))
+
+		open := alertsFor(product.ID, "open")[0]
+		Expect(open.ID).NotTo(Equal(alert.ID))
+		Expect(open.Severity).To(Equal("critical"))
+		Expect(open.Quantity).To(Equal(1))
+		Expect(alertsFor(product.ID, "all")).To(HaveLen(2))
+	})
+
+	It("rejects unknown statuses", func() {
+		resp, err := TestSuite.ApiClient.ListLowStockAlerts("closed")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,72 @@
+package mainspec
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Maintenance mode", Ordered, func() {
+	var productID string
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{
+			Description: "Maintenance product",
+			Quantity:    1,
+			Price:       "1.00",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		var product handler.ProductResponse
+		Expect(decodeBody(resp, &product)).To(Succeed())
+		productID = product.ID
+
+		resp, err = TestSuite.ApiClient.SetMaintenance(handler.MaintenanceRequest{Locked: true, Reason: "migration"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	AfterAll(func() {
+		resp, err := TestSuite.ApiClient.SetMaintenance(handler.MaintenanceRequest{Locked: false})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("rejects writes with 503 and Retry-After", func() {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{
+			Description: "Rejected product",
+			Quantity:    1,
+			Price:       "1.00",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("5"))
+	})
+
+	It("keeps serving reads", func() {
+		resp, err := TestSuite.ApiClient.GetProduct(productID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("reports the lock in readiness", func() {
+		resp, err := TestSuite.ApiClient.Readyz()
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		var ready handler.ReadinessResponse
+		Expect(decodeBody(resp, &ready)).To(Succeed())
+		Expect(ready.Locked).To(BeTrue())
+	})
+})
@@ -0,0 +1,147 @@
+package mainspec
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Order lifecycle and stock adjustments", Ordered, func() {
+	var (
+		user    handler.UserResponse
+		product handler.ProductResponse
+		order   handler.OrderResponse
+	)
+
+	getQuantity := func() int {
+		resp, err := TestSuite.ApiClient.GetProduct(product.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p.Quantity
+	}
+
+	setStatus := func(status string) *http.Response {
+		resp, err := TestSuite.ApiClient.UpdateOrderStatus(order.ID, handler.UpdateOrderStatusRequest{Status: status})
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     fmt.Sprintf("lifecycle-%d@example.com", time.Now().UnixNano()),
+			FirstName: "Life",
+			LastName:  "Cycle",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		resp, err = TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{
+			Description: "Lifecycle product",
+			Quantity:    5,
+			Price:       "2.50",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &product)).To(Succeed())
+	})
+
+	It("adjusts stock up and down but never below zero", func() {
+		resp, err := TestSuite.ApiClient.AdjustStock(product.ID, handler.AdjustStockRequest{Delta: 5, Reason: "delivery"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var adjusted handler.ProductResponse
+		Expect(decodeBody(resp, &adjusted)).To(Succeed())
+		Expect(adjusted.Quantity).To(Equal(10))
+
+		resp, err = TestSuite.ApiClient.AdjustStock(product.ID, handler.AdjustStockRequest{Delta: -11, Reason: "stocktake"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		resp, err = TestSuite.ApiClient.AdjustStock(product.ID, handler.AdjustStockRequest{Delta: 0})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		Expect(getQuantity()).To(Equal(10))
+	})
+
+	It("creates an order in the created status", func() {
+		resp, err := TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{
+			UserID: user.ID,
+			Items:  []handler.CreateOrderItemBody{{ProductID: product.ID, Quantity: 4}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &order)).To(Succeed())
+		Expect(order.Status).To(Equal("created"))
+		Expect(getQuantity()).To(Equal(6))
+	})
+
+	It("rejects unknown statuses and invalid transitions", func() {
+		resp := setStatus("lost")
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp = setStatus("delivered")
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		resp, err := TestSuite.ApiClient.UpdateOrderStatus("00000000-0000-0000-0000-000000000000", handler.UpdateOrderStatusRequest{Status: "paid"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns items to stock when a paid order is cancelled", func() {
+		resp := setStatus("paid")
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var paid handler.OrderResponse
+		Expect(decodeBody(resp, &paid)).To(Succeed())
+		Expect(paid.Status).To(Equal("paid"))
+
+		resp = setStatus("cancelled")
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(getQuantity()).To(Equal(10))
+
+		resp = setStatus("paid")
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("records every change in the outbox", func() {
+		if TestSuite.Repo == nil {
+			Skip("outbox events are only visible with the in-memory repository")
+		}
+		var types []string
+		for _, e := range TestSuite.Repo.Events() {
+			if e.AggregateID == product.ID || e.AggregateID == order.ID {
+				types = append(types, e.Type)
+			}
+		}
+		Expect(types).To(Equal([]string{
+			domain.EventStockAdjusted,
+			domain.EventOrderCreated,
+			domain.EventOrderStatusChanged,
+			domain.EventOrderStatusChanged,
+		}))
+	})
+})
@@ -0,0 +1,104 @@
+package mainspec
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Partial order acceptance", Ordered, func() {
+	var (
+		user             handler.UserResponse
+		plenty, few, out handler.ProductResponse
+	)
+
+	createProduct := func(description string, quantity int, price string) handler.ProductResponse {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{Description: description, Quantity: quantity, Price: price})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p
+	}
+
+	createOrder := func(policy string, items ...handler.CreateOrderItemBody) *http.Response {
+		resp, err := TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{UserID: user.ID, Items: items, FulfillmentPolicy: policy})
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	stock := func(id string) int {
+		resp, err := TestSuite.ApiClient.GetProduct(id)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p.Quantity
+	}
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     fmt.Sprintf("partial-%d@example.com", time.Now().UnixNano()),
+			FirstName: "Part",
+			LastName:  "Ial",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		plenty = createProduct("Plenty", 10, "2.00")
+		few = createProduct("Few", 2, "5.00")
+		out = createProduct("Out", 0, "9.00")
+	})
+
+	It("rejects the whole order by default", func() {
+		resp := createOrder("", handler.CreateOrderItemBody{ProductID: plenty.ID, Quantity: 1}, handler.CreateOrderItemBody{ProductID: few.ID, Quantity: 3})
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+		Expect(stock(plenty.ID)).To(Equal(10))
+	})
+
+	It("accepts what is in stock and drops unavailable lines", func() {
+		resp := createOrder("partial",
+			handler.CreateOrderItemBody{ProductID: plenty.ID, Quantity: 3},
+			handler.CreateOrderItemBody{ProductID: few.ID, Quantity: 5},
+			handler.CreateOrderItemBody{ProductID: out.ID, Quantity: 1},
+		)
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var order handler.OrderResponse
+		Expect(decodeBody(resp, &order)).To(Succeed())
+
+		Expect(order.Lines).To(HaveExactElements(
+			handler.OrderLineResponse{ProductID: plenty.ID, RequestedQuantity: 3, AcceptedQuantity: 3},
+			handler.OrderLineResponse{ProductID: few.ID, RequestedQuantity: 5, AcceptedQuantity: 2},
+			handler.OrderLineResponse{ProductID: out.ID, RequestedQuantity: 1, AcceptedQuantity: 0},
+		))
+		Expect(order.Items).To(HaveLen(2))
+		Expect(order.TotalPrice).To(Equal("16.00"))
+		Expect(order.FulfillmentStatus).To(Equal("fulfilled"))
+		Expect(stock(plenty.ID)).To(Equal(7))
+		Expect(stock(few.ID)).To(BeZero())
+	})
+
+	It("fails when nothing is available", func() {
+		resp := createOrder("partial", handler.CreateOrderItemBody{ProductID: out.ID, Quantity: 1})
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("rejects an unknown policy", func() {
+		resp := createOrder("best_effort", handler.CreateOrderItemBody{ProductID: plenty.ID, Quantity: 1})
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
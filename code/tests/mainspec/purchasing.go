@@ -0,0 +1,191 @@
+package mainspec
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Purchasing", Ordered, func() {
+	var (
+		supplier handler.SupplierResponse
+		widget   handler.ProductResponse
+		gadget   handler.ProductResponse
+		po       handler.PurchaseOrderResponse
+	)
+
+	const missingID = "00000000-0000-0000-0000-000000000000"
+
+	createProduct := func(description string, quantity int) handler.ProductResponse {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{Description: description, Quantity: quantity, Price: "4.00"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p
+	}
+
+	quantityOf := func(id string) int {
+		resp, err := TestSuite.ApiClient.GetProduct(id)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p.Quantity
+	}
+
+	receive := func(lines ...handler.PurchaseOrderLineBody) *http.Response {
+		resp, err := TestSuite.ApiClient.ReceivePurchaseOrder(po.ID, handler.ReceivePurchaseOrderRequest{ReceivedBy: "dock-1", Lines: lines})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		return resp
+	}
+
+	BeforeAll(func() {
+		widget = createProduct("Purchased widget", 1)
+		gadget = createProduct("Purchased gadget", 0)
+	})
+
+	It("manages suppliers", func() {
+		resp, err := TestSuite.ApiClient.CreateSupplier(handler.SupplierRequest{Email: "sales@acme.example"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err = TestSuite.ApiClient.CreateSupplier(handler.SupplierRequest{
+			Name:         "Acme Supplies",
+			ContactName:  "Jane Roe",
+			Email:        "sales@acme.example",
+			Phone:        "+1 555 0100",
+			LeadTimeDays: 7,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &supplier)).To(Succeed())
+		Expect(supplier.LeadTimeDays).To(Equal(7))
+
+		resp, err = TestSuite.ApiClient.UpdateSupplier(supplier.ID, handler.SupplierRequest{Name: "Acme Supplies", Email: "sales@acme.example", LeadTimeDays: 10})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, err = TestSuite.ApiClient.GetSupplier(supplier.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(decodeBody(resp, &supplier)).To(Succeed())
+		Expect(supplier.LeadTimeDays).To(Equal(10))
+		Expect(supplier.ContactName).To(BeEmpty())
+
+		resp, err = TestSuite.ApiClient.GetSupplier(missingID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("rejects invalid purchase orders", func() {
+		for req, status := range map[*handler.CreatePurchaseOrderRequest]int{
+			{SupplierID: missingID, Lines: []handler.PurchaseOrderLineBody{{ProductID: widget.ID, Quantity: 1}}}:                                        http.StatusNotFound,
+			{SupplierID: supplier.ID, Lines: []handler.PurchaseOrderLineBody{{ProductID: missingID, Quantity: 1}}}:                                      http.StatusNotFound,
+			{SupplierID: supplier.ID, Lines: []handler.PurchaseOrderLineBody{{ProductID: widget.ID, Quantity: 1}, {ProductID: widget.ID, Quantity: 2}}}: http.StatusBadRequest,
+			{SupplierID: supplier.ID, ExpectedDate: "next week", Lines: []handler.PurchaseOrderLineBody{{ProductID: widget.ID, Quantity: 1}}}:           http.StatusBadRequest,
+			{SupplierID: supplier.ID}: http.StatusBadRequest,
+		} {
+			resp, err := TestSuite.ApiClient.CreatePurchaseOrder(*req)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(status), "%+v", *req)
+		}
+	})
+
+	It("creates a draft purchase order", func() {
+		resp, err := TestSuite.ApiClient.CreatePurchaseOrder(handler.CreatePurchaseOrderRequest{
+			SupplierID:   supplier.ID,
+			ExpectedDate: "2030-01-15",
+			Lines: []handler.PurchaseOrderLineBody{
+				{ProductID: widget.ID, Quantity: 5},
+				{ProductID: gadget.ID, Quantity: 3},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &po)).To(Succeed())
+		Expect(po.Status).To(Equal("draft"))
+		Expect(po.ExpectedDate).To(Equal("2030-01-15"))
+		Expect(po.Lines).To(HaveLen(2))
+	})
+
+	It("receives only sent purchase orders", func() {
+		Expect(receive(handler.PurchaseOrderLineBody{ProductID: widget.ID, Quantity: 1}).StatusCode).To(Equal(http.StatusConflict))
+
+		resp, err := TestSuite.ApiClient.UpdatePurchaseOrderStatus(po.ID, handler.UpdatePurchaseOrderStatusRequest{Status: "received"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		resp, err = TestSuite.ApiClient.UpdatePurchaseOrderStatus(po.ID, handler.UpdatePurchaseOrderStatusRequest{Status: "sent"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(decodeBody(resp, &po)).To(Succeed())
+		Expect(po.Status).To(Equal("sent"))
+	})
+
+	It("receives part of the order into stock", func() {
+		resp := receive(handler.PurchaseOrderLineBody{ProductID: widget.ID, Quantity: 2})
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var received handler.ReceivePurchaseOrderResponse
+		Expect(decodeBody(resp, &received)).To(Succeed())
+		Expect(received.Receipt.ReceivedBy).To(Equal("dock-1"))
+		Expect(received.Receipt.Lines).To(ConsistOf(handler.PurchaseOrderLineBody{ProductID: widget.ID, Quantity: 2}))
+		Expect(received.PurchaseOrder.Status).To(Equal("partially_received"))
+		Expect(quantityOf(widget.ID)).To(Equal(3))
+
+		Expect(receive(handler.PurchaseOrderLineBody{ProductID: widget.ID, Quantity: 4}).StatusCode).To(Equal(http.StatusConflict))
+		Expect(receive(handler.PurchaseOrderLineBody{ProductID: missingID, Quantity: 1}).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(quantityOf(widget.ID)).To(Equal(3))
+	})
+
+	It("is received once every line is", func() {
+		resp := receive(
+			handler.PurchaseOrderLineBody{ProductID: widget.ID, Quantity: 3},
+			handler.PurchaseOrderLineBody{ProductID: gadget.ID, Quantity: 3},
+		)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var received handler.ReceivePurchaseOrderResponse
+		Expect(decodeBody(resp, &received)).To(Succeed())
+		Expect(received.PurchaseOrder.Status).To(Equal("received"))
+		for _, l := range received.PurchaseOrder.Lines {
+			Expect(l.ReceivedQuantity).To(Equal(l.Quantity))
+		}
+		Expect(quantityOf(widget.ID)).To(Equal(6))
+		Expect(quantityOf(gadget.ID)).To(Equal(3))
+
+		Expect(receive(handler.PurchaseOrderLineBody{ProductID: gadget.ID, Quantity: 1}).StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("keeps a log of receipts", func() {
+		resp, err := TestSuite.ApiClient.ListPurchaseReceipts(po.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var receipts []handler.PurchaseReceiptResponse
+		Expect(decodeBody(resp, &receipts)).To(Succeed())
+		Expect(receipts).To(HaveLen(2))
+		Expect(receipts[0].Lines).To(HaveLen(1))
+		Expect(receipts[1].Lines).To(HaveLen(2))
+		Expect(receipts[1].ReceivedAt).NotTo(BeTemporally("<", receipts[0].ReceivedAt))
+
+		resp, err = TestSuite.ApiClient.ListPurchaseOrders("received")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var orders []handler.PurchaseOrderResponse
+		Expect(decodeBody(resp, &orders)).To(Succeed())
+		Expect(orders).To(ContainElement(HaveField("ID", po.ID)))
+	})
+})
@@ -0,0 +1,186 @@
+package mainspec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Reorder suggestions", Ordered, func() {
+	var (
+		user               handler.UserResponse
+		fast, empty, still handler.ProductResponse
+	)
+
+	createProduct := func(description string, quantity int) handler.ProductResponse {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{Description: description, Quantity: quantity, Price: "1.00"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p
+	}
+
+	order := func(productID string, quantity int) handler.OrderResponse {
+		resp, err := TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{
+			UserID: user.ID,
+			Items:  []handler.CreateOrderItemBody{{ProductID: productID, Quantity: quantity}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var o handler.OrderResponse
+		Expect(decodeBody(resp, &o)).To(Succeed())
+		return o
+	}
+
+	// suggestions returns this spec's products only; the catalog is shared.
+	suggestions := func() map[string]handler.ReorderSuggestionResponse {
+		resp, err := TestSuite.ApiClient.ListReorderSuggestions("7", "")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var list []handler.ReorderSuggestionResponse
+		Expect(decodeBody(resp, &list)).To(Succeed())
+		result := map[string]handler.ReorderSuggestionResponse{}
+		for _, s := range list {
+			if s.ProductID == fast.ID || s.ProductID == empty.ID || s.ProductID == still.ID {
+				result[s.ProductID] = s
+			}
+		}
+		return result
+	}
+
+	inDays := func(days int) string {
+		return time.Now().UTC().AddDate(0, 0, days).Format("2006-01-02")
+	}
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     fmt.Sprintf("reorder-%d@example.com", time.Now().UnixNano()),
+			FirstName: "Re",
+			LastName:  "Order",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		fast = createProduct("Fast mover", 30)
+		empty = createProduct("Empty shelf", 0)
+		still = createProduct("Shelf warmer", 100)
+
+		resp, err = TestSuite.ApiClient.SetReorderPolicy(empty.ID, handler.ReorderPolicyRequest{ReorderPoint: 5, SafetyStock: 2})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		order(fast.ID, 10)
+		order(fast.ID, 4)
+		cancelled := order(fast.ID, 6)
+		resp, err = TestSuite.ApiClient.UpdateOrderStatus(cancelled.ID, handler.UpdateOrderStatusRequest{Status: "cancelled"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("suggests products running out within the cover period", func() {
+		s := suggestions()
+		Expect(s).To(HaveLen(2))
+		Expect(s).NotTo(HaveKey(still.ID))
+
+		// 14 units in 7 days: 2 a day, 14 over the default 7 day lead time.
+		Expect(s[fast.ID].Quantity).To(Equal(16))
+		Expect(s[fast.ID].UnitsSold).To(Equal(14))
+		Expect(s[fast.ID].DailyVelocity).To(Equal(2.0))
+		Expect(s[fast.ID].LeadTimeDays).To(Equal(7))
+		Expect(s[fast.ID].ReorderLevel).To(Equal(14))
+		Expect(*s[fast.ID].DaysOfCover).To(Equal(8.0))
+		Expect(s[fast.ID].ReorderBy).To(Equal(inDays(1)))
+		Expect(s[fast.ID].SuggestedQuantity).To(Equal(14))
+
+		// Nothing sold, but below the reorder point.
+		Expect(s[empty.ID].DaysOfCover).To(BeNil())
+		Expect(s[empty.ID].ReorderLevel).To(Equal(5))
+		Expect(s[empty.ID].ReorderBy).To(Equal(inDays(0)))
+		Expect(s[empty.ID].SuggestedQuantity).To(Equal(5))
+	})
+
+	It("counts stock on order and uses the supplier's lead time", func() {
+		resp, err := TestSuite.ApiClient.CreateSupplier(handler.SupplierRequest{Name: "Quick Parts", LeadTimeDays: 5})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var supplier handler.SupplierResponse
+		Expect(decodeBody(resp, &supplier)).To(Succeed())
+
+		resp, err = TestSuite.ApiClient.CreatePurchaseOrder(handler.CreatePurchaseOrderRequest{
+			SupplierID: supplier.ID,
+			Lines: []handler.PurchaseOrderLineBody{
+				{ProductID: fast.ID, Quantity: 10},
+				{ProductID: empty.ID, Quantity: 2},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		s := suggestions()
+		Expect(s).To(HaveLen(1))
+		Expect(s[empty.ID].OnOrder).To(Equal(2))
+		Expect(s[empty.ID].SupplierName).To(Equal("Quick Parts"))
+		Expect(s[empty.ID].LeadTimeDays).To(Equal(5))
+		Expect(s[empty.ID].SuggestedQuantity).To(Equal(3))
+	})
+
+	It("exports suggestions as CSV", func() {
+		resp, err := TestSuite.ApiClient.ListReorderSuggestions("7", "csv")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/csv"))
+		Expect(resp.Header.Get("Content-Disposition")).To(ContainSubstring("reorder-suggestions.csv"))
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records[0]).To(HaveExactElements("product_id", "description", "supplier_id", "supplier_name", "quantity", "on_order",
+			"units_sold", "daily_velocity", "lead_time_days", "days_of_cover", "reorder_level", "reorder_by", "suggested_quantity"))
+		Expect(records).To(ContainElement(HaveExactElements(empty.ID, "Empty shelf", Not(BeEmpty()), "Quick Parts",
+			"0", "2", "0", "0.00", "5", "", "5", inDays(0), "3")))
+	})
+
+	It("escapes formula-like cells in the CSV export", func() {
+		formula := createProduct("=HYPERLINK(\"http://evil.example\")", 0)
+		resp, err := TestSuite.ApiClient.SetReorderPolicy(formula.ID, handler.ReorderPolicyRequest{ReorderPoint: 5})
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, err = TestSuite.ApiClient.ListReorderSuggestions("7", "csv")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(ContainElement(HaveExactElements(formula.ID, "'=HYPERLINK(\"http://evil.example\")", "", "",
+			"0", "0", "0", "0.00", "7", "", "5", inDays(0), "5")))
+	})
+
+	It("rejects invalid parameters", func() {
+		for _, params := range [][2]string{{"-1", ""}, {"366", ""}, {"week", ""}, {"7", "xlsx"}} {
+			resp, err := TestSuite.ApiClient.ListReorderSuggestions(params[0], params[1])
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), "%v", params)
+		}
+	})
+})
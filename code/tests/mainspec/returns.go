@@ -0,0 +1,242 @@
+package mainspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Returns", Ordered, func() {
+	var (
+		user          handler.UserResponse
+		mug, plate    handler.ProductResponse
+		order         handler.OrderResponse
+		mugItem       handler.OrderItemResponse
+		plateItem     handler.OrderItemResponse
+		first, second handler.ReturnResponse
+	)
+
+	createProduct := func(description string, price string) handler.ProductResponse {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{Description: description, Quantity: 10, Price: price})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p
+	}
+
+	setOrderStatus := func(status string) {
+		resp, err := TestSuite.ApiClient.UpdateOrderStatus(order.ID, handler.UpdateOrderStatusRequest{Status: status})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	createReturn := func(lines ...handler.ReturnLineBody) *http.Response {
+		resp, err := TestSuite.ApiClient.CreateReturn(order.ID, handler.CreateReturnRequest{Reason: "wrong colour", Lines: lines})
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	setReturnStatus := func(id, status string) (int, handler.ReturnResponse) {
+		resp, err := TestSuite.ApiClient.UpdateReturnStatus(id, handler.UpdateReturnStatusRequest{Status: status})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var ret handler.ReturnResponse
+		if resp.StatusCode == http.StatusOK {
+			Expect(decodeBody(resp, &ret)).To(Succeed())
+		}
+		return resp.StatusCode, ret
+	}
+
+	stock := func(id string) int {
+		resp, err := TestSuite.ApiClient.GetProduct(id)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var p handler.ProductResponse
+		Expect(decodeBody(resp, &p)).To(Succeed())
+		return p.Quantity
+	}
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     fmt.Sprintf("returns-%d@example.com", time.Now().UnixNano()),
+			FirstName: "Re",
+			LastName:  "Turn",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		mug = createProduct("Mug", "6.50")
+		plate = createProduct("Plate", "4.00")
+
+		resp, err = TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{
+			UserID: user.ID,
+			Items: []handler.CreateOrderItemBody{
+				{ProductID: mug.ID, Quantity: 3},
+				{ProductID: plate.ID, Quantity: 2},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &order)).To(Succeed())
+		for _, item := range order.Items {
+			if item.ProductID == mug.ID {
+				mugItem = item
+			} else {
+				plateItem = item
+			}
+		}
+	})
+
+	It("only authorizes returns of delivered orders", func() {
+		resp := createReturn(handler.ReturnLineBody{OrderItemID: mugItem.ID, Quantity: 1})
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		setOrderStatus("paid")
+		setOrderStatus("shipped")
+		setOrderStatus("delivered")
+	})
+
+	It("authorizes a return of some items", func() {
+		resp := createReturn(
+			handler.ReturnLineBody{OrderItemID: mugItem.ID, Quantity: 2},
+			handler.ReturnLineBody{OrderItemID: plateItem.ID, Quantity: 1},
+		)
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &first)).To(Succeed())
+		Expect(first.Status).To(Equal("authorized"))
+		Expect(first.Reason).To(Equal("wrong colour"))
+		Expect(first.Lines).To(HaveLen(2))
+		Expect(first.Refund).To(BeNil())
+	})
+
+	It("never returns more than was ordered, counting earlier returns", func() {
+		resp := createReturn(handler.ReturnLineBody{OrderItemID: mugItem.ID, Quantity: 2})
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+
+		resp = createReturn(handler.ReturnLineBody{OrderItemID: mugItem.ID, Quantity: 1})
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &second)).To(Succeed())
+
+		resp = createReturn(handler.ReturnLineBody{OrderItemID: mugItem.ID, Quantity: 1})
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("rejects invalid returns", func() {
+		for _, lines := range [][]handler.ReturnLineBody{
+			nil,
+			{{OrderItemID: mugItem.ID, Quantity: 0}},
+			{{OrderItemID: order.ID, Quantity: 1}},
+			{{OrderItemID: plateItem.ID, Quantity: 1}, {OrderItemID: plateItem.ID, Quantity: 1}},
+		} {
+			resp := createReturn(lines...)
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), "%v", lines)
+		}
+		resp, err := TestSuite.ApiClient.CreateReturn(order.ID, handler.CreateReturnRequest{
+			Lines: []handler.ReturnLineBody{{OrderItemID: plateItem.ID, Quantity: 1}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("records the refund at the ordered prices on receipt", func() {
+		code, _ := setReturnStatus(first.ID, "restocked")
+		Expect(code).To(Equal(http.StatusConflict))
+
+		code, received := setReturnStatus(first.ID, "received")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(received.Status).To(Equal("received"))
+		Expect(received.ReceivedAt).NotTo(BeNil())
+		Expect(received.Refund).NotTo(BeNil())
+		Expect(received.Refund.Amount).To(Equal("17.00"))
+		Expect(stock(mug.ID)).To(Equal(7))
+	})
+
+	It("restocks received goods", func() {
+		code, restocked := setReturnStatus(first.ID, "restocked")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(restocked.Status).To(Equal("restocked"))
+		Expect(restocked.ResolvedAt).NotTo(BeNil())
+		Expect(restocked.Refund.Amount).To(Equal("17.00"))
+		Expect(stock(mug.ID)).To(Equal(9))
+		Expect(stock(plate.ID)).To(Equal(9))
+
+		code, _ = setReturnStatus(first.ID, "written_off")
+		Expect(code).To(Equal(http.StatusConflict))
+	})
+
+	It("writes damaged goods off without restocking them", func() {
+		code, _ := setReturnStatus(second.ID, "received")
+		Expect(code).To(Equal(http.StatusOK))
+		code, writtenOff := setReturnStatus(second.ID, "written_off")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(writtenOff.Status).To(Equal("written_off"))
+		Expect(writtenOff.Refund.Amount).To(Equal("6.50"))
+		Expect(stock(mug.ID)).To(Equal(9))
+	})
+
+	It("lists the returns of an order", func() {
+		resp, err := TestSuite.ApiClient.ListReturns(order.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var list []handler.ReturnResponse
+		Expect(decodeBody(resp, &list)).To(Succeed())
+		Expect(list).To(HaveLen(2))
+		Expect([]string{list[0].Status, list[1].Status}).To(ConsistOf("restocked", "written_off"))
+
+		resp, err = TestSuite.ApiClient.GetReturn("00000000-0000-0000-0000-000000000000")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("records received returns and restock credits in the outbox", func() {
+		if TestSuite.Repo == nil {
+			Skip("outbox events are only visible with the in-memory repository")
+		}
+		var received []domain.ReturnReceivedEvent
+		var credits []domain.StockAdjustedEvent
+		for _, e := range TestSuite.Repo.Events() {
+			switch {
+			case e.Type == domain.EventReturnReceived:
+				var payload domain.ReturnReceivedEvent
+				Expect(json.Unmarshal(e.Payload, &payload)).To(Succeed())
+				if payload.OrderID == order.ID {
+					received = append(received, payload)
+				}
+			case e.Type == domain.EventStockAdjusted && (e.AggregateID == mug.ID || e.AggregateID == plate.ID):
+				var payload domain.StockAdjustedEvent
+				Expect(json.Unmarshal(e.Payload, &payload)).To(Succeed())
+				credits = append(credits, payload)
+			}
+		}
+		Expect(received).To(HaveLen(2))
+		Expect(received[0].RefundAmount).To(Equal("17.00"))
+		Expect(credits).To(HaveLen(2))
+		for _, c := range credits {
+			Expect(c.Reason).To(Equal("return " + first.ID))
+		}
+	})
+})
@@ -0,0 +1,178 @@
+package mainspec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/code/tests"
+	"stockpilot/internal/domain"
+	"stockpilot/internal/handler"
+)
+
+var _ = Describe("Stock stream", Ordered, func() {
+	var (
+		product handler.ProductResponse
+		lastID  string
+	)
+
+	open := func(lastEventID string) (*tests.SSEReader, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := TestSuite.ApiClient.StreamStock(ctx, []string{product.ID}, lastEventID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+		DeferCleanup(resp.Body.Close)
+		return tests.NewSSEReader(resp.Body), cancel
+	}
+
+	// nextStock skips heartbeats and returns the next stock event.
+	nextStock := func(r *tests.SSEReader) (tests.SSEMessage, domain.StockLevel) {
+		for {
+			msg, err := r.Next()
+			Expect(err).NotTo(HaveOccurred())
+			if msg.Event == "" {
+				continue
+			}
+			Expect(msg.Event).To(Equal("stock"))
+			var level domain.StockLevel
+			Expect(json.Unmarshal([]byte(msg.Data), &level)).To(Succeed())
+			return msg, level
+		}
+	}
+
+	adjust := func(delta int) {
+		resp, err := TestSuite.ApiClient.AdjustStock(product.ID, handler.AdjustStockRequest{Delta: delta, Reason: "stream"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	BeforeAll(func() {
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{
+			Description: "Streamed product",
+			Quantity:    3,
+			Price:       "1.00",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &product)).To(Succeed())
+	})
+
+	It("rejects unknown products", func() {
+		resp, err := TestSuite.ApiClient.StreamStock(context.Background(), []string{"00000000-0000-0000-0000-000000000000"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("sends the current level and then every committed change", func() {
+		r, cancel := open("")
+		defer cancel()
+
+		msg, level := nextStock(r)
+		Expect(msg.ID).To(BeEmpty())
+		Expect(level.Quantity).To(Equal(3))
+
+		adjust(4)
+		msg, level = nextStock(r)
+		Expect(msg.ID).NotTo(BeEmpty())
+		Expect(level.ProductID).To(Equal(product.ID))
+		Expect(level.Quantity).To(Equal(7))
+		lastID = msg.ID
+	})
+
+	It("resumes after Last-Event-ID without a snapshot", func() {
+		adjust(-2)
+		adjust(-1)
+
+		r, cancel := open(lastID)
+		defer cancel()
+
+		_, level := nextStock(r)
+		Expect(level.Quantity).To(Equal(5))
+		_, level = nextStock(r)
+		Expect(level.Quantity).To(Equal(4))
+	})
+
+	It("sends the current level again for an unknown Last-Event-ID", func() {
+		r, cancel := open("1")
+		defer cancel()
+
+		msg, level := nextStock(r)
+		Expect(msg.ID).To(BeEmpty())
+		Expect(level.Quantity).To(Equal(4))
+	})
+
+	It("sends every current level for an unknown Last-Event-ID without a filter", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		resp, err := TestSuite.ApiClient.StreamStock(ctx, nil, "1")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		r := tests.NewSSEReader(resp.Body)
+
+		for {
+			msg, level := nextStock(r)
+			Expect(msg.ID).To(BeEmpty())
+			if level.ProductID == product.ID {
+				Expect(level.Quantity).To(Equal(4))
+				break
+			}
+		}
+	})
+
+	It("sends heartbeats on idle streams", func() {
+		r, cancel := open("")
+		defer cancel()
+
+		nextStock(r)
+		Eventually(func() string {
+			msg, err := r.Next()
+			Expect(err).NotTo(HaveOccurred())
+			return msg.Comment
+		}).Should(Equal("heartbeat"))
+	})
+
+	It("streams order checkouts and cancellations", func() {
+		resp, err := TestSuite.ApiClient.RegisterUser(handler.RegisterUserRequest{
+			Email:     "stream-" + product.ID + "@example.com",
+			FirstName: "Stream",
+			LastName:  "User",
+			Password:  "StrongPassword",
+			Age:       30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var user handler.UserResponse
+		Expect(decodeBody(resp, &user)).To(Succeed())
+
+		r, cancel := open("")
+		defer cancel()
+		nextStock(r)
+
+		resp, err = TestSuite.ApiClient.CreateOrder(handler.CreateOrderRequest{
+			UserID: user.ID,
+			Items:  []handler.CreateOrderItemBody{{ProductID: product.ID, Quantity: 3}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		var order handler.OrderResponse
+		Expect(decodeBody(resp, &order)).To(Succeed())
+		_, level := nextStock(r)
+		Expect(level.Quantity).To(Equal(1))
+
+		resp, err = TestSuite.ApiClient.UpdateOrderStatus(order.ID, handler.UpdateOrderStatusRequest{Status: "cancelled"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		_, level = nextStock(r)
+		Expect(level.Quantity).To(Equal(4))
+	})
+})
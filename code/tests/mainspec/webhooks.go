@@ -0,0 +1,221 @@
+package mainspec
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/handler"
+	"stockpilot/pkg/gonerve/webhook"
+)
+
+var _ = Describe("Webhook subscriptions", Ordered, func() {
+	var (
+		sub      handler.WebhookResponse
+		received chan *http.Request
+		bodies   chan []byte
+		receiver *httptest.Server
+	)
+
+	BeforeAll(func() {
+		received = make(chan *http.Request, 10)
+		bodies = make(chan []byte, 10)
+		receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received <- r
+			bodies <- body
+			_, _ = w.Write([]byte("ok"))
+		}))
+		DeferCleanup(receiver.Close)
+	})
+
+	It("rejects invalid urls and unknown event types", func() {
+		resp, err := TestSuite.ApiClient.CreateWebhook(handler.WebhookRequest{URL: "ftp://example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err = TestSuite.ApiClient.CreateWebhook(handler.WebhookRequest{URL: receiver.URL, EventTypes: []string{"order.lost"}})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("creates a subscription and returns the secret only once", func() {
+		resp, err := TestSuite.ApiClient.CreateWebhook(handler.WebhookRequest{
+			URL:         receiver.URL,
+			Description: "stock feed",
+			EventTypes:  []string{domain.EventStockAdjusted},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(decodeBody(resp, &sub)).To(Succeed())
+		Expect(sub.Secret).To(HaveLen(64))
+		Expect(sub.Active).To(BeTrue())
+
+		resp, err = TestSuite.ApiClient.GetWebhook(sub.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var got handler.WebhookResponse
+		Expect(decodeBody(resp, &got)).To(Succeed())
+		Expect(got.Secret).To(BeEmpty())
+		Expect(got.EventTypes).To(Equal([]string{domain.EventStockAdjusted}))
+	})
+
+	It("updates a subscription", func() {
+		active := false
+		resp, err := TestSuite.ApiClient.UpdateWebhook(sub.ID, handler.WebhookRequest{URL: receiver.URL, Active: &active})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var updated handler.WebhookResponse
+		Expect(decodeBody(resp, &updated)).To(Succeed())
+		Expect(updated.Active).To(BeFalse())
+		Expect(updated.EventTypes).To(BeEmpty())
+
+		active = true
+		resp, err = TestSuite.ApiClient.UpdateWebhook(sub.ID, handler.WebhookRequest{
+			URL:        receiver.URL,
+			EventTypes: []string{domain.EventStockAdjusted},
+			Active:     &active,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, err = TestSuite.ApiClient.UpdateWebhook("00000000-0000-0000-0000-000000000000", handler.WebhookRequest{URL: receiver.URL})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("delivers signed events and logs every attempt", func() {
+		if TestSuite.Webhooks == nil {
+			Skip("delivery is driven directly only with the in-memory suite")
+		}
+		resp, err := TestSuite.ApiClient.CreateProduct(handler.CreateProductRequest{Description: "Webhook product", Quantity: 1, Price: "1.00"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var product handler.ProductResponse
+		Expect(decodeBody(resp, &product)).To(Succeed())
+
+		resp, err = TestSuite.ApiClient.AdjustStock(product.ID, handler.AdjustStockRequest{Delta: 2, Reason: "delivery"})
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		ctx := context.Background()
+		sink := TestSuite.Webhooks.Sink()
+		for _, e := range TestSuite.Repo.Events() {
+			if e.AggregateID == product.ID {
+				Expect(sink.Deliver(ctx, e)).To(Succeed())
+				// A redelivered event must not fan out twice.
+				Expect(sink.Deliver(ctx, e)).To(Succeed())
+			}
+		}
+		n, err := TestSuite.Webhooks.ProcessDeliveries(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+
+		var r *http.Request
+		Eventually(received).Should(Receive(&r))
+		body := <-bodies
+		Expect(r.Header.Get(webhook.HeaderEvent)).To(Equal(domain.EventStockAdjusted))
+		Expect(webhook.Verify(sub.Secret, r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, time.Minute, time.Now())).To(Succeed())
+
+		resp, err = TestSuite.ApiClient.ListWebhookDeliveries(sub.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var deliveries []handler.WebhookDeliveryResponse
+		Expect(decodeBody(resp, &deliveries)).To(Succeed())
+		Expect(deliveries).To(HaveLen(1))
+		Expect(deliveries[0].Status).To(Equal("succeeded"))
+		Expect(deliveries[0].ID).To(Equal(r.Header.Get(webhook.HeaderDelivery)))
+
+		resp, err = TestSuite.ApiClient.ReplayWebhookDelivery(deliveries[0].ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		_, err = TestSuite.Webhooks.ProcessDeliveries(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		var replayedBody []byte
+		Eventually(bodies).Should(Receive(&replayedBody))
+		Expect(replayedBody).To(Equal(body))
+
+		resp, err = TestSuite.ApiClient.GetWebhookDelivery(deliveries[0].ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var detail handler.WebhookDeliveryDetailResponse
+		Expect(decodeBody(resp, &detail)).To(Succeed())
+		Expect(detail.Log).To(HaveLen(2))
+		Expect(detail.Log[1].StatusCode).To(Equal(http.StatusOK))
+		Expect(detail.Log[1].ResponseSnippet).To(Equal("ok"))
+	})
+
+	It("holds pending deliveries while the subscription is inactive", func() {
+		if TestSuite.Webhooks == nil {
+			Skip("delivery is driven directly only with the in-memory suite")
+		}
+		ctx := context.Background()
+		setActive := func(active bool) {
+			resp, err := TestSuite.ApiClient.UpdateWebhook(sub.ID, handler.WebhookRequest{
+				URL:        receiver.URL,
+				EventTypes: []string{domain.EventStockAdjusted},
+				Active:     &active,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		}
+
+		resp, err := TestSuite.ApiClient.ListWebhookDeliveries(sub.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		var deliveries []handler.WebhookDeliveryResponse
+		Expect(decodeBody(resp, &deliveries)).To(Succeed())
+		Expect(deliveries).NotTo(BeEmpty())
+
+		setActive(false)
+		resp, err = TestSuite.ApiClient.ReplayWebhookDelivery(deliveries[0].ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		n, err := TestSuite.Webhooks.ProcessDeliveries(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(BeZero())
+		Consistently(bodies).WithTimeout(200 * time.Millisecond).ShouldNot(Receive())
+
+		setActive(true)
+		n, err = TestSuite.Webhooks.ProcessDeliveries(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Eventually(bodies).Should(Receive())
+	})
+
+	It("returns 404 for unknown deliveries", func() {
+		resp, err := TestSuite.ApiClient.ReplayWebhookDelivery("00000000-0000-0000-0000-000000000000")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("deletes a subscription", func() {
+		resp, err := TestSuite.ApiClient.DeleteWebhook(sub.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		resp, err = TestSuite.ApiClient.GetWebhook(sub.ID)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
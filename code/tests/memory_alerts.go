@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+)
+
+func (r *MemoryRepository) SetReorderPolicy(_ context.Context, id string, reorderPoint, safetyStock int) (*domain.Product, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	p.ReorderPoint, p.SafetyStock = reorderPoint, safetyStock
+	p.UpdatedAt = time.Now().UTC()
+	r.products[id] = p
+	return &p, nil
+}
+
+func (r *MemoryRepository) RaiseLowStockAlert(_ context.Context, tx pgx.Tx, productID string) (*domain.LowStockAlert, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	p, ok := r.products[productID]
+	if !ok {
+		return nil, nil
+	}
+	wasBelow := r.belowPoint[productID]
+	r.belowPoint[productID] = p.LowStock()
+	if !p.LowStock() || wasBelow {
+		return nil, nil
+	}
+	for _, a := range r.alerts {
+		if a.ProductID == productID && a.AcknowledgedAt == nil {
+			return nil, nil
+		}
+	}
+	a := domain.LowStockAlert{
+		ID:           r.nextID(),
+		ProductID:    productID,
+		Severity:     domain.LowStockSeverity(p.Quantity, p.SafetyStock),
+		Quantity:     p.Quantity,
+		ReorderPoint: p.ReorderPoint,
+		SafetyStock:  p.SafetyStock,
+		CreatedAt:    time.Now().UTC(),
+	}
+	r.alerts[a.ID] = a
+	return &a, nil
+}
+
+func (r *MemoryRepository) ListLowStockAlerts(_ context.Context, includeAcknowledged bool, limit int) ([]domain.LowStockAlert, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := []domain.LowStockAlert{}
+	for _, a := range r.alerts {
+		if includeAcknowledged || a.AcknowledgedAt == nil {
+			result = append(result, a)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) AcknowledgeLowStockAlert(_ context.Context, id, by string) (*domain.LowStockAlert, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	a, ok := r.alerts[id]
+	if !ok {
+		return nil, errors.New("alert not found")
+	}
+	if a.AcknowledgedAt != nil {
+		return nil, errors.New("alert already acknowledged")
+	}
+	now := time.Now().UTC()
+	a.AcknowledgedAt, a.AcknowledgedBy = &now, by
+	r.alerts[id] = a
+	return &a, nil
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+)
+
+func (r *MemoryRepository) SetBackorderPolicy(_ context.Context, id string, allow bool) (*domain.Product, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	p.AllowBackorder = allow
+	p.UpdatedAt = time.Now().UTC()
+	r.products[id] = p
+	return &p, nil
+}
+
+func (r *MemoryRepository) GetOrder(_ context.Context, id string) (*domain.Order, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	return r.getOrder(id), nil
+}
+
+// getOrder expects the lock to be held.
+func (r *MemoryRepository) getOrder(id string) *domain.Order {
+	o, ok := r.orders[id]
+	if !ok {
+		return nil
+	}
+	o.Items = append([]domain.OrderItem(nil), o.Items...)
+	for i := range o.Items {
+		o.Items[i].Backordered = 0
+		for _, b := range r.backorders {
+			if b.OrderItemID == o.Items[i].ID && b.Status == domain.BackorderOpen {
+				o.Items[i].Backordered = b.Outstanding()
+			}
+		}
+	}
+	return &o
+}
+
+// addBackorders expects the lock to be held.
+func (r *MemoryRepository) addBackorders(order *domain.Order) {
+	for _, item := range order.Items {
+		if item.Backordered <= 0 {
+			continue
+		}
+		r.backorders = append(r.backorders, domain.Backorder{
+			ID:          r.nextID(),
+			OrderID:     order.ID,
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			Quantity:    item.Backordered,
+			Status:      domain.BackorderOpen,
+			CreatedAt:   order.CreatedAt,
+		})
+	}
+}
+
+func (r *MemoryRepository) ListOpenBackorders(_ context.Context, tx pgx.Tx, productID string) ([]domain.Backorder, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	result := []domain.Backorder{}
+	for _, b := range r.backorders {
+		if b.ProductID == productID && b.Status == domain.BackorderOpen {
+			result = append(result, b)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (r *MemoryRepository) FillBackorder(_ context.Context, tx pgx.Tx, id string, quantity int) error {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	for i := range r.backorders {
+		b := &r.backorders[i]
+		if b.ID != id || b.Status != domain.BackorderOpen {
+			continue
+		}
+		b.FilledQuantity += quantity
+		if b.FilledQuantity == b.Quantity {
+			now := time.Now().UTC()
+			b.Status, b.ClosedAt = domain.BackorderFilled, &now
+		}
+		return nil
+	}
+	return errors.New("backorder not found")
+}
+
+func (r *MemoryRepository) FulfilledOrders(_ context.Context, tx pgx.Tx, orderIDs []string) ([]string, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	open := map[string]bool{}
+	for _, b := range r.backorders {
+		if b.Status == domain.BackorderOpen {
+			open[b.OrderID] = true
+		}
+	}
+	ids := slices.Clone(orderIDs)
+	slices.Sort(ids)
+	result := []string{}
+	for _, id := range slices.Compact(ids) {
+		if !open[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) CancelBackorders(_ context.Context, tx pgx.Tx, orderID string) error {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	now := time.Now().UTC()
+	for i := range r.backorders {
+		if b := &r.backorders[i]; b.OrderID == orderID && b.Status == domain.BackorderOpen {
+			b.Status, b.ClosedAt = domain.BackorderCancelled, &now
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type memoryPurchasing struct {
+	suppliers map[string]domain.Supplier
+	orders    map[string]domain.PurchaseOrder
+	receipts  []domain.PurchaseReceipt
+}
+
+func newMemoryPurchasing() *memoryPurchasing {
+	return &memoryPurchasing{
+		suppliers: map[string]domain.Supplier{},
+		orders:    map[string]domain.PurchaseOrder{},
+	}
+}
+
+func clonePurchaseOrder(po domain.PurchaseOrder) *domain.PurchaseOrder {
+	po.Lines = append([]domain.PurchaseOrderLine(nil), po.Lines...)
+	return &po
+}
+
+func (r *MemoryRepository) CreateSupplier(_ context.Context, s *domain.Supplier) (*domain.Supplier, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	clone := *s
+	if clone.ID == "" {
+		clone.ID = r.nextID()
+	}
+	now := time.Now().UTC()
+	clone.CreatedAt, clone.UpdatedAt = now, now
+	r.purchasing.suppliers[clone.ID] = clone
+	return &clone, nil
+}
+
+func (r *MemoryRepository) GetSupplier(_ context.Context, id string) (*domain.Supplier, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	s, ok := r.purchasing.suppliers[id]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (r *MemoryRepository) ListSuppliers(_ context.Context) ([]domain.Supplier, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := make([]domain.Supplier, 0, len(r.purchasing.suppliers))
+	for _, s := range r.purchasing.suppliers {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (r *MemoryRepository) UpdateSupplier(_ context.Context, s *domain.Supplier) (*domain.Supplier, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	existing, ok := r.purchasing.suppliers[s.ID]
+	if !ok {
+		return nil, errors.New("supplier not found")
+	}
+	clone := *s
+	clone.CreatedAt, clone.UpdatedAt = existing.CreatedAt, time.Now().UTC()
+	r.purchasing.suppliers[clone.ID] = clone
+	return &clone, nil
+}
+
+func (r *MemoryRepository) CreatePurchaseOrder(_ context.Context, tx pgx.Tx, po *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	if _, ok := r.purchasing.suppliers[po.SupplierID]; !ok {
+		return nil, errors.New("supplier not found")
+	}
+	clone := clonePurchaseOrder(*po)
+	if clone.ID == "" {
+		clone.ID = r.nextID()
+	}
+	if clone.Status == "" {
+		clone.Status = domain.PurchaseOrderDraft
+	}
+	now := time.Now().UTC()
+	clone.CreatedAt, clone.UpdatedAt = now, now
+	for i := range clone.Lines {
+		if _, ok := r.products[clone.Lines[i].ProductID]; !ok {
+			return nil, errors.New("product not found")
+		}
+		if clone.Lines[i].ID == "" {
+			clone.Lines[i].ID = r.nextID()
+		}
+		clone.Lines[i].PurchaseOrderID = clone.ID
+		clone.Lines[i].ReceivedQuantity = 0
+	}
+	sort.Slice(clone.Lines, func(i, j int) bool { return clone.Lines[i].ProductID < clone.Lines[j].ProductID })
+	r.purchasing.orders[clone.ID] = *clone
+	return clonePurchaseOrder(*clone), nil
+}
+
+func (r *MemoryRepository) GetPurchaseOrder(_ context.Context, id string) (*domain.PurchaseOrder, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	po, ok := r.purchasing.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return clonePurchaseOrder(po), nil
+}
+
+func (r *MemoryRepository) ListPurchaseOrders(_ context.Context, status domain.PurchaseOrderStatus, limit int) ([]domain.PurchaseOrder, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := []domain.PurchaseOrder{}
+	for _, po := range r.purchasing.orders {
+		if status == "" || po.Status == status {
+			result = append(result, *clonePurchaseOrder(po))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) GetPurchaseOrderForUpdate(_ context.Context, tx pgx.Tx, id string) (*domain.PurchaseOrder, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	po, ok := r.purchasing.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return clonePurchaseOrder(po), nil
+}
+
+func (r *MemoryRepository) UpdatePurchaseOrderStatus(_ context.Context, tx pgx.Tx, id string, status domain.PurchaseOrderStatus) (*domain.PurchaseOrder, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	po, ok := r.purchasing.orders[id]
+	if !ok {
+		return nil, errors.New("purchase order not found")
+	}
+	po.Status = status
+	po.UpdatedAt = time.Now().UTC()
+	r.purchasing.orders[id] = po
+	return clonePurchaseOrder(po), nil
+}
+
+func (r *MemoryRepository) RecordPurchaseReceipt(_ context.Context, tx pgx.Tx, receipt *domain.PurchaseReceipt) (*domain.PurchaseReceipt, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	po, ok := r.purchasing.orders[receipt.PurchaseOrderID]
+	if !ok {
+		return nil, errors.New("purchase order not found")
+	}
+	lines := append([]domain.PurchaseOrderLine(nil), po.Lines...)
+	for _, rl := range receipt.Lines {
+		found := false
+		for i := range lines {
+			if lines[i].ID == rl.LineID {
+				if lines[i].ReceivedQuantity+rl.Quantity > lines[i].Quantity {
+					return nil, errors.New("received quantity exceeds ordered quantity")
+				}
+				lines[i].ReceivedQuantity += rl.Quantity
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.New("product is not on the purchase order")
+		}
+	}
+	po.Lines = lines
+	r.purchasing.orders[po.ID] = po
+
+	clone := *receipt
+	if clone.ID == "" {
+		clone.ID = r.nextID()
+	}
+	if clone.ReceivedAt.IsZero() {
+		clone.ReceivedAt = time.Now().UTC()
+	}
+	clone.Lines = append([]domain.PurchaseReceiptLine(nil), receipt.Lines...)
+	r.purchasing.receipts = append(r.purchasing.receipts, clone)
+	return &clone, nil
+}
+
+func (r *MemoryRepository) ListPurchaseReceipts(_ context.Context, purchaseOrderID string) ([]domain.PurchaseReceipt, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := []domain.PurchaseReceipt{}
+	for _, rc := range r.purchasing.receipts {
+		if rc.PurchaseOrderID == purchaseOrderID {
+			rc.Lines = append([]domain.PurchaseReceiptLine(nil), rc.Lines...)
+			result = append(result, rc)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+)
+
+func (r *MemoryRepository) ListReorderCandidates(_ context.Context, tx pgx.Tx, since time.Time) ([]domain.ReorderCandidate, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	sold := map[string]int{}
+	for _, o := range r.orders {
+		if o.Status == domain.OrderCancelled || o.CreatedAt.Before(since) {
+			continue
+		}
+		for _, item := range o.Items {
+			sold[item.ProductID] += item.Quantity
+		}
+	}
+
+	onOrder := map[string]int{}
+	latest := map[string]domain.PurchaseOrder{}
+	for _, po := range r.purchasing.orders {
+		for _, l := range po.Lines {
+			if po.Status != domain.PurchaseOrderReceived {
+				onOrder[l.ProductID] += l.Outstanding()
+			}
+			prev, ok := latest[l.ProductID]
+			if !ok || po.CreatedAt.After(prev.CreatedAt) || (po.CreatedAt.Equal(prev.CreatedAt) && po.ID < prev.ID) {
+				latest[l.ProductID] = po
+			}
+		}
+	}
+
+	backordered := map[string]int{}
+	for _, b := range r.backorders {
+		if b.Status == domain.BackorderOpen {
+			backordered[b.ProductID] += b.Outstanding()
+		}
+	}
+
+	result := make([]domain.ReorderCandidate, 0, len(r.products))
+	for _, p := range r.products {
+		c := domain.ReorderCandidate{
+			ProductID:    p.ID,
+			Description:  p.Description,
+			Quantity:     p.Quantity,
+			ReorderPoint: p.ReorderPoint,
+			SafetyStock:  p.SafetyStock,
+			UnitsSold:    sold[p.ID],
+			OnOrder:      onOrder[p.ID],
+			Backordered:  backordered[p.ID],
+		}
+		if po, ok := latest[p.ID]; ok {
+			if s, ok := r.purchasing.suppliers[po.SupplierID]; ok {
+				leadTime := s.LeadTimeDays
+				c.SupplierID, c.SupplierName, c.LeadTimeDays = s.ID, s.Name, &leadTime
+			}
+		}
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ProductID < result[j].ProductID })
+	return result, nil
+}
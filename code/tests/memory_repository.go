@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,14 +13,26 @@ import (
 	"stockpilot/internal/domain"
 	"stockpilot/pkg/gonerve/errors"
 	"stockpilot/pkg/gonerve/genuuid"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/postgresql"
 )
 
 type MemoryRepository struct {
-	mu       sync.Mutex
-	users    map[string]domain.User
-	products map[string]domain.Product
-	orders   map[string]domain.Order
-	ug       genuuid.GeneratorUUID
+	mu         sync.Mutex
+	users      map[string]domain.User
+	products   map[string]domain.Product
+	orders     map[string]domain.Order
+	events     []outbox.Event
+	webhooks   *memoryWebhooks
+	alerts     map[string]domain.LowStockAlert
+	belowPoint map[string]bool
+	purchasing *memoryPurchasing
+	backorders []domain.Backorder
+	returns    *memoryReturns
+	ug         genuuid.GeneratorUUID
+
+	maintenanceMu sync.Mutex
+	maintenance   postgresql.MaintenanceState
 }
 
 type memoryTx struct {
@@ -28,10 +41,15 @@ type memoryTx struct {
 
 func NewMemoryRepository() *MemoryRepository {
 	return &MemoryRepository{
-		users:    map[string]domain.User{},
-		products: map[string]domain.Product{},
-		orders:   map[string]domain.Order{},
-		ug:       genuuid.New(),
+		users:      map[string]domain.User{},
+		products:   map[string]domain.Product{},
+		orders:     map[string]domain.Order{},
+		webhooks:   newMemoryWebhooks(),
+		alerts:     map[string]domain.LowStockAlert{},
+		belowPoint: map[string]bool{},
+		purchasing: newMemoryPurchasing(),
+		returns:    newMemoryReturns(),
+		ug:         genuuid.New(),
 	}
 }
 
@@ -47,14 +65,51 @@ func (r *MemoryRepository) nextID() string {
 	return r.ug.V4()
 }
 
-func (r *MemoryRepository) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *MemoryRepository) Ping(_ context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) Locked() error {
+	if r.Maintenance().Locked {
+		return postgresql.ErrLocked
+	}
+	return nil
+}
 
-	return f(ctx, &memoryTx{repo: r})
+func (r *MemoryRepository) Maintenance() postgresql.MaintenanceState {
+	r.maintenanceMu.Lock()
+	defer r.maintenanceMu.Unlock()
+	return r.maintenance
+}
+
+func (r *MemoryRepository) SetMaintenance(_ context.Context, locked bool, reason string) error {
+	r.maintenanceMu.Lock()
+	defer r.maintenanceMu.Unlock()
+	r.maintenance = postgresql.MaintenanceState{Locked: locked, Reason: reason, UpdatedAt: time.Now().UTC()}
+	return nil
+}
+
+func (r *MemoryRepository) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error, opts ...domain.TxOption) error {
+	if !domain.NewTxOptions(opts...).ReadOnly {
+		if err := r.Locked(); err != nil {
+			return err
+		}
+	}
+	ctx, runHooks := postgresql.WithCommitHooks(ctx)
+	r.mu.Lock()
+	err := f(ctx, &memoryTx{repo: r})
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	runHooks()
+	return nil
 }
 
 func (r *MemoryRepository) CreateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
 	unlock := r.lock(nil)
 	defer unlock()
 
@@ -100,6 +155,9 @@ func (r *MemoryRepository) GetByID(_ context.Context, id string) (*domain.User,
 }
 
 func (r *MemoryRepository) CreateProduct(_ context.Context, product *domain.Product) (*domain.Product, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
 	unlock := r.lock(nil)
 	defer unlock()
 
@@ -129,7 +187,19 @@ func (r *MemoryRepository) GetProductByID(_ context.Context, id string) (*domain
 	return nil, nil
 }
 
-func (r *MemoryRepository) GetByIDsForUpdate(_ context.Context, tx pgx.Tx, ids []string) ([]domain.Product, error) {
+func (r *MemoryRepository) ListStockLevels(_ context.Context) ([]domain.StockLevel, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := make([]domain.StockLevel, 0, len(r.products))
+	for _, p := range r.products {
+		result = append(result, domain.StockLevel{ProductID: p.ID, Quantity: p.Quantity, UpdatedAt: p.UpdatedAt})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ProductID < result[j].ProductID })
+	return result, nil
+}
+
+func (r *MemoryRepository) GetByIDsForUpdate(_ context.Context, tx pgx.Tx, ids []string, _ domain.LockMode) ([]domain.Product, error) {
 	unlock := r.lock(tx)
 	defer unlock()
 
@@ -151,7 +221,7 @@ func (r *MemoryRepository) UpdateQuantity(_ context.Context, tx pgx.Tx, id strin
 		return errors.New("product not found")
 	}
 	if p.Quantity+delta < 0 {
-		return errors.New("insufficient stock")
+		return domain.ErrInsufficientStock
 	}
 	p.Quantity += delta
 	p.UpdatedAt = time.Now().UTC()
@@ -159,6 +229,26 @@ func (r *MemoryRepository) UpdateQuantity(_ context.Context, tx pgx.Tx, id strin
 	return nil
 }
 
+func (r *MemoryRepository) UpdateQuantities(_ context.Context, tx pgx.Tx, changes []domain.StockChange) error {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	for _, c := range changes {
+		p, ok := r.products[c.ProductID]
+		if !ok || p.Quantity+c.Delta < 0 {
+			return domain.ErrInsufficientStock
+		}
+	}
+	now := time.Now().UTC()
+	for _, c := range changes {
+		p := r.products[c.ProductID]
+		p.Quantity += c.Delta
+		p.UpdatedAt = now
+		r.products[c.ProductID] = p
+	}
+	return nil
+}
+
 func (r *MemoryRepository) CreateOrder(_ context.Context, tx pgx.Tx, order *domain.Order, items []domain.OrderItem) (*domain.Order, error) {
 	unlock := r.lock(tx)
 	defer unlock()
@@ -169,6 +259,10 @@ func (r *MemoryRepository) CreateOrder(_ context.Context, tx pgx.Tx, order *doma
 	if order.CreatedAt.IsZero() {
 		order.CreatedAt = time.Now().UTC()
 	}
+	if order.Status == "" {
+		order.Status = domain.OrderCreated
+	}
+	order.UpdatedAt = order.CreatedAt
 	order.Items = make([]domain.OrderItem, len(items))
 	copy(order.Items, items)
 
@@ -178,12 +272,52 @@ func (r *MemoryRepository) CreateOrder(_ context.Context, tx pgx.Tx, order *doma
 		}
 		order.Items[i].OrderID = order.ID
 	}
+	r.addBackorders(order)
 
 	clone := *order
 	r.orders[order.ID] = clone
 	return &clone, nil
 }
 
+func (r *MemoryRepository) GetOrderForUpdate(_ context.Context, tx pgx.Tx, id string) (*domain.Order, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	return r.getOrder(id), nil
+}
+
+func (r *MemoryRepository) UpdateOrderStatus(_ context.Context, tx pgx.Tx, id string, status domain.OrderStatus) (*domain.Order, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, errors.New("order not found")
+	}
+	o.Status = status
+	o.UpdatedAt = time.Now().UTC()
+	r.orders[id] = o
+	clone := o
+	clone.Items = nil
+	return &clone, nil
+}
+
+func (r *MemoryRepository) AddEvents(_ context.Context, tx pgx.Tx, events ...outbox.Event) error {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	r.events = append(r.events, events...)
+	return nil
+}
+
+// Events returns the outbox events recorded so far.
+func (r *MemoryRepository) Events() []outbox.Event {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	return append([]outbox.Event(nil), r.events...)
+}
+
 func (memoryTx) Begin(ctx context.Context) (pgx.Tx, error) { return memoryTx{}, nil }
 func (memoryTx) Commit(ctx context.Context) error          { return nil }
 func (memoryTx) Rollback(ctx context.Context) error        { return nil }
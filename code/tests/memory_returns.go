@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type memoryReturns struct {
+	returns map[string]domain.Return
+	refunds map[string]domain.Refund
+}
+
+func newMemoryReturns() *memoryReturns {
+	return &memoryReturns{
+		returns: map[string]domain.Return{},
+		refunds: map[string]domain.Refund{},
+	}
+}
+
+// cloneReturn expects the lock to be held.
+func (r *MemoryRepository) cloneReturn(ret domain.Return) *domain.Return {
+	ret.Lines = append([]domain.ReturnLine(nil), ret.Lines...)
+	ret.Refund = nil
+	if refund, ok := r.returns.refunds[ret.ID]; ok {
+		ret.Refund = &refund
+	}
+	return &ret
+}
+
+func (r *MemoryRepository) CreateReturn(_ context.Context, tx pgx.Tx, ret *domain.Return) (*domain.Return, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	clone := *ret
+	clone.Lines = append([]domain.ReturnLine(nil), ret.Lines...)
+	if clone.ID == "" {
+		clone.ID = r.nextID()
+	}
+	if clone.Status == "" {
+		clone.Status = domain.ReturnAuthorized
+	}
+	now := time.Now().UTC()
+	clone.CreatedAt, clone.UpdatedAt = now, now
+	for i := range clone.Lines {
+		if clone.Lines[i].ID == "" {
+			clone.Lines[i].ID = r.nextID()
+		}
+		clone.Lines[i].ReturnID = clone.ID
+	}
+	sort.Slice(clone.Lines, func(i, j int) bool { return clone.Lines[i].OrderItemID < clone.Lines[j].OrderItemID })
+	r.returns.returns[clone.ID] = clone
+	return r.cloneReturn(clone), nil
+}
+
+func (r *MemoryRepository) ReturnedQuantities(_ context.Context, tx pgx.Tx, orderID string) (map[string]int, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	result := map[string]int{}
+	for _, ret := range r.returns.returns {
+		if ret.OrderID != orderID {
+			continue
+		}
+		for _, l := range ret.Lines {
+			result[l.OrderItemID] += l.Quantity
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) GetReturn(_ context.Context, id string) (*domain.Return, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	ret, ok := r.returns.returns[id]
+	if !ok {
+		return nil, nil
+	}
+	return r.cloneReturn(ret), nil
+}
+
+func (r *MemoryRepository) GetReturnForUpdate(_ context.Context, tx pgx.Tx, id string) (*domain.Return, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	ret, ok := r.returns.returns[id]
+	if !ok {
+		return nil, nil
+	}
+	return r.cloneReturn(ret), nil
+}
+
+func (r *MemoryRepository) ListReturns(_ context.Context, orderID string) ([]domain.Return, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := []domain.Return{}
+	for _, ret := range r.returns.returns {
+		if ret.OrderID == orderID {
+			result = append(result, *r.cloneReturn(ret))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (r *MemoryRepository) UpdateReturnStatus(_ context.Context, tx pgx.Tx, id string, status domain.ReturnStatus) (*domain.Return, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	ret, ok := r.returns.returns[id]
+	if !ok {
+		return nil, errors.New("return not found")
+	}
+	now := time.Now().UTC()
+	ret.Status, ret.UpdatedAt = status, now
+	switch status {
+	case domain.ReturnReceived:
+		ret.ReceivedAt = &now
+	case domain.ReturnRestocked, domain.ReturnWrittenOff:
+		ret.ResolvedAt = &now
+	}
+	r.returns.returns[id] = ret
+	return r.cloneReturn(ret), nil
+}
+
+func (r *MemoryRepository) CreateRefund(_ context.Context, tx pgx.Tx, refund *domain.Refund) (*domain.Refund, error) {
+	unlock := r.lock(tx)
+	defer unlock()
+
+	if _, ok := r.returns.refunds[refund.ReturnID]; ok {
+		return nil, errors.New("refund already exists")
+	}
+	clone := *refund
+	if clone.ID == "" {
+		clone.ID = r.nextID()
+	}
+	if clone.CreatedAt.IsZero() {
+		clone.CreatedAt = time.Now().UTC()
+	}
+	r.returns.refunds[clone.ReturnID] = clone
+	return &clone, nil
+}
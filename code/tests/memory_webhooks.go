@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/outbox"
+)
+
+type memoryWebhooks struct {
+	subscriptions map[string]domain.WebhookSubscription
+	deliveries    map[string]domain.WebhookDelivery
+	attempts      map[string][]domain.WebhookAttempt
+}
+
+func newMemoryWebhooks() *memoryWebhooks {
+	return &memoryWebhooks{
+		subscriptions: map[string]domain.WebhookSubscription{},
+		deliveries:    map[string]domain.WebhookDelivery{},
+		attempts:      map[string][]domain.WebhookAttempt{},
+	}
+}
+
+func (r *MemoryRepository) CreateWebhook(_ context.Context, w *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	clone := *w
+	if clone.ID == "" {
+		clone.ID = r.nextID()
+	}
+	now := time.Now().UTC()
+	clone.CreatedAt, clone.UpdatedAt = now, now
+	clone.EventTypes = slices.Clone(w.EventTypes)
+	r.webhooks.subscriptions[clone.ID] = clone
+	return &clone, nil
+}
+
+func (r *MemoryRepository) GetWebhook(_ context.Context, id string) (*domain.WebhookSubscription, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	w, ok := r.webhooks.subscriptions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &w, nil
+}
+
+func (r *MemoryRepository) ListWebhooks(_ context.Context) ([]domain.WebhookSubscription, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	result := make([]domain.WebhookSubscription, 0, len(r.webhooks.subscriptions))
+	for _, w := range r.webhooks.subscriptions {
+		result = append(result, w)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (r *MemoryRepository) UpdateWebhook(_ context.Context, w *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	current, ok := r.webhooks.subscriptions[w.ID]
+	if !ok {
+		return nil, errors.New("webhook not found")
+	}
+	clone := *w
+	clone.CreatedAt = current.CreatedAt
+	clone.UpdatedAt = time.Now().UTC()
+	clone.EventTypes = slices.Clone(w.EventTypes)
+	r.webhooks.subscriptions[w.ID] = clone
+	return &clone, nil
+}
+
+func (r *MemoryRepository) DeleteWebhook(_ context.Context, id string) error {
+	if err := r.Locked(); err != nil {
+		return err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	state := r.webhooks
+	if _, ok := state.subscriptions[id]; !ok {
+		return errors.New("webhook not found")
+	}
+	delete(state.subscriptions, id)
+	for deliveryID, d := range state.deliveries {
+		if d.SubscriptionID == id {
+			delete(state.deliveries, deliveryID)
+			delete(state.attempts, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) EnqueueWebhookDeliveries(_ context.Context, e outbox.Event) (int, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return 0, errors.Wrap(err, "marshal event")
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	state := r.webhooks
+	n := 0
+	now := time.Now().UTC()
+	for _, w := range state.subscriptions {
+		if !w.Wants(e.Type) || hasDelivery(state, w.ID, e.ID) {
+			continue
+		}
+		d := domain.WebhookDelivery{
+			ID:             r.nextID(),
+			SubscriptionID: w.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+			Status:         domain.WebhookPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		state.deliveries[d.ID] = d
+		n++
+	}
+	return n, nil
+}
+
+func hasDelivery(state *memoryWebhooks, subscriptionID, eventID string) bool {
+	for _, d := range state.deliveries {
+		if d.SubscriptionID == subscriptionID && d.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) ClaimWebhookDeliveries(_ context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	state := r.webhooks
+	now := time.Now().UTC()
+	var due []domain.WebhookDelivery
+	for _, d := range state.deliveries {
+		if d.Status == domain.WebhookPending && !d.NextAttemptAt.After(now) && state.subscriptions[d.SubscriptionID].Active {
+			due = append(due, d)
+		}
+	}
+	sortDeliveries(due, false)
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		stored := due[i]
+		stored.NextAttemptAt = now.Add(lease)
+		state.deliveries[stored.ID] = stored
+		w := state.subscriptions[stored.SubscriptionID]
+		due[i].URL, due[i].Secret = w.URL, w.Secret
+	}
+	return due, nil
+}
+
+func (r *MemoryRepository) RecordWebhookAttempt(_ context.Context, d domain.WebhookDelivery, a domain.WebhookAttempt) error {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	state := r.webhooks
+	stored, ok := state.deliveries[d.ID]
+	if !ok {
+		return errors.New("delivery not found")
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.NextAttemptAt = d.NextAttemptAt
+	stored.LastStatusCode = d.LastStatusCode
+	stored.LastError = d.LastError
+	stored.UpdatedAt = time.Now().UTC()
+	state.deliveries[d.ID] = stored
+	state.attempts[d.ID] = append(state.attempts[d.ID], a)
+	return nil
+}
+
+func (r *MemoryRepository) ListWebhookDeliveries(_ context.Context, subscriptionID string, limit int) ([]domain.WebhookDelivery, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	var result []domain.WebhookDelivery
+	for _, d := range r.webhooks.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			result = append(result, d)
+		}
+	}
+	sortDeliveries(result, true)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) GetWebhookDelivery(_ context.Context, id string) (*domain.WebhookDelivery, []domain.WebhookAttempt, error) {
+	unlock := r.lock(nil)
+	defer unlock()
+
+	state := r.webhooks
+	d, ok := state.deliveries[id]
+	if !ok {
+		return nil, nil, nil
+	}
+	return &d, slices.Clone(state.attempts[id]), nil
+}
+
+func (r *MemoryRepository) ReplayWebhookDelivery(_ context.Context, id string) (*domain.WebhookDelivery, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	unlock := r.lock(nil)
+	defer unlock()
+
+	state := r.webhooks
+	d, ok := state.deliveries[id]
+	if !ok {
+		return nil, errors.New("delivery not found")
+	}
+	now := time.Now().UTC()
+	d.Status = domain.WebhookPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+	state.deliveries[id] = d
+	return &d, nil
+}
+
+func sortDeliveries(items []domain.WebhookDelivery, newestFirst bool) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt) != newestFirst
+		}
+		return a.ID < b.ID
+	})
+}
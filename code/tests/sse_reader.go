@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// SSEMessage is one parsed server-sent event or comment.
+type SSEMessage struct {
+	ID      string
+	Event   string
+	Data    string
+	Comment string
+}
+
+type SSEReader struct {
+	scanner *bufio.Scanner
+}
+
+func NewSSEReader(r io.Reader) *SSEReader {
+	return &SSEReader{scanner: bufio.NewScanner(r)}
+}
+
+// Next returns the next event or comment block.
+func (r *SSEReader) Next() (SSEMessage, error) {
+	var msg SSEMessage
+	var data []string
+	seen := false
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if !seen {
+				continue
+			}
+			msg.Data = strings.Join(data, "\n")
+			return msg, nil
+		}
+		seen = true
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			msg.Comment = value
+		case "id":
+			msg.ID = value
+		case "event":
+			msg.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return msg, err
+	}
+	return msg, io.EOF
+}
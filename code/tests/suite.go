@@ -13,6 +13,7 @@ import (
 	"stockpilot/internal/handler"
 	"stockpilot/internal/service"
 	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/sse"
 )
 
 type Suite struct {
@@ -20,6 +21,8 @@ type Suite struct {
 	ApiClient     *Client
 	Server        *handler.Server
 	Repo          *MemoryRepository
+	Webhooks      *service.WebhookService
+	Alerts        *service.MemoryNotifier
 	GetServerLogs func() ([]string, error)
 }
 
@@ -32,12 +35,40 @@ func CreateUnitTestingSuite(t *testing.T, cfg *config.Config) *Suite {
 	repo := NewMemoryRepository()
 
 	userSvc := service.NewUserService(repo)
-	productSvc := service.NewProductService(repo)
-	orderSvc := service.NewOrderService(repo, repo, repo, repo)
+	stockStream := service.NewStockStream(sse.NewBroker(sse.WithBufferSize(cfg.Stream.BufferSize)))
+	notifier := &service.MemoryNotifier{}
+	lowStock := service.NewLowStockService(repo, repo,
+		service.WithLowStockDebounce(time.Duration(cfg.Alerts.Debounce)*time.Second),
+		service.WithLowStockNotifiers(notifier),
+		service.WithLowStockEvents(repo),
+	)
+	productSvc := service.NewProductService(repo, repo, service.WithProductEvents(repo), service.WithProductBackorders(repo), service.WithProductStockPublisher(stockStream), service.WithProductStockPublisher(lowStock), service.WithProductLowStockChecks(lowStock))
+	orderSvc := service.NewOrderService(repo, repo, repo, repo, service.WithOrderEvents(repo), service.WithOrderBackorders(repo), service.WithOrderStockPublisher(stockStream), service.WithOrderStockPublisher(lowStock))
+	purchasingSvc := service.NewPurchasingService(repo, repo, repo, service.WithPurchasingEvents(repo), service.WithPurchasingBackorders(repo), service.WithPurchasingStockPublisher(stockStream), service.WithPurchasingStockPublisher(lowStock))
+	returnSvc := service.NewReturnService(repo, repo, repo, repo, service.WithReturnEvents(repo), service.WithReturnBackorders(repo), service.WithReturnStockPublisher(stockStream), service.WithReturnStockPublisher(lowStock))
+	reorderSvc := service.NewReorderService(repo, repo,
+		service.WithReorderWindow(cfg.Reorder.WindowDays),
+		service.WithReorderLeadTime(cfg.Reorder.LeadTimeDays),
+		service.WithReorderCover(cfg.Reorder.CoverDays),
+	)
+	webhookSvc := service.NewWebhookService(repo)
 
-	server, err := handler.NewServer(cfg.ListenAddr, userSvc, productSvc, orderSvc, cfg.Log.LogHTTPRequests, cfg.Sentry.ToSentryConfig() != nil)
+	server, err := handler.NewServer(cfg.ListenAddr, userSvc, productSvc, orderSvc, cfg.Log.LogHTTPRequests, cfg.Sentry.ToSentryConfig() != nil,
+		handler.WithHealthChecker(repo),
+		handler.WithAdminToken(cfg.Admin.Token),
+		handler.WithMaintenance(repo, cfg.Maintenance.RetryAfter),
+		handler.WithWebhooks(webhookSvc),
+		handler.WithStockStream(stockStream, time.Duration(cfg.Stream.HeartbeatInterval)*time.Second),
+		handler.WithLowStockAlerts(lowStock),
+		handler.WithPurchasing(purchasingSvc),
+		handler.WithReorderSuggestions(reorderSvc),
+		handler.WithReturns(returnSvc),
+	)
 	require.NoError(t, err)
 
+	ctx, stop := context.WithCancel(context.Background())
+	go lowStock.Run(ctx)
+
 	go func() {
 		if errStart := server.Start(); errStart != nil && !errors.Is(errStart, http.ErrServerClosed) {
 			t.Errorf("server start failed: %v", errStart)
@@ -51,10 +82,13 @@ func CreateUnitTestingSuite(t *testing.T, cfg *config.Config) *Suite {
 		ApiClient:     NewAPIClient(*cfg),
 		Server:        server,
 		Repo:          repo,
+		Webhooks:      webhookSvc,
+		Alerts:        notifier,
 		GetServerLogs: func() ([]string, error) { return []string{}, nil },
 	}
 
 	t.Cleanup(func() {
+		stop()
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
 		_ = server.Shutdown(ctx)
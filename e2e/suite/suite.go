@@ -115,7 +115,7 @@ func RunNewE2ESuite(t *testing.T, params Params) *E2ESuite {
 	params.Cfg.PG.Password = "postgres"
 	params.Cfg.PG.SSLMode = "disable"
 
-	migrationsPath := filepath.Clean("../../migrations/0001_init.sql")
+	migrationsPath := filepath.Clean("../../migrations")
 	require.NoError(t, tests.ApplyMigrations(context.Background(), pg.ConnString, migrationsPath))
 
 	tests.StoreCfgToFile(t, params.Cfg, params.ConfigFilePath)
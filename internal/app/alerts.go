@@ -0,0 +1,30 @@
+package app
+
+import (
+	"time"
+
+	"stockpilot/internal/config"
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/postgres"
+	"stockpilot/internal/service"
+)
+
+func newLowStockService(cfg *config.Config, repo *postgres.Repository) *service.LowStockService {
+	notifiers := []domain.LowStockNotifier{}
+	for _, name := range cfg.Alerts.NotifierNames() {
+		switch name {
+		case "log":
+			notifiers = append(notifiers, service.LogNotifier{})
+		case "webhook":
+			notifiers = append(notifiers, service.NewWebhookNotifier(cfg.Alerts.WebhookURL, cfg.Alerts.WebhookSecret, time.Duration(cfg.Alerts.WebhookTimeout)*time.Second))
+		}
+	}
+	opts := []service.LowStockServiceOption{
+		service.WithLowStockDebounce(time.Duration(cfg.Alerts.Debounce) * time.Second),
+		service.WithLowStockNotifiers(notifiers...),
+	}
+	if cfg.Outbox.Enabled {
+		opts = append(opts, service.WithLowStockEvents(repo))
+	}
+	return service.NewLowStockService(repo, repo, opts...)
+}
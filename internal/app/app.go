@@ -11,16 +11,26 @@ import (
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 
 	"stockpilot/internal/config"
+	"stockpilot/internal/domain"
 	"stockpilot/internal/handler"
+	"stockpilot/internal/middleware"
 	"stockpilot/internal/repository/postgres"
 	"stockpilot/internal/service"
 	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/features"
 	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/postgresql"
+	sentrymw "stockpilot/pkg/gonerve/sentry"
+	"stockpilot/pkg/gonerve/sse"
 	"stockpilot/pkg/gonerve/tracing"
+	"stockpilot/pkg/gonerve/webhook"
 )
 
 type App struct{}
@@ -37,6 +47,10 @@ func (a *App) Run() error {
 	if err := loadConfig(*cfgPath, &cfg); err != nil {
 		return err
 	}
+	if err := cfg.Validate(); err != nil {
+		return errors.Wrap(err, "validate config")
+	}
+	features.Set(cfg.Features)
 
 	logCfg := cfg.Log.ToLoggingConfig()
 	if err := logging.Init("stockpilot", &logCfg); err != nil {
@@ -53,36 +67,141 @@ func (a *App) Run() error {
 
 	sentryCfg := cfg.Sentry.ToSentryConfig()
 	if sentryCfg != nil {
-		if err := sentry.Init(sentry.ClientOptions{
-			Dsn:              sentryCfg.DSN,
-			SampleRate:       sentryCfg.ErrSampleRate,
-			AttachStacktrace: true,
-		}); err != nil {
+		info := buildInfo()
+		sentryCfg.Release = "stockpilot@" + info.Version
+		if sentryCfg.Environment == "" {
+			sentryCfg.Environment = info.Branch
+		}
+		if err := sentrymw.Init(*sentryCfg); err != nil {
 			return errors.Wrap(err, "sentry init")
 		}
+		sentry.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTag("commit", info.CommitHash)
+			scope.SetTag("build_date", info.BuildDate)
+		})
 		defer sentry.Flush(2 * time.Second)
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	repo, err := postgres.New(ctx, cfg.PG.ToDBConfig())
+	repoOpts := []postgresql.Option{}
+	if traceCfg != nil && traceCfg.Queries {
+		repoOpts = append(repoOpts, postgresql.WithQueryTracer(tracing.NewQueryTracer()))
+	}
+	if cfg.Maintenance.SyncInterval > 0 {
+		repoOpts = append(repoOpts, postgresql.WithMaintenanceSync(time.Duration(cfg.Maintenance.SyncInterval)*time.Second))
+	}
+	repo, err := postgres.New(ctx, cfg.PG.ToDBConfig(), repoOpts...)
 	if err != nil {
 		return err
 	}
 	defer repo.Close()
+	if collector := postgresql.NewPoolCollector(repo.Repository); collector != nil {
+		prometheus.MustRegister(collector)
+	}
+	go repo.RunMaintenanceSync(ctx)
+	go repo.RunReplicaChecks(ctx)
+
+	productOpts := []service.ProductServiceOption{service.WithProductBackorders(repo)}
+	lockMode, _ := domain.ParseLockMode(cfg.Checkout.LockMode)
+	orderOpts := []service.OrderServiceOption{service.WithLockMode(lockMode), service.WithOrderBackorders(repo)}
+	purchasingOpts := []service.PurchasingServiceOption{service.WithPurchasingBackorders(repo)}
+	returnOpts := []service.ReturnServiceOption{service.WithReturnBackorders(repo)}
+	var stockStream *service.StockStream
+	if cfg.Stream.Enabled {
+		stockStream = service.NewStockStream(sse.NewBroker(sse.WithBufferSize(cfg.Stream.BufferSize)), service.WithStockNotifier(repo))
+		// Levels committed on other instances; without pg.listen_notifications
+		// each instance streams only its own.
+		repo.SubscribeStock(stockStream.PublishStock)
+		productOpts = append(productOpts, service.WithProductStockPublisher(stockStream))
+		orderOpts = append(orderOpts, service.WithOrderStockPublisher(stockStream))
+		purchasingOpts = append(purchasingOpts, service.WithPurchasingStockPublisher(stockStream))
+		returnOpts = append(returnOpts, service.WithReturnStockPublisher(stockStream))
+	}
+	var lowStock *service.LowStockService
+	if cfg.Alerts.Enabled {
+		lowStock = newLowStockService(&cfg, repo)
+		productOpts = append(productOpts, service.WithProductStockPublisher(lowStock), service.WithProductLowStockChecks(lowStock))
+		orderOpts = append(orderOpts, service.WithOrderStockPublisher(lowStock))
+		purchasingOpts = append(purchasingOpts, service.WithPurchasingStockPublisher(lowStock))
+		returnOpts = append(returnOpts, service.WithReturnStockPublisher(lowStock))
+		go lowStock.Run(ctx)
+	}
+	var webhookSvc *service.WebhookService
+	if cfg.Outbox.Enabled {
+		productOpts = append(productOpts, service.WithProductEvents(repo))
+		orderOpts = append(orderOpts, service.WithOrderEvents(repo))
+		purchasingOpts = append(purchasingOpts, service.WithPurchasingEvents(repo))
+		returnOpts = append(returnOpts, service.WithReturnEvents(repo))
+		var sinks []outbox.Sink
+		if cfg.Webhooks.Enabled {
+			webhookSvc = service.NewWebhookService(repo,
+				service.WithWebhookSender(webhook.NewSender(time.Duration(cfg.Webhooks.Timeout)*time.Second)),
+				service.WithWebhookPolling(time.Duration(cfg.Webhooks.PollInterval)*time.Second, cfg.Webhooks.BatchSize),
+				service.WithWebhookRetries(cfg.Webhooks.MaxAttempts, 0, 0),
+			)
+			sinks = append(sinks, webhookSvc.Sink())
+			go webhookSvc.Run(ctx)
+		}
+		relay := newOutboxRelay(cfg.Outbox, repo, sinks...)
+		if sub := repo.Subscriber(); sub != nil {
+			sub.Subscribe(outbox.Channel, func(context.Context, *pgconn.Notification) { relay.Wake() })
+			// Events committed while the subscriber reconnected were not announced.
+			sub.OnListen(func(context.Context) { relay.Wake() })
+		}
+		go relay.Run(ctx)
+	}
+	if sub := repo.Subscriber(); sub != nil {
+		go sub.Run(ctx)
+	}
 
 	userSvc := service.NewUserService(repo)
-	productSvc := service.NewProductService(repo)
-	orderSvc := service.NewOrderService(repo, repo, repo, repo)
-
-	server, err := handler.NewServer(cfg.ListenAddr, userSvc, productSvc, orderSvc, logCfg.LogHttpRequests, sentryCfg != nil)
+	productSvc := service.NewProductService(repo, repo, productOpts...)
+	orderSvc := service.NewOrderService(repo, repo, repo, repo, orderOpts...)
+	purchasingSvc := service.NewPurchasingService(repo, repo, repo, purchasingOpts...)
+	returnSvc := service.NewReturnService(repo, repo, repo, repo, returnOpts...)
+	reorderSvc := service.NewReorderService(repo, repo,
+		service.WithReorderWindow(cfg.Reorder.WindowDays),
+		service.WithReorderLeadTime(cfg.Reorder.LeadTimeDays),
+		service.WithReorderCover(cfg.Reorder.CoverDays),
+	)
+
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+
+	serverOpts := []handler.ServerOption{
+		handler.WithRateLimiter(rateLimiter),
+		handler.WithHealthChecker(repo),
+		handler.WithBuildInfo(buildInfo()),
+		handler.WithAdminToken(cfg.Admin.Token),
+		handler.WithMaintenance(repo, cfg.Maintenance.RetryAfter),
+		handler.WithPurchasing(purchasingSvc),
+		handler.WithReorderSuggestions(reorderSvc),
+		handler.WithReturns(returnSvc),
+	}
+	if webhookSvc != nil {
+		serverOpts = append(serverOpts, handler.WithWebhooks(webhookSvc))
+	}
+	if stockStream != nil {
+		serverOpts = append(serverOpts, handler.WithStockStream(stockStream, time.Duration(cfg.Stream.HeartbeatInterval)*time.Second))
+	}
+	if lowStock != nil {
+		serverOpts = append(serverOpts, handler.WithLowStockAlerts(lowStock))
+	}
+	server, err := handler.NewServer(cfg.ListenAddr, userSvc, productSvc, orderSvc, logCfg.LogHttpRequests, sentryCfg != nil, serverOpts...)
 	if err != nil {
 		return err
 	}
 
+	go newReloader(*cfgPath, cfg, rateLimiter).run(ctx)
+
 	go func() {
 		<-ctx.Done()
+		server.Drain()
+		if cfg.ShutdownDelay > 0 {
+			logging.Info(context.Background(), "draining before shutdown", zap.Int("delay_seconds", cfg.ShutdownDelay))
+			time.Sleep(time.Duration(cfg.ShutdownDelay) * time.Second)
+		}
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		_ = server.Shutdown(shutdownCtx)
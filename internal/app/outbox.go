@@ -0,0 +1,26 @@
+package app
+
+import (
+	"time"
+
+	"stockpilot/internal/config"
+	"stockpilot/pkg/gonerve/outbox"
+)
+
+func newOutboxRelay(cfg config.OutboxConfig, store outbox.Store, extra ...outbox.Sink) *outbox.Relay {
+	sinks := []outbox.Sink{}
+	for _, name := range cfg.SinkNames() {
+		switch name {
+		case "stdout":
+			sinks = append(sinks, outbox.NewStdoutSink())
+		case "webhook":
+			sinks = append(sinks, outbox.NewWebhookSink(cfg.WebhookURL, time.Duration(cfg.WebhookTimeout)*time.Second))
+		}
+	}
+	sinks = append(sinks, extra...)
+	return outbox.NewRelay(store, sinks,
+		outbox.WithPollInterval(time.Duration(cfg.PollInterval)*time.Second),
+		outbox.WithBatchSize(cfg.BatchSize),
+		outbox.WithMaxAttempts(cfg.MaxAttempts),
+	)
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stockpilot/internal/config"
+	"stockpilot/internal/middleware"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/features"
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/tracing"
+)
+
+type reloader struct {
+	path        string
+	current     config.Config
+	modTime     time.Time
+	rateLimiter *middleware.RateLimiter
+}
+
+func newReloader(path string, cfg config.Config, rateLimiter *middleware.RateLimiter) *reloader {
+	r := &reloader{
+		path:        path,
+		current:     cfg,
+		rateLimiter: rateLimiter,
+	}
+	if st, err := os.Stat(path); err == nil {
+		r.modTime = st.ModTime()
+	}
+	return r
+}
+
+func (r *reloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if r.current.ReloadInterval > 0 {
+		ticker := time.NewTicker(time.Duration(r.current.ReloadInterval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logging.Info(ctx, "config reload requested by SIGHUP")
+			r.reload(ctx)
+		case <-tick:
+			st, err := os.Stat(r.path)
+			if err != nil {
+				logging.Warn(ctx, "config stat failed", zap.String("path", r.path), zap.Error(err))
+				continue
+			}
+			if st.ModTime().Equal(r.modTime) {
+				continue
+			}
+			r.modTime = st.ModTime()
+			logging.Info(ctx, "config file changed", zap.String("path", r.path))
+			r.reload(ctx)
+		}
+	}
+}
+
+func (r *reloader) reload(ctx context.Context) {
+	next := config.Config{}
+	if err := loadConfig(r.path, &next); err != nil {
+		logging.Error(ctx, "config reload failed", zap.Error(err))
+		return
+	}
+	if err := next.Validate(); err != nil {
+		logging.Error(ctx, "config reload rejected", zap.Error(err))
+		return
+	}
+	if err := r.apply(ctx, next); err != nil {
+		logging.Error(ctx, "config reload failed", zap.Error(err))
+		return
+	}
+}
+
+func (r *reloader) apply(ctx context.Context, next config.Config) error {
+	prev := r.current
+	applied := []string{}
+
+	if next.Log.Level != prev.Log.Level {
+		if l := logging.GlobalLogger(); l != nil {
+			if err := l.SetLevel(next.Log.Level); err != nil {
+				return errors.Wrap(err, "set log level")
+			}
+		}
+		applied = append(applied, "log.level")
+	}
+	if next.Tracing.SampleRatio != prev.Tracing.SampleRatio {
+		tracing.SetSampleRatio(next.Tracing.SampleRatio)
+		applied = append(applied, "tracing.sample_ratio")
+	}
+	if next.RateLimit != prev.RateLimit {
+		if r.rateLimiter != nil {
+			r.rateLimiter.Update(next.RateLimit.RequestsPerSecond, next.RateLimit.Burst)
+		}
+		applied = append(applied, "rate_limit")
+	}
+	if !reflect.DeepEqual(next.Features, prev.Features) {
+		features.Set(next.Features)
+		applied = append(applied, "features")
+	}
+
+	if pending := restartRequired(prev, next); len(pending) > 0 {
+		logging.Warn(ctx, "config changes require restart", zap.Strings("fields", pending))
+	}
+	if len(applied) > 0 {
+		logging.Info(ctx, "config reloaded", zap.Strings("applied", applied))
+	}
+
+	// Settings that need a restart keep their running values, so a later
+	// reload still reports them until the process is restarted.
+	live := prev
+	live.Log.Level = next.Log.Level
+	live.Tracing.SampleRatio = next.Tracing.SampleRatio
+	live.RateLimit = next.RateLimit
+	live.Features = next.Features
+	r.current = live
+	return nil
+}
+
+// restartRequired lists the changed settings other than the live-applied
+// ones, walking every top-level section so new ones are covered too.
+func restartRequired(prev, next config.Config) []string {
+	for _, c := range []*config.Config{&prev, &next} {
+		c.Log.Level = ""
+		c.Tracing.SampleRatio = 0
+		c.RateLimit = config.RateLimitConfig{}
+		c.Features = nil
+	}
+	return changedFields("", prev, next)
+}
+
+func changedFields(prefix string, prev, next any) []string {
+	pv, nv := reflect.ValueOf(prev), reflect.ValueOf(next)
+	t := pv.Type()
+	fields := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(pv.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		name := t.Field(i).Tag.Get("yaml")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if pv.Field(i).Kind() == reflect.Struct {
+			fields = append(fields, changedFields(name, pv.Field(i).Interface(), nv.Field(i).Interface())...)
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
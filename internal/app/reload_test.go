@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/config"
+	"stockpilot/internal/middleware"
+	"stockpilot/pkg/gonerve/features"
+	"stockpilot/pkg/gonerve/logging"
+)
+
+func TestRestartRequired(t *testing.T) {
+	prev := config.Config{
+		ListenAddr: ":8080",
+		PG:         config.PGConfig{Endpoint: "db:5432"},
+		Log:        config.LogConfig{Level: "info", Encoding: "json"},
+		Tracing:    config.TracingConfig{Endpoint: "otel:4317", SampleRatio: 1},
+	}
+
+	next := prev
+	next.Log.Level = "debug"
+	next.Tracing.SampleRatio = 0.5
+	next.RateLimit.RequestsPerSecond = 10
+	next.Features = map[string]bool{"beta": true}
+	require.Empty(t, restartRequired(prev, next))
+
+	next.ListenAddr = ":9090"
+	next.PG.Endpoint = "replica:5432"
+	next.Log.Encoding = "console"
+	next.Reorder.WindowDays = 14
+	require.Equal(t, []string{"listen_addr", "pg.endpoint", "log.encoding", "reorder.window_days"}, restartRequired(prev, next))
+}
+
+func TestReloaderApply(t *testing.T) {
+	require.NoError(t, logging.Init("stockpilot-tests", &logging.Config{Level: "info"}))
+
+	prev := config.Config{
+		ListenAddr: ":8080",
+		Log:        config.LogConfig{Level: "info"},
+	}
+	r := newReloader("config.yaml", prev, middleware.NewRateLimiter(0, 0))
+
+	next := prev
+	next.ListenAddr = ":9090"
+	next.Log.Level = "warn"
+	next.Features = map[string]bool{"partial_orders": true}
+	require.NoError(t, r.apply(context.Background(), next))
+
+	require.Equal(t, "warn", logging.GlobalLogger().GetLevel())
+	require.True(t, features.Enabled("partial_orders"))
+	require.Equal(t, ":8080", r.current.ListenAddr)
+	require.Equal(t, "warn", r.current.Log.Level)
+}
@@ -1,5 +1,7 @@
 package app
 
+import "stockpilot/internal/handler"
+
 var (
 	_Branch       = "dev"
 	_BuildVersion = "dev"
@@ -7,3 +9,13 @@ var (
 	_CommitDate   = "unknown"
 	_BuildDate    = "unknown"
 )
+
+func buildInfo() handler.BuildInfo {
+	return handler.BuildInfo{
+		Branch:     _Branch,
+		Version:    _BuildVersion,
+		CommitHash: _CommitHash,
+		CommitDate: _CommitDate,
+		BuildDate:  _BuildDate,
+	}
+}
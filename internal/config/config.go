@@ -2,9 +2,12 @@ package config
 
 import (
 	"strings"
+	"time"
 
+	"stockpilot/internal/domain"
 	"stockpilot/pkg/flagparser"
 	"stockpilot/pkg/gonerve/db"
+	"stockpilot/pkg/gonerve/errors"
 	"stockpilot/pkg/gonerve/logging"
 	"stockpilot/pkg/gonerve/postgresql"
 	"stockpilot/pkg/gonerve/sentry"
@@ -12,20 +15,43 @@ import (
 )
 
 type Config struct {
-	ListenAddr string        `json:"listen_addr" yaml:"listen_addr" flag:"listen-addr" default:":8080" usage:"http listen address"`
-	PG         PGConfig      `json:"pg" yaml:"pg" flag:"pg" default:"" usage:"postgres settings"`
-	Log        LogConfig     `json:"log" yaml:"log" flag:"log" default:"" usage:"logging settings"`
-	Sentry     SentryConfig  `json:"sentry" yaml:"sentry" flag:"sentry" default:"" usage:"sentry settings"`
-	Tracing    TracingConfig `json:"tracing" yaml:"tracing" flag:"tracing" default:"" usage:"tracing settings"`
+	ListenAddr     string            `json:"listen_addr" yaml:"listen_addr" flag:"listen-addr" default:":8080" usage:"http listen address"`
+	ReloadInterval int               `json:"reload_interval" yaml:"reload_interval" flag:"reload-interval" default:"0" usage:"config file check interval in seconds, 0 disables watching"`
+	ShutdownDelay  int               `json:"shutdown_delay" yaml:"shutdown_delay" flag:"shutdown-delay" default:"0" usage:"seconds to report not ready before shutting the http server down"`
+	PG             PGConfig          `json:"pg" yaml:"pg" flag:"pg" default:"" usage:"postgres settings"`
+	Log            LogConfig         `json:"log" yaml:"log" flag:"log" default:"" usage:"logging settings"`
+	Sentry         SentryConfig      `json:"sentry" yaml:"sentry" flag:"sentry" default:"" usage:"sentry settings"`
+	Tracing        TracingConfig     `json:"tracing" yaml:"tracing" flag:"tracing" default:"" usage:"tracing settings"`
+	RateLimit      RateLimitConfig   `json:"rate_limit" yaml:"rate_limit" flag:"rate-limit" default:"" usage:"rate limit settings"`
+	Admin          AdminConfig       `json:"admin" yaml:"admin" flag:"admin" default:"" usage:"admin api settings"`
+	Checkout       CheckoutConfig    `json:"checkout" yaml:"checkout" flag:"checkout" default:"" usage:"checkout settings"`
+	Maintenance    MaintenanceConfig `json:"maintenance" yaml:"maintenance" flag:"maintenance" default:"" usage:"maintenance mode settings"`
+	Outbox         OutboxConfig      `json:"outbox" yaml:"outbox" flag:"outbox" default:"" usage:"domain event outbox settings"`
+	Webhooks       WebhooksConfig    `json:"webhooks" yaml:"webhooks" flag:"webhooks" default:"" usage:"webhook subscription settings"`
+	Stream         StreamConfig      `json:"stream" yaml:"stream" flag:"stream" default:"" usage:"stock stream settings"`
+	Alerts         AlertsConfig      `json:"alerts" yaml:"alerts" flag:"alerts" default:"" usage:"low-stock alert settings"`
+	Reorder        ReorderConfig     `json:"reorder" yaml:"reorder" flag:"reorder" default:"" usage:"reorder suggestion settings"`
+	Features       map[string]bool   `json:"features" yaml:"features" flag:"-"`
 }
 
 type PGConfig struct {
-	Endpoint            string `json:"endpoint" yaml:"endpoint" flag:"pg-endpoint" default:"localhost:5432" usage:"postgres host:port"`
-	Database            string `json:"database" yaml:"database" flag:"pg-database" default:"stockpilot" usage:"postgres database"`
-	Username            string `json:"username" yaml:"username" flag:"pg-username" default:"postgres" usage:"postgres user"`
-	Password            string `json:"password" yaml:"password" flag:"pg-password" default:"postgres" usage:"postgres password"`
-	SSLMode             string `json:"sslmode" yaml:"sslmode" flag:"pg-sslmode" default:"disable" usage:"postgres sslmode"`
-	ListenNotifications bool   `json:"listen_notifications" yaml:"listen_notifications" flag:"pg-listen-notifications" default:"false" usage:"postgres listen notifications"`
+	Endpoint             string `json:"endpoint" yaml:"endpoint" flag:"pg-endpoint" default:"localhost:5432" usage:"postgres host:port"`
+	Database             string `json:"database" yaml:"database" flag:"pg-database" default:"stockpilot" usage:"postgres database"`
+	Username             string `json:"username" yaml:"username" flag:"pg-username" default:"postgres" usage:"postgres user"`
+	Password             string `json:"password" yaml:"password" flag:"pg-password" default:"postgres" usage:"postgres password"`
+	SSLMode              string `json:"sslmode" yaml:"sslmode" flag:"pg-sslmode" default:"disable" usage:"postgres sslmode"`
+	ListenNotifications  bool   `json:"listen_notifications" yaml:"listen_notifications" flag:"pg-listen-notifications" default:"false" usage:"postgres listen notifications"`
+	Replicas             string `json:"replicas" yaml:"replicas" flag:"pg-replicas" default:"" usage:"comma separated read replica host:port list"`
+	ReplicaMaxLag        int    `json:"replica_max_lag" yaml:"replica_max_lag" flag:"pg-replica-max-lag" default:"0" usage:"seconds of replication lag before a replica stops receiving reads, 0 disables the check"`
+	ReplicaCheckInterval int    `json:"replica_check_interval" yaml:"replica_check_interval" flag:"pg-replica-check-interval" default:"5" usage:"seconds between replica health checks"`
+	MaxConns             int    `json:"max_conns" yaml:"max_conns" flag:"pg-max-conns" default:"0" usage:"maximum pool size, 0 keeps the pgxpool default"`
+	MinConns             int    `json:"min_conns" yaml:"min_conns" flag:"pg-min-conns" default:"0" usage:"connections kept open when idle"`
+	MaxConnLifetime      int    `json:"max_conn_lifetime" yaml:"max_conn_lifetime" flag:"pg-max-conn-lifetime" default:"0" usage:"seconds before a connection is closed and replaced, 0 keeps the pgxpool default"`
+	MaxConnIdleTime      int    `json:"max_conn_idle_time" yaml:"max_conn_idle_time" flag:"pg-max-conn-idle-time" default:"0" usage:"seconds an idle connection is kept, 0 keeps the pgxpool default"`
+	HealthCheckPeriod    int    `json:"health_check_period" yaml:"health_check_period" flag:"pg-health-check-period" default:"0" usage:"seconds between idle connection health checks, 0 keeps the pgxpool default"`
+	ConnectTimeout       int    `json:"connect_timeout" yaml:"connect_timeout" flag:"pg-connect-timeout" default:"0" usage:"seconds to wait for a new connection, 0 waits indefinitely"`
+	StatementTimeout     int    `json:"statement_timeout" yaml:"statement_timeout" flag:"pg-statement-timeout" default:"0" usage:"seconds before the server cancels a statement, 0 disables the timeout"`
+	ApplicationName      string `json:"application_name" yaml:"application_name" flag:"pg-application-name" default:"stockpilot" usage:"application_name reported to postgres"`
 }
 
 type LogConfig struct {
@@ -36,11 +62,15 @@ type LogConfig struct {
 	Encoding          string `json:"encoding" yaml:"encoding" flag:"log-encoding" default:"json" usage:"log encoding"`
 	UltraHuman        bool   `json:"ultra_human" yaml:"ultra_human" flag:"log-ultra-human" default:"false" usage:"human friendly logs"`
 	LogHTTPRequests   bool   `json:"log_http_requests" yaml:"log_http_requests" flag:"log-http-requests" default:"true" usage:"log http requests"`
+	SampleInitial     int    `json:"sample_initial" yaml:"sample_initial" flag:"log-sample-initial" default:"0" usage:"entries with the same message logged per second before sampling, 0 disables sampling"`
+	SampleThereafter  int    `json:"sample_thereafter" yaml:"sample_thereafter" flag:"log-sample-thereafter" default:"0" usage:"log every n-th entry after sample_initial"`
+	RedactFields      string `json:"redact_fields" yaml:"redact_fields" flag:"log-redact-fields" default:"" usage:"comma separated log fields to mask in addition to password, password_hash, token, authorization"`
 }
 
 type SentryConfig struct {
 	DSN           string  `json:"dsn" yaml:"dsn" flag:"sentry-dsn" default:"" usage:"sentry dsn"`
 	ErrSampleRate float64 `json:"err_sample_rate" yaml:"err_sample_rate" flag:"sentry-sample-rate" default:"1" usage:"sentry error sample rate"`
+	Environment   string  `json:"environment" yaml:"environment" flag:"sentry-environment" default:"" usage:"sentry environment, defaults to the build branch"`
 }
 
 type TracingConfig struct {
@@ -48,17 +78,183 @@ type TracingConfig struct {
 	Endpoint    string  `json:"endpoint" yaml:"endpoint" flag:"trace-endpoint" default:"" usage:"otlp collector endpoint"`
 	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio" flag:"trace-sample" default:"1" usage:"trace sample ratio"`
 	Insecure    bool    `json:"insecure" yaml:"insecure" flag:"trace-insecure" default:"true" usage:"otlp insecure transport"`
+	Queries     bool    `json:"queries" yaml:"queries" flag:"trace-queries" default:"false" usage:"create a span per sql statement"`
+}
+
+type RateLimitConfig struct {
+	RequestsPerSecond int `json:"requests_per_second" yaml:"requests_per_second" flag:"rate-limit-rps" default:"0" usage:"allowed requests per second per client, 0 disables limiting"`
+	Burst             int `json:"burst" yaml:"burst" flag:"rate-limit-burst" default:"0" usage:"rate limit burst, defaults to requests per second"`
+}
+
+type AdminConfig struct {
+	Token string `json:"token" yaml:"token" flag:"admin-token" default:"" usage:"bearer token for /admin endpoints, empty disables them"`
+}
+
+type CheckoutConfig struct {
+	LockMode string `json:"lock_mode" yaml:"lock_mode" flag:"checkout-lock-mode" default:"wait" usage:"what checkout does with products locked by another order: wait, nowait or skip_locked"`
+}
+
+type MaintenanceConfig struct {
+	SyncInterval int `json:"sync_interval" yaml:"sync_interval" flag:"maintenance-sync-interval" default:"0" usage:"seconds between reads of the shared maintenance state, 0 keeps maintenance mode local to the instance"`
+	RetryAfter   int `json:"retry_after" yaml:"retry_after" flag:"maintenance-retry-after" default:"30" usage:"Retry-After seconds returned for writes during maintenance"`
 }
 
 func (c *Config) Load() error {
 	return flagparser.ParseFlags(c)
 }
 
+type OutboxConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled" flag:"outbox-enabled" default:"false" usage:"record domain events and relay them to sinks"`
+	Sinks          string `json:"sinks" yaml:"sinks" flag:"outbox-sinks" default:"stdout" usage:"comma separated event sinks: stdout, webhook"`
+	WebhookURL     string `json:"webhook_url" yaml:"webhook_url" flag:"outbox-webhook-url" default:"" usage:"url the webhook sink posts events to"`
+	WebhookTimeout int    `json:"webhook_timeout" yaml:"webhook_timeout" flag:"outbox-webhook-timeout" default:"10" usage:"webhook request timeout in seconds"`
+	PollInterval   int    `json:"poll_interval" yaml:"poll_interval" flag:"outbox-poll-interval" default:"1" usage:"seconds between outbox polls when no notification arrives"`
+	BatchSize      int    `json:"batch_size" yaml:"batch_size" flag:"outbox-batch-size" default:"100" usage:"events claimed per poll"`
+	MaxAttempts    int    `json:"max_attempts" yaml:"max_attempts" flag:"outbox-max-attempts" default:"10" usage:"failed deliveries before an event is dead-lettered"`
+}
+
+type WebhooksConfig struct {
+	Enabled      bool `json:"enabled" yaml:"enabled" flag:"webhooks-enabled" default:"false" usage:"deliver outbox events to webhook subscriptions"`
+	Timeout      int  `json:"timeout" yaml:"timeout" flag:"webhooks-timeout" default:"10" usage:"webhook request timeout in seconds"`
+	PollInterval int  `json:"poll_interval" yaml:"poll_interval" flag:"webhooks-poll-interval" default:"1" usage:"seconds between polls for due deliveries"`
+	BatchSize    int  `json:"batch_size" yaml:"batch_size" flag:"webhooks-batch-size" default:"50" usage:"deliveries claimed per poll"`
+	MaxAttempts  int  `json:"max_attempts" yaml:"max_attempts" flag:"webhooks-max-attempts" default:"8" usage:"attempts before a delivery is marked failed"`
+}
+
+type StreamConfig struct {
+	Enabled           bool `json:"enabled" yaml:"enabled" flag:"stream-enabled" default:"true" usage:"serve the server-sent events stock stream"`
+	HeartbeatInterval int  `json:"heartbeat_interval" yaml:"heartbeat_interval" flag:"stream-heartbeat-interval" default:"15" usage:"seconds between heartbeats on idle streams"`
+	BufferSize        int  `json:"buffer_size" yaml:"buffer_size" flag:"stream-buffer-size" default:"1024" usage:"latest stock events kept for Last-Event-ID resumes"`
+}
+
+type AlertsConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled" flag:"alerts-enabled" default:"true" usage:"raise low-stock alerts for products below their reorder point"`
+	Debounce       int    `json:"debounce" yaml:"debounce" flag:"alerts-debounce" default:"30" usage:"seconds a stock change settles before it is checked"`
+	Notifiers      string `json:"notifiers" yaml:"notifiers" flag:"alerts-notifiers" default:"log" usage:"comma separated alert notifiers: log, webhook"`
+	WebhookURL     string `json:"webhook_url" yaml:"webhook_url" flag:"alerts-webhook-url" default:"" usage:"url the webhook notifier posts alerts to"`
+	WebhookSecret  string `json:"webhook_secret" yaml:"webhook_secret" flag:"alerts-webhook-secret" default:"" usage:"secret the webhook notifier signs alerts with"`
+	WebhookTimeout int    `json:"webhook_timeout" yaml:"webhook_timeout" flag:"alerts-webhook-timeout" default:"10" usage:"webhook notifier timeout in seconds"`
+}
+
+type ReorderConfig struct {
+	WindowDays   int `json:"window_days" yaml:"window_days" flag:"reorder-window-days" default:"28" usage:"days of sales the velocity is averaged over"`
+	LeadTimeDays int `json:"lead_time_days" yaml:"lead_time_days" flag:"reorder-lead-time-days" default:"7" usage:"lead time of products never ordered from a supplier"`
+	CoverDays    int `json:"cover_days" yaml:"cover_days" flag:"reorder-cover-days" default:"7" usage:"days an order should last after it arrives"`
+}
+
+func (c OutboxConfig) SinkNames() []string {
+	return splitList(c.Sinks)
+}
+
+func (c AlertsConfig) NotifierNames() []string {
+	return splitList(c.Notifiers)
+}
+
+func (c Config) Validate() error {
+	if c.ListenAddr == "" {
+		return errors.New("listen_addr is required")
+	}
+	if c.ReloadInterval < 0 {
+		return errors.New("reload_interval cannot be negative")
+	}
+	if c.ShutdownDelay < 0 {
+		return errors.New("shutdown_delay cannot be negative")
+	}
+	if c.Log.Level != "" {
+		if err := logging.ValidateLevel(c.Log.Level); err != nil {
+			return errors.Wrap(err, "log.level")
+		}
+	}
+	if c.Log.SampleInitial < 0 || c.Log.SampleThereafter < 0 {
+		return errors.New("log sampling values cannot be negative")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return errors.New("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.RateLimit.RequestsPerSecond < 0 {
+		return errors.New("rate_limit.requests_per_second cannot be negative")
+	}
+	if c.RateLimit.Burst < 0 {
+		return errors.New("rate_limit.burst cannot be negative")
+	}
+	if _, err := domain.ParseLockMode(c.Checkout.LockMode); err != nil {
+		return errors.Wrap(err, "checkout.lock_mode")
+	}
+	if c.PG.ReplicaMaxLag < 0 || c.PG.ReplicaCheckInterval < 0 {
+		return errors.New("pg replica values cannot be negative")
+	}
+	if c.PG.MaxConns < 0 || c.PG.MinConns < 0 || c.PG.MaxConnLifetime < 0 || c.PG.MaxConnIdleTime < 0 ||
+		c.PG.HealthCheckPeriod < 0 || c.PG.ConnectTimeout < 0 || c.PG.StatementTimeout < 0 {
+		return errors.New("pg pool values cannot be negative")
+	}
+	if c.PG.MaxConns > 0 && c.PG.MinConns > c.PG.MaxConns {
+		return errors.New("pg min_conns cannot exceed max_conns")
+	}
+	if c.Maintenance.SyncInterval < 0 || c.Maintenance.RetryAfter < 0 {
+		return errors.New("maintenance values cannot be negative")
+	}
+	if c.Outbox.WebhookTimeout < 0 || c.Outbox.PollInterval < 0 || c.Outbox.BatchSize < 0 || c.Outbox.MaxAttempts < 0 {
+		return errors.New("outbox values cannot be negative")
+	}
+	if c.Outbox.Enabled {
+		for _, sink := range c.Outbox.SinkNames() {
+			switch sink {
+			case "stdout":
+			case "webhook":
+				if c.Outbox.WebhookURL == "" {
+					return errors.New("outbox.webhook_url is required for the webhook sink")
+				}
+			default:
+				return errors.New("unknown outbox sink " + sink)
+			}
+		}
+	}
+	if c.Webhooks.Timeout < 0 || c.Webhooks.PollInterval < 0 || c.Webhooks.BatchSize < 0 || c.Webhooks.MaxAttempts < 0 {
+		return errors.New("webhooks values cannot be negative")
+	}
+	if c.Webhooks.Enabled && !c.Outbox.Enabled {
+		return errors.New("webhooks require outbox.enabled")
+	}
+	if c.Stream.HeartbeatInterval < 0 || c.Stream.BufferSize < 0 {
+		return errors.New("stream values cannot be negative")
+	}
+	if c.Alerts.Debounce < 0 || c.Alerts.WebhookTimeout < 0 {
+		return errors.New("alerts values cannot be negative")
+	}
+	for _, n := range c.Alerts.NotifierNames() {
+		switch n {
+		case "log":
+		case "webhook":
+			if c.Alerts.WebhookURL == "" {
+				return errors.New("alerts.webhook_url is required for the webhook notifier")
+			}
+		default:
+			return errors.New("unknown alerts notifier " + n)
+		}
+	}
+	if c.Reorder.WindowDays < 1 || c.Reorder.WindowDays > 365 {
+		return errors.New("reorder.window_days must be between 1 and 365")
+	}
+	if c.Reorder.LeadTimeDays < 0 || c.Reorder.CoverDays < 0 {
+		return errors.New("reorder values cannot be negative")
+	}
+	return nil
+}
+
 func (c PGConfig) ToDBConfig() postgresql.Config {
 	options := map[string]any{}
 	if c.SSLMode != "" {
 		options["sslmode"] = c.SSLMode
 	}
+	if c.ConnectTimeout > 0 {
+		options["connect_timeout"] = c.ConnectTimeout
+	}
+	if c.StatementTimeout > 0 {
+		options["statement_timeout"] = c.StatementTimeout * 1000
+	}
+	if c.ApplicationName != "" {
+		options["application_name"] = c.ApplicationName
+	}
 	return postgresql.Config{
 		Config: db.Config{
 			Scheme:   "postgres",
@@ -69,18 +265,22 @@ func (c PGConfig) ToDBConfig() postgresql.Config {
 			Password: c.Password,
 			Options:  options,
 		},
-		ListenNotifications: c.ListenNotifications,
+		Pool: postgresql.PoolConfig{
+			MaxConns:          int32(c.MaxConns),
+			MinConns:          int32(c.MinConns),
+			MaxConnLifetime:   time.Duration(c.MaxConnLifetime) * time.Second,
+			MaxConnIdleTime:   time.Duration(c.MaxConnIdleTime) * time.Second,
+			HealthCheckPeriod: time.Duration(c.HealthCheckPeriod) * time.Second,
+		},
+		ListenNotifications:  c.ListenNotifications,
+		Replicas:             splitList(c.Replicas),
+		ReplicaMaxLag:        time.Duration(c.ReplicaMaxLag) * time.Second,
+		ReplicaCheckInterval: time.Duration(c.ReplicaCheckInterval) * time.Second,
 	}
 }
 
 func (c LogConfig) ToLoggingConfig() logging.Config {
-	outputs := []string{}
-	for _, v := range strings.Split(c.Output, ",") {
-		s := strings.TrimSpace(v)
-		if s != "" {
-			outputs = append(outputs, s)
-		}
-	}
+	outputs := splitList(c.Output)
 	if len(outputs) == 0 {
 		outputs = []string{"stdout"}
 	}
@@ -92,7 +292,22 @@ func (c LogConfig) ToLoggingConfig() logging.Config {
 		Encoding:          c.Encoding,
 		UltraHuman:        c.UltraHuman,
 		LogHttpRequests:   c.LogHTTPRequests,
+		Sampling: logging.Sampling{
+			Initial:    c.SampleInitial,
+			Thereafter: c.SampleThereafter,
+		},
+		RedactFields: splitList(c.RedactFields),
+	}
+}
+
+func splitList(v string) []string {
+	result := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if s := strings.TrimSpace(item); s != "" {
+			result = append(result, s)
+		}
 	}
+	return result
 }
 
 func (c SentryConfig) ToSentryConfig() *sentry.Config {
@@ -102,6 +317,7 @@ func (c SentryConfig) ToSentryConfig() *sentry.Config {
 	return &sentry.Config{
 		DSN:           c.DSN,
 		ErrSampleRate: c.ErrSampleRate,
+		Environment:   c.Environment,
 	}
 }
 
@@ -114,5 +330,6 @@ func (c TracingConfig) ToTracingConfig() *tracing.Config {
 		SampleRatio: c.SampleRatio,
 		Endpoint:    c.Endpoint,
 		Insecure:    c.Insecure,
+		Queries:     c.Queries,
 	}
 }
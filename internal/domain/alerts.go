@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type AlertSeverity string
+
+const (
+	AlertLow      AlertSeverity = "low"
+	AlertCritical AlertSeverity = "critical"
+)
+
+func LowStockSeverity(quantity, safetyStock int) AlertSeverity {
+	if quantity <= safetyStock {
+		return AlertCritical
+	}
+	return AlertLow
+}
+
+// LowStockAlert records a product falling below its reorder point. The
+// thresholds are copied so the alert still makes sense after they change.
+type LowStockAlert struct {
+	ID             string
+	ProductID      string
+	Severity       AlertSeverity
+	Quantity       int
+	ReorderPoint   int
+	SafetyStock    int
+	CreatedAt      time.Time
+	AcknowledgedAt *time.Time
+	AcknowledgedBy string
+}
+
+type LowStockNotifier interface {
+	Name() string
+	NotifyLowStock(ctx context.Context, alert LowStockAlert) error
+}
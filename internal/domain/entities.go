@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/shopspring/decimal"
+
+	"stockpilot/pkg/gonerve/errors"
 )
 
 type User struct {
@@ -36,16 +38,141 @@ type Product struct {
 	Tags        []string
 	Quantity    int
 	Price       decimal.Decimal
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// ReorderPoint is the level below which a low-stock alert is raised;
+	// zero disables alerts. Stock at or below SafetyStock is critical.
+	ReorderPoint int
+	SafetyStock  int
+	// AllowBackorder lets orders take more than is in stock; the short part
+	// waits for incoming stock.
+	AllowBackorder bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// LowStock reports whether p is below its reorder point.
+func (p Product) LowStock() bool {
+	return p.ReorderPoint > 0 && p.Quantity < p.ReorderPoint
+}
+
+// ErrInsufficientStock is returned when a change would take a product below
+// zero. Its message is part of the API.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+type StockChange struct {
+	ProductID string
+	Delta     int
+}
+
+// LockMode controls what locking product rows for an order does when
+// another order already holds them.
+type LockMode string
+
+const (
+	LockWait       LockMode = "wait"
+	LockNoWait     LockMode = "nowait"
+	LockSkipLocked LockMode = "skip_locked"
+)
+
+func ParseLockMode(v string) (LockMode, error) {
+	switch LockMode(v) {
+	case "", LockWait:
+		return LockWait, nil
+	case LockNoWait, LockSkipLocked:
+		return LockMode(v), nil
+	}
+	return "", errors.New("unknown lock mode " + v)
+}
+
+type OrderStatus string
+
+const (
+	OrderCreated   OrderStatus = "created"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderCreated: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+	OrderShipped: {OrderDelivered},
+}
+
+func ParseOrderStatus(v string) (OrderStatus, error) {
+	switch s := OrderStatus(v); s {
+	case OrderCreated, OrderPaid, OrderShipped, OrderDelivered, OrderCancelled:
+		return s, nil
+	}
+	return "", errors.New("unknown order status " + v)
+}
+
+// CanTransition reports whether an order may move from s to next.
+func (s OrderStatus) CanTransition(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
 }
 
 type Order struct {
 	ID         string
 	UserID     string
+	Status     OrderStatus
 	CreatedAt  time.Time
+	UpdatedAt  time.Time
 	TotalPrice decimal.Decimal
 	Items      []OrderItem
+	// Lines is what was requested against what was accepted, per requested
+	// line. It is only set on a newly created order.
+	Lines []OrderLine
+}
+
+type OrderLine struct {
+	ProductID string
+	Requested int
+	Accepted  int
+}
+
+// FulfillmentPolicy decides what an order does when there is not enough
+// stock for it.
+type FulfillmentPolicy string
+
+const (
+	// FulfillAllOrNothing rejects the whole order, unless the product
+	// allows backorders.
+	FulfillAllOrNothing FulfillmentPolicy = "all_or_nothing"
+	// FulfillPartial accepts what is in stock and drops the rest.
+	FulfillPartial FulfillmentPolicy = "partial"
+)
+
+func ParseFulfillmentPolicy(v string) (FulfillmentPolicy, error) {
+	switch FulfillmentPolicy(v) {
+	case "", FulfillAllOrNothing:
+		return FulfillAllOrNothing, nil
+	case FulfillPartial:
+		return FulfillPartial, nil
+	}
+	return "", errors.New("unknown fulfillment policy " + v)
+}
+
+type FulfillmentStatus string
+
+const (
+	OrderFulfilled   FulfillmentStatus = "fulfilled"
+	OrderBackordered FulfillmentStatus = "backordered"
+)
+
+// Fulfillment reports whether any item of o still waits for stock.
+func (o Order) Fulfillment() FulfillmentStatus {
+	for _, item := range o.Items {
+		if item.Backordered > 0 {
+			return OrderBackordered
+		}
+	}
+	return OrderFulfilled
 }
 
 type OrderItem struct {
@@ -54,4 +181,32 @@ type OrderItem struct {
 	ProductID string
 	Quantity  int
 	Price     decimal.Decimal
+	// Backordered is the part of Quantity still waiting for stock.
+	Backordered int
+}
+
+type BackorderStatus string
+
+const (
+	BackorderOpen      BackorderStatus = "open"
+	BackorderFilled    BackorderStatus = "filled"
+	BackorderCancelled BackorderStatus = "cancelled"
+)
+
+// Backorder is the short part of an order item. Incoming stock fills open
+// backorders of the product oldest first before it goes on the shelf.
+type Backorder struct {
+	ID             string
+	OrderID        string
+	OrderItemID    string
+	ProductID      string
+	Quantity       int
+	FilledQuantity int
+	Status         BackorderStatus
+	CreatedAt      time.Time
+	ClosedAt       *time.Time
+}
+
+func (b Backorder) Outstanding() int {
+	return b.Quantity - b.FilledQuantity
 }
@@ -0,0 +1,108 @@
+package domain
+
+import "time"
+
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderStatusChanged = "order.status_changed"
+	EventOrderFulfilled     = "order.fulfilled"
+	EventStockAdjusted      = "stock.adjusted"
+	EventStockLow           = "stock.low"
+	EventPurchaseReceived   = "purchase_order.received"
+	EventReturnReceived     = "return.received"
+
+	AggregateOrder         = "order"
+	AggregateProduct       = "product"
+	AggregatePurchaseOrder = "purchase_order"
+	AggregateReturn        = "return"
+)
+
+type OrderCreatedEvent struct {
+	OrderID     string                  `json:"order_id"`
+	UserID      string                  `json:"user_id"`
+	Status      OrderStatus             `json:"status"`
+	Fulfillment FulfillmentStatus       `json:"fulfillment_status"`
+	TotalPrice  string                  `json:"total_price"`
+	Items       []OrderCreatedEventItem `json:"items"`
+	CreatedAt   time.Time               `json:"created_at"`
+}
+
+type OrderCreatedEventItem struct {
+	ProductID   string `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	Backordered int    `json:"backordered,omitempty"`
+	Price       string `json:"price"`
+}
+
+type OrderStatusChangedEvent struct {
+	OrderID   string      `json:"order_id"`
+	From      OrderStatus `json:"from"`
+	To        OrderStatus `json:"to"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+// OrderFulfilledEvent is recorded when the last open backorder of an order
+// is filled.
+type OrderFulfilledEvent struct {
+	OrderID     string    `json:"order_id"`
+	FulfilledAt time.Time `json:"fulfilled_at"`
+}
+
+type StockAdjustedEvent struct {
+	ProductID string `json:"product_id"`
+	Delta     int    `json:"delta"`
+	// Backordered is the part of a positive delta that went to backorders
+	// instead of the shelf.
+	Backordered int       `json:"backordered,omitempty"`
+	Quantity    int       `json:"quantity"`
+	Reason      string    `json:"reason"`
+	AdjustedAt  time.Time `json:"adjusted_at"`
+}
+
+type StockLowEvent struct {
+	AlertID      string        `json:"alert_id"`
+	ProductID    string        `json:"product_id"`
+	Severity     AlertSeverity `json:"severity"`
+	Quantity     int           `json:"quantity"`
+	ReorderPoint int           `json:"reorder_point"`
+	SafetyStock  int           `json:"safety_stock"`
+	RaisedAt     time.Time     `json:"raised_at"`
+}
+
+type PurchaseReceivedEvent struct {
+	PurchaseOrderID string                      `json:"purchase_order_id"`
+	ReceiptID       string                      `json:"receipt_id"`
+	Status          PurchaseOrderStatus         `json:"status"`
+	ReceivedBy      string                      `json:"received_by"`
+	Lines           []PurchaseReceivedEventLine `json:"lines"`
+	ReceivedAt      time.Time                   `json:"received_at"`
+}
+
+type PurchaseReceivedEventLine struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type ReturnReceivedEvent struct {
+	ReturnID     string                    `json:"return_id"`
+	OrderID      string                    `json:"order_id"`
+	RefundID     string                    `json:"refund_id"`
+	RefundAmount string                    `json:"refund_amount"`
+	Lines        []ReturnReceivedEventLine `json:"lines"`
+	ReceivedAt   time.Time                 `json:"received_at"`
+}
+
+type ReturnReceivedEventLine struct {
+	OrderItemID string `json:"order_item_id"`
+	ProductID   string `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	Price       string `json:"price"`
+}
+
+// StockLevel is the quantity of a product after a committed change, as sent
+// on the stock stream.
+type StockLevel struct {
+	ProductID string    `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -2,8 +2,11 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+
+	"stockpilot/pkg/gonerve/outbox"
 )
 
 type UserRepository interface {
@@ -15,14 +18,210 @@ type UserRepository interface {
 type ProductRepository interface {
 	CreateProduct(ctx context.Context, product *Product) (*Product, error)
 	GetProductByID(ctx context.Context, id string) (*Product, error)
-	GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []string) ([]Product, error)
+	GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []string, mode LockMode) ([]Product, error)
 	UpdateQuantity(ctx context.Context, tx pgx.Tx, id string, delta int) error
+	UpdateQuantities(ctx context.Context, tx pgx.Tx, changes []StockChange) error
+	SetReorderPolicy(ctx context.Context, id string, reorderPoint, safetyStock int) (*Product, error)
+	SetBackorderPolicy(ctx context.Context, id string, allow bool) (*Product, error)
+	ListStockLevels(ctx context.Context) ([]StockLevel, error)
 }
 
 type OrderRepository interface {
+	// CreateOrder stores the order and its items, and opens a backorder for
+	// every item with a Backordered part.
 	CreateOrder(ctx context.Context, tx pgx.Tx, order *Order, items []OrderItem) (*Order, error)
+	GetOrder(ctx context.Context, id string) (*Order, error)
+	GetOrderForUpdate(ctx context.Context, tx pgx.Tx, id string) (*Order, error)
+	UpdateOrderStatus(ctx context.Context, tx pgx.Tx, id string, status OrderStatus) (*Order, error)
+}
+
+// BackorderRepository is only called with the product rows involved
+// locked, which serializes filling and cancelling per product.
+type BackorderRepository interface {
+	// ListOpenBackorders returns the open backorders of a product, oldest
+	// first.
+	ListOpenBackorders(ctx context.Context, tx pgx.Tx, productID string) ([]Backorder, error)
+	// FillBackorder allocates quantity to the backorder and closes it once
+	// it is filled.
+	FillBackorder(ctx context.Context, tx pgx.Tx, id string, quantity int) error
+	// FulfilledOrders returns the given orders that have no open backorders
+	// left. It first takes a lock per order, in id order, that nothing else
+	// takes, so transactions closing the last backorders of an order on
+	// different products see each other without a lock on the order row.
+	FulfilledOrders(ctx context.Context, tx pgx.Tx, orderIDs []string) ([]string, error)
+	CancelBackorders(ctx context.Context, tx pgx.Tx, orderID string) error
+}
+
+// OutboxRepository stores events in the caller's transaction, so they are
+// published if and only if the change that produced them commits.
+type OutboxRepository interface {
+	AddEvents(ctx context.Context, tx pgx.Tx, events ...outbox.Event) error
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, w *WebhookSubscription) (*WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id string) (*WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]WebhookSubscription, error)
+	UpdateWebhook(ctx context.Context, w *WebhookSubscription) (*WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// EnqueueWebhookDeliveries creates a pending delivery of e for every
+	// active subscription that wants it. Enqueuing the same event again is a
+	// no-op, so outbox redeliveries do not duplicate webhooks.
+	EnqueueWebhookDeliveries(ctx context.Context, e outbox.Event) (int, error)
+	// ClaimWebhookDeliveries returns due pending deliveries and hides them
+	// from other claims for lease.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	// RecordWebhookAttempt logs an attempt and stores the delivery state
+	// that follows from it.
+	RecordWebhookAttempt(ctx context.Context, d WebhookDelivery, a WebhookAttempt) error
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, []WebhookAttempt, error)
+	// ReplayWebhookDelivery makes a delivery pending and due now with a
+	// fresh attempt budget.
+	ReplayWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+}
+
+type LowStockAlertRepository interface {
+	// RaiseLowStockAlert opens an alert when the product fell below its
+	// reorder point since the previous call and has no open alert yet.
+	// Otherwise it returns nil.
+	RaiseLowStockAlert(ctx context.Context, tx pgx.Tx, productID string) (*LowStockAlert, error)
+	ListLowStockAlerts(ctx context.Context, includeAcknowledged bool, limit int) ([]LowStockAlert, error)
+	AcknowledgeLowStockAlert(ctx context.Context, id, by string) (*LowStockAlert, error)
+}
+
+type PurchasingRepository interface {
+	CreateSupplier(ctx context.Context, s *Supplier) (*Supplier, error)
+	GetSupplier(ctx context.Context, id string) (*Supplier, error)
+	ListSuppliers(ctx context.Context) ([]Supplier, error)
+	UpdateSupplier(ctx context.Context, s *Supplier) (*Supplier, error)
+
+	// CreatePurchaseOrder stores the order with its lines. It fails with
+	// "product not found" when a line refers to an unknown product.
+	CreatePurchaseOrder(ctx context.Context, tx pgx.Tx, po *PurchaseOrder) (*PurchaseOrder, error)
+	GetPurchaseOrder(ctx context.Context, id string) (*PurchaseOrder, error)
+	ListPurchaseOrders(ctx context.Context, status PurchaseOrderStatus, limit int) ([]PurchaseOrder, error)
+	GetPurchaseOrderForUpdate(ctx context.Context, tx pgx.Tx, id string) (*PurchaseOrder, error)
+	UpdatePurchaseOrderStatus(ctx context.Context, tx pgx.Tx, id string, status PurchaseOrderStatus) (*PurchaseOrder, error)
+	// RecordPurchaseReceipt stores the receipt and adds its quantities to
+	// the received quantities of the order lines.
+	RecordPurchaseReceipt(ctx context.Context, tx pgx.Tx, receipt *PurchaseReceipt) (*PurchaseReceipt, error)
+	ListPurchaseReceipts(ctx context.Context, purchaseOrderID string) ([]PurchaseReceipt, error)
+}
+
+type ReturnRepository interface {
+	// CreateReturn stores the return with its lines.
+	CreateReturn(ctx context.Context, tx pgx.Tx, r *Return) (*Return, error)
+	// ReturnedQuantities sums the quantities of every return of an order by
+	// order item id.
+	ReturnedQuantities(ctx context.Context, tx pgx.Tx, orderID string) (map[string]int, error)
+	GetReturn(ctx context.Context, id string) (*Return, error)
+	GetReturnForUpdate(ctx context.Context, tx pgx.Tx, id string) (*Return, error)
+	ListReturns(ctx context.Context, orderID string) ([]Return, error)
+	// UpdateReturnStatus also stamps the time the return was received or
+	// resolved.
+	UpdateReturnStatus(ctx context.Context, tx pgx.Tx, id string, status ReturnStatus) (*Return, error)
+	CreateRefund(ctx context.Context, tx pgx.Tx, refund *Refund) (*Refund, error)
+}
+
+type ReorderRepository interface {
+	// ListReorderCandidates returns every product with its units sold since
+	// the given time and its quantity still on order.
+	ListReorderCandidates(ctx context.Context, tx pgx.Tx, since time.Time) ([]ReorderCandidate, error)
+}
+
+// StockPublisher is told about new stock levels once the transaction that
+// changed them has committed.
+type StockPublisher interface {
+	PublishStock(levels ...StockLevel)
+}
+
+// StockNotifier tells the other instances about stock levels, in the
+// transaction that changed them or right away when tx is nil, so they only
+// hear about committed levels.
+type StockNotifier interface {
+	NotifyStock(ctx context.Context, tx pgx.Tx, levels ...StockLevel) error
+}
+
+// LowStockChecker re-evaluates the low-stock alerts of products whose
+// thresholds changed without their stock moving.
+type LowStockChecker interface {
+	ScheduleCheck(productIDs ...string)
 }
 
 type TxManager interface {
-	WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error) error
+	WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error, opts ...TxOption) error
+}
+
+type TxIsolation int
+
+const (
+	IsolationDefault TxIsolation = iota
+	IsolationReadCommitted
+	IsolationRepeatableRead
+	IsolationSerializable
+)
+
+// TxOptions are the transaction settings services can ask a TxManager for;
+// adapters map them to their own.
+type TxOptions struct {
+	Isolation  TxIsolation
+	ReadOnly   bool
+	Deferrable bool
+	// Replica lets a read-only transaction see data that lags the primary.
+	Replica          bool
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+}
+
+type TxOption func(o *TxOptions)
+
+func NewTxOptions(opts ...TxOption) TxOptions {
+	var o TxOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithIsolation(level TxIsolation) TxOption {
+	return func(o *TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly runs the transaction read-only, which is allowed in maintenance
+// mode.
+func ReadOnly() TxOption {
+	return func(o *TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// Deferrable only has an effect for serializable read-only transactions.
+func Deferrable() TxOption {
+	return func(o *TxOptions) {
+		o.Deferrable = true
+	}
+}
+
+// OnReplica runs a read-only transaction on a read replica when there is
+// one.
+func OnReplica() TxOption {
+	return func(o *TxOptions) {
+		o.Replica = true
+	}
+}
+
+func WithStatementTimeout(d time.Duration) TxOption {
+	return func(o *TxOptions) {
+		o.StatementTimeout = d
+	}
+}
+
+func WithLockTimeout(d time.Duration) TxOption {
+	return func(o *TxOptions) {
+		o.LockTimeout = d
+	}
 }
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"time"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type Supplier struct {
+	ID          string
+	Name        string
+	ContactName string
+	Email       string
+	Phone       string
+	// LeadTimeDays is how long the supplier usually takes to deliver.
+	LeadTimeDays int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"
+	PurchaseOrderSent              PurchaseOrderStatus = "sent"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received"
+	PurchaseOrderReceived          PurchaseOrderStatus = "received"
+)
+
+var purchaseOrderTransitions = map[PurchaseOrderStatus][]PurchaseOrderStatus{
+	PurchaseOrderDraft:             {PurchaseOrderSent},
+	PurchaseOrderSent:              {PurchaseOrderPartiallyReceived, PurchaseOrderReceived},
+	PurchaseOrderPartiallyReceived: {PurchaseOrderPartiallyReceived, PurchaseOrderReceived},
+}
+
+func ParsePurchaseOrderStatus(v string) (PurchaseOrderStatus, error) {
+	switch s := PurchaseOrderStatus(v); s {
+	case PurchaseOrderDraft, PurchaseOrderSent, PurchaseOrderPartiallyReceived, PurchaseOrderReceived:
+		return s, nil
+	}
+	return "", errors.New("unknown purchase order status " + v)
+}
+
+// CanTransition reports whether a purchase order may move from s to next.
+// The received statuses are only reached by receiving goods.
+func (s PurchaseOrderStatus) CanTransition(next PurchaseOrderStatus) bool {
+	for _, allowed := range purchaseOrderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Receivable reports whether goods can be received against the order.
+func (s PurchaseOrderStatus) Receivable() bool {
+	return s.CanTransition(PurchaseOrderReceived)
+}
+
+type PurchaseOrder struct {
+	ID           string
+	SupplierID   string
+	Status       PurchaseOrderStatus
+	ExpectedDate *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Lines        []PurchaseOrderLine
+}
+
+// FullyReceived reports whether every line has been received in full.
+func (po PurchaseOrder) FullyReceived() bool {
+	for _, l := range po.Lines {
+		if l.Outstanding() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type PurchaseOrderLine struct {
+	ID               string
+	PurchaseOrderID  string
+	ProductID        string
+	Quantity         int
+	ReceivedQuantity int
+}
+
+func (l PurchaseOrderLine) Outstanding() int {
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// PurchaseReceipt records one delivery received against a purchase order.
+type PurchaseReceipt struct {
+	ID              string
+	PurchaseOrderID string
+	ReceivedBy      string
+	ReceivedAt      time.Time
+	Lines           []PurchaseReceiptLine
+}
+
+type PurchaseReceiptLine struct {
+	LineID    string
+	ProductID string
+	Quantity  int
+}
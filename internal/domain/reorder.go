@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// ReorderCandidate is a product with the figures a reorder suggestion is
+// computed from.
+type ReorderCandidate struct {
+	ProductID    string
+	Description  string
+	Quantity     int
+	ReorderPoint int
+	SafetyStock  int
+	// UnitsSold over the velocity window, cancelled orders excluded.
+	UnitsSold int
+	// OnOrder is what open purchase orders still have to deliver.
+	OnOrder int
+	// Backordered is what open backorders still wait for; incoming stock
+	// goes to them first.
+	Backordered int
+	// The supplier of the most recent purchase order for the product, if
+	// it was ever ordered.
+	SupplierID   string
+	SupplierName string
+	LeadTimeDays *int
+}
+
+type ReorderParams struct {
+	WindowDays          int
+	DefaultLeadTimeDays int
+	// CoverDays is how long an order should last after it arrives, usually
+	// the time until the next reorder review.
+	CoverDays int
+}
+
+type ReorderSuggestion struct {
+	ProductID     string
+	Description   string
+	SupplierID    string
+	SupplierName  string
+	Quantity      int
+	OnOrder       int
+	UnitsSold     int
+	DailyVelocity float64
+	LeadTimeDays  int
+	// DaysOfCover is how long the stock on hand lasts at the current
+	// velocity; nil when nothing sold.
+	DaysOfCover       *float64
+	ReorderLevel      int
+	ReorderBy         time.Time
+	SuggestedQuantity int
+}
+
+// Suggest reports whether the product has to be reordered within the next
+// CoverDays, and how much. The reorder level is the demand over the lead
+// time plus safety stock, but never below the product's reorder point; stock
+// on hand and on order counts towards it, less what is owed to backorders.
+func (c ReorderCandidate) Suggest(p ReorderParams, today time.Time) (ReorderSuggestion, bool) {
+	velocity := float64(c.UnitsSold) / float64(p.WindowDays)
+	lead := p.DefaultLeadTimeDays
+	if c.LeadTimeDays != nil {
+		lead = *c.LeadTimeDays
+	}
+	level := max(c.ReorderPoint, int(math.Ceil(velocity*float64(lead)))+c.SafetyStock)
+	position := c.Quantity + c.OnOrder - c.Backordered
+
+	days := 0
+	if position > level {
+		if velocity == 0 {
+			return ReorderSuggestion{}, false
+		}
+		days = int(math.Floor(float64(position-level) / velocity))
+		if days > p.CoverDays {
+			return ReorderSuggestion{}, false
+		}
+	}
+	target := max(level, int(math.Ceil(velocity*float64(days+lead+p.CoverDays)))+c.SafetyStock)
+	if target <= position {
+		return ReorderSuggestion{}, false
+	}
+
+	s := ReorderSuggestion{
+		ProductID:         c.ProductID,
+		Description:       c.Description,
+		SupplierID:        c.SupplierID,
+		SupplierName:      c.SupplierName,
+		Quantity:          c.Quantity,
+		OnOrder:           c.OnOrder,
+		UnitsSold:         c.UnitsSold,
+		DailyVelocity:     velocity,
+		LeadTimeDays:      lead,
+		ReorderLevel:      level,
+		ReorderBy:         today.AddDate(0, 0, days),
+		SuggestedQuantity: target - position,
+	}
+	if velocity > 0 {
+		cover := float64(c.Quantity) / velocity
+		s.DaysOfCover = &cover
+	}
+	return s, true
+}
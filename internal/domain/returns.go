@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnAuthorized ReturnStatus = "authorized"
+	ReturnReceived   ReturnStatus = "received"
+	ReturnRestocked  ReturnStatus = "restocked"
+	// ReturnWrittenOff means the goods came back damaged and do not go back
+	// into stock.
+	ReturnWrittenOff ReturnStatus = "written_off"
+)
+
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnAuthorized: {ReturnReceived},
+	ReturnReceived:   {ReturnRestocked, ReturnWrittenOff},
+}
+
+func ParseReturnStatus(v string) (ReturnStatus, error) {
+	switch s := ReturnStatus(v); s {
+	case ReturnAuthorized, ReturnReceived, ReturnRestocked, ReturnWrittenOff:
+		return s, nil
+	}
+	return "", errors.New("unknown return status " + v)
+}
+
+func (s ReturnStatus) CanTransition(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Return is a return authorization (RMA) for items of a delivered order.
+type Return struct {
+	ID         string
+	OrderID    string
+	Reason     string
+	Status     ReturnStatus
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReceivedAt *time.Time
+	// ResolvedAt is when the goods were restocked or written off.
+	ResolvedAt *time.Time
+	Lines      []ReturnLine
+	// Refund is set once the goods are received.
+	Refund *Refund
+}
+
+// RefundAmount is what the returned items cost when they were ordered.
+func (r Return) RefundAmount() decimal.Decimal {
+	total := decimal.Zero
+	for _, l := range r.Lines {
+		total = total.Add(l.Price.Mul(decimal.NewFromInt(int64(l.Quantity))))
+	}
+	return total
+}
+
+type ReturnLine struct {
+	ID          string
+	ReturnID    string
+	OrderItemID string
+	ProductID   string
+	Quantity    int
+	// Price is the unit price of the order item.
+	Price decimal.Decimal
+}
+
+type Refund struct {
+	ID        string
+	ReturnID  string
+	OrderID   string
+	Amount    decimal.Decimal
+	CreatedAt time.Time
+}
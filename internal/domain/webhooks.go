@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// EventTypes lists the events webhook subscriptions can filter on.
+var EventTypes = []string{
+	EventOrderCreated,
+	EventOrderStatusChanged,
+	EventOrderFulfilled,
+	EventStockAdjusted,
+	EventStockLow,
+	EventPurchaseReceived,
+	EventReturnReceived,
+}
+
+type WebhookSubscription struct {
+	ID          string
+	URL         string
+	Secret      string
+	Description string
+	// EventTypes filters delivered events; empty means every event.
+	EventTypes []string
+	Active     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (s WebhookSubscription) Wants(eventType string) bool {
+	return s.Active && (len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, eventType))
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookSucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookFailed deliveries ran out of attempts; only a replay sends them
+	// again.
+	WebhookFailed WebhookDeliveryStatus = "failed"
+)
+
+type WebhookDelivery struct {
+	ID             string
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	// Payload is the request body, kept so replays send the same bytes.
+	Payload        json.RawMessage
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	// URL and Secret are filled from the subscription for claimed deliveries.
+	URL    string
+	Secret string
+}
+
+type WebhookAttempt struct {
+	DeliveryID      string
+	Attempt         int
+	StatusCode      int
+	Latency         time.Duration
+	ResponseSnippet string
+	Error           string
+	CreatedAt       time.Time
+}
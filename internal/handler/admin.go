@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"stockpilot/internal/middleware"
+	"stockpilot/pkg/gonerve/logging"
+)
+
+type LogLevelRequest struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+}
+
+type LogLevelResponse struct {
+	Level   string              `json:"level"`
+	Loggers []logging.LevelInfo `json:"loggers"`
+}
+
+func WithAdminToken(token string) ServerOption {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+func (s *Server) registerAdmin(e *echo.Echo) *echo.Group {
+	if s.adminToken == "" {
+		return nil
+	}
+	g := e.Group("/admin", middleware.AdminAuth(s.adminToken))
+	g.GET("/log/level", s.GetLogLevel)
+	g.PUT("/log/level", s.SetLogLevel)
+	g.PUT("/log/level/:name", s.SetLogLevel)
+	g.DELETE("/log/level/:name", s.ResetLogLevel)
+	if s.maintenance != nil {
+		g.GET("/maintenance", s.GetMaintenance)
+		g.PUT("/maintenance", s.SetMaintenance)
+	}
+	if s.webhooks != nil {
+		s.registerWebhooks(g)
+	}
+	return g
+}
+
+// GetLogLevel godoc
+// @Summary Get global and per-logger log levels
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} LogLevelResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/log/level [get]
+func (s *Server) GetLogLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, logLevelResponse())
+}
+
+// SetLogLevel godoc
+// @Summary Change the global or a named logger level, optionally for a limited time
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string false "logger name, e.g. query, http, tx"
+// @Param request body LogLevelRequest true "level and optional duration, e.g. 10m"
+// @Success 200 {object} LogLevelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/log/level [put]
+// @Router /admin/log/level/{name} [put]
+func (s *Server) SetLogLevel(c echo.Context) error {
+	var req LogLevelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		d, err = time.ParseDuration(req.Duration)
+		if err != nil || d < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid duration"})
+		}
+	}
+	l := logging.GlobalLogger()
+	if name := c.Param("name"); name != "" {
+		var ok bool
+		if l, ok = logging.Lookup(name); !ok {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Message: "logger not found"})
+		}
+	}
+	if l == nil {
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{Message: "logger is not initialized"})
+	}
+	if err := l.SetLevelFor(req.Level, d); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid level"})
+	}
+	logging.Info(c.Request().Context(), "log level changed",
+		zap.String("logger", c.Param("name")),
+		zap.String("level", req.Level),
+		zap.Duration("duration", d),
+	)
+	return c.JSON(http.StatusOK, logLevelResponse())
+}
+
+// ResetLogLevel godoc
+// @Summary Make a named logger follow the global level again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "logger name"
+// @Success 200 {object} LogLevelResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/log/level/{name} [delete]
+func (s *Server) ResetLogLevel(c echo.Context) error {
+	l, ok := logging.Lookup(c.Param("name"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Message: "logger not found"})
+	}
+	l.ResetLevel()
+	return c.JSON(http.StatusOK, logLevelResponse())
+}
+
+func logLevelResponse() LogLevelResponse {
+	resp := LogLevelResponse{Loggers: logging.Levels()}
+	if l := logging.GlobalLogger(); l != nil {
+		resp.Level = l.GetLevel()
+	}
+	return resp
+}
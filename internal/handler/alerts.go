@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/service"
+)
+
+// WithLowStockAlerts enables the low-stock alert endpoints.
+func WithLowStockAlerts(alerts *service.LowStockService) ServerOption {
+	return func(s *Server) {
+		s.alerts = alerts
+	}
+}
+
+type LowStockAlertResponse struct {
+	ID             string     `json:"id"`
+	ProductID      string     `json:"product_id"`
+	Severity       string     `json:"severity"`
+	Quantity       int        `json:"quantity"`
+	ReorderPoint   int        `json:"reorder_point"`
+	SafetyStock    int        `json:"safety_stock"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+}
+
+type AcknowledgeAlertRequest struct {
+	AcknowledgedBy string `json:"acknowledged_by"`
+}
+
+// ListLowStockAlerts godoc
+// @Summary List low-stock alerts, newest first
+// @Tags alerts
+// @Produce json
+// @Param status query string false "open (default) or all"
+// @Param limit query int false "at most 100"
+// @Success 200 {array} LowStockAlertResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/alerts/low-stock [get]
+func (h *Handler) ListLowStockAlerts(c echo.Context) error {
+	var includeAcknowledged bool
+	switch c.QueryParam("status") {
+	case "", "open":
+	case "all":
+		includeAcknowledged = true
+	default:
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid status"})
+	}
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil && c.QueryParam("limit") != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid limit"})
+	}
+	alerts, err := h.alerts.List(c.Request().Context(), includeAcknowledged, limit)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	resp := make([]LowStockAlertResponse, 0, len(alerts))
+	for i := range alerts {
+		resp = append(resp, toLowStockAlertResponse(&alerts[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// AcknowledgeLowStockAlert godoc
+// @Summary Acknowledge a low-stock alert; the product can alert again afterwards
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "alert id"
+// @Param request body AcknowledgeAlertRequest true "who acknowledges the alert"
+// @Success 200 {object} LowStockAlertResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/alerts/low-stock/{id}/ack [post]
+func (h *Handler) AcknowledgeLowStockAlert(c echo.Context) error {
+	var req AcknowledgeAlertRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	alert, err := h.alerts.Acknowledge(c.Request().Context(), c.Param("id"), strings.TrimSpace(req.AcknowledgedBy))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toLowStockAlertResponse(alert))
+}
+
+func toLowStockAlertResponse(a *domain.LowStockAlert) LowStockAlertResponse {
+	return LowStockAlertResponse{
+		ID:             a.ID,
+		ProductID:      a.ProductID,
+		Severity:       string(a.Severity),
+		Quantity:       a.Quantity,
+		ReorderPoint:   a.ReorderPoint,
+		SafetyStock:    a.SafetyStock,
+		CreatedAt:      a.CreatedAt,
+		AcknowledgedAt: a.AcknowledgedAt,
+		AcknowledgedBy: a.AcknowledgedBy,
+	}
+}
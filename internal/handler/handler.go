@@ -3,28 +3,44 @@ package handler
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"go.opentelemetry.io/otel/attribute"
 
 	"stockpilot/internal/domain"
 	"stockpilot/internal/middleware"
 	"stockpilot/internal/service"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/genuuid"
 	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/metrics"
+	"stockpilot/pkg/gonerve/postgresql"
 	sentrymw "stockpilot/pkg/gonerve/sentry"
+	"stockpilot/pkg/gonerve/tracing"
 )
 
 type Handler struct {
-	users    *service.UserService
-	products *service.ProductService
-	orders   *service.OrderService
+	users       *service.UserService
+	products    *service.ProductService
+	orders      *service.OrderService
+	retryAfter  int
+	stream      *service.StockStream
+	heartbeat   time.Duration
+	streamsDone <-chan struct{}
+	alerts      *service.LowStockService
+	purchasing  *service.PurchasingService
+	reorder     *service.ReorderService
+	returns     *service.ReturnService
 }
 
 func New(users *service.UserService, products *service.ProductService, orders *service.OrderService) *Handler {
-	return &Handler{users: users, products: products, orders: orders}
+	return &Handler{users: users, products: products, orders: orders, retryAfter: defaultRetryAfter}
 }
 
 func (h *Handler) Register(e *echo.Echo) {
@@ -32,38 +48,104 @@ func (h *Handler) Register(e *echo.Echo) {
 	g.POST("/users/register", h.RegisterUser)
 	g.POST("/products", h.CreateProduct)
 	g.GET("/products/:id", h.GetProduct)
+	g.POST("/products/:id/stock", h.AdjustStock)
+	g.PUT("/products/:id/reorder-policy", h.SetReorderPolicy)
+	g.PUT("/products/:id/backorder-policy", h.SetBackorderPolicy)
 	g.POST("/orders", h.CreateOrder)
+	g.GET("/orders/:id", h.GetOrder)
+	g.PUT("/orders/:id/status", h.UpdateOrderStatus)
+	if h.stream != nil {
+		g.GET("/stream/stock", h.StreamStock)
+	}
+	if h.alerts != nil {
+		g.GET("/alerts/low-stock", h.ListLowStockAlerts)
+		g.POST("/alerts/low-stock/:id/ack", h.AcknowledgeLowStockAlert)
+	}
+	if h.purchasing != nil {
+		h.registerPurchasing(g)
+	}
+	if h.reorder != nil {
+		g.GET("/inventory/reorder-suggestions", h.ListReorderSuggestions)
+	}
+	if h.returns != nil {
+		h.registerReturns(g)
+	}
 }
 
 type Server struct {
-	echo   *echo.Echo
-	addr   string
-	server *http.Server
+	echo        *echo.Echo
+	addr        string
+	server      *http.Server
+	rateLimiter *middleware.RateLimiter
+	health      HealthChecker
+	buildInfo   BuildInfo
+	draining    atomic.Bool
+	adminToken  string
+	maintenance MaintenanceController
+	webhooks    *service.WebhookService
+	stream      *service.StockStream
+	heartbeat   time.Duration
+	alerts      *service.LowStockService
+	purchasing  *service.PurchasingService
+	reorder     *service.ReorderService
+	returns     *service.ReturnService
+	retryAfter  int
+	// handler serves the public API; admin handlers share its writeError.
+	handler *Handler
+}
+
+type ServerOption func(s *Server)
+
+func WithRateLimiter(l *middleware.RateLimiter) ServerOption {
+	return func(s *Server) {
+		s.rateLimiter = l
+	}
 }
 
-func NewServer(addr string, users *service.UserService, products *service.ProductService, orders *service.OrderService, logRequests bool, useSentry bool) (*Server, error) {
+func NewServer(addr string, users *service.UserService, products *service.ProductService, orders *service.OrderService, logRequests bool, useSentry bool, opts ...ServerOption) (*Server, error) {
+	s := &Server{addr: addr, retryAfter: defaultRetryAfter, heartbeat: defaultHeartbeat}
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	e := echo.New()
 	e.HideBanner = true
+	e.Use(metrics.EchoMiddleware)
+	e.Use(tracing.EchoMiddleware)
+	e.Use(middleware.RequestID(genuuid.New()))
 	if logRequests {
-		e.Use(middleware.RequestLogger(logging.GlobalLogger()))
+		e.Use(middleware.RequestLogger(logging.Named("http")))
 	}
+	e.Use(sentrymw.HubMiddleware)
 	e.Use(sentrymw.PanicEchoMiddleware)
 	if useSentry {
 		e.Use(sentrymw.ErrEchoMiddleware)
 	}
+	if s.rateLimiter != nil {
+		e.Use(s.rateLimiter.Middleware())
+	}
 
+	// Shutdown waits for open connections, so streams end as soon as it
+	// starts.
+	streamsCtx, closeStreams := context.WithCancel(context.Background())
 	h := New(users, products, orders)
+	h.retryAfter = s.retryAfter
+	h.stream, h.heartbeat, h.streamsDone = s.stream, s.heartbeat, streamsCtx.Done()
+	h.alerts, h.purchasing, h.reorder, h.returns = s.alerts, s.purchasing, s.reorder, s.returns
+	s.handler = h
+
+	s.registerHealth(e)
+	s.registerAdmin(e)
 	h.Register(e)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
 
-	s := &Server{
-		echo: e,
-		addr: addr,
-		server: &http.Server{
-			Addr:    addr,
-			Handler: e,
-		},
+	s.echo = e
+	s.server = &http.Server{
+		Addr:    addr,
+		Handler: e,
 	}
+	s.server.RegisterOnShutdown(closeStreams)
 	return s, nil
 }
 
@@ -71,7 +153,14 @@ func (s *Server) Start() error {
 	return s.server.ListenAndServe()
 }
 
+// Drain marks the server as not ready, so load balancers stop routing new
+// traffic to it while in-flight requests are still being served.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.Drain()
 	return s.server.Shutdown(ctx)
 }
 
@@ -120,24 +209,31 @@ func (h *Handler) RegisterUser(c echo.Context) error {
 	if err != nil {
 		return h.writeError(c, err)
 	}
+	setUser(c, user.ID)
 	return c.JSON(http.StatusCreated, toUserResponse(user))
 }
 
 type CreateProductRequest struct {
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-	Quantity    int      `json:"quantity"`
-	Price       string   `json:"price"`
+	Description    string   `json:"description"`
+	Tags           []string `json:"tags"`
+	Quantity       int      `json:"quantity"`
+	Price          string   `json:"price"`
+	ReorderPoint   int      `json:"reorder_point"`
+	SafetyStock    int      `json:"safety_stock"`
+	AllowBackorder bool     `json:"allow_backorder"`
 }
 
 type ProductResponse struct {
-	ID          string    `json:"id"`
-	Description string    `json:"description"`
-	Tags        []string  `json:"tags"`
-	Quantity    int       `json:"quantity"`
-	Price       string    `json:"price"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	Description    string    `json:"description"`
+	Tags           []string  `json:"tags"`
+	Quantity       int       `json:"quantity"`
+	Price          string    `json:"price"`
+	ReorderPoint   int       `json:"reorder_point"`
+	SafetyStock    int       `json:"safety_stock"`
+	AllowBackorder bool      `json:"allow_backorder"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // CreateProduct godoc
@@ -159,10 +255,13 @@ func (h *Handler) CreateProduct(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid price"})
 	}
 	product, err := h.products.Create(c.Request().Context(), service.CreateProductInput{
-		Description: strings.TrimSpace(req.Description),
-		Tags:        req.Tags,
-		Quantity:    req.Quantity,
-		Price:       price,
+		Description:    strings.TrimSpace(req.Description),
+		Tags:           req.Tags,
+		Quantity:       req.Quantity,
+		Price:          price,
+		ReorderPoint:   req.ReorderPoint,
+		SafetyStock:    req.SafetyStock,
+		AllowBackorder: req.AllowBackorder,
 	})
 	if err != nil {
 		return h.writeError(c, err)
@@ -190,9 +289,97 @@ func (h *Handler) GetProduct(c echo.Context) error {
 	return c.JSON(http.StatusOK, toProductResponse(product))
 }
 
+type AdjustStockRequest struct {
+	Delta  int    `json:"delta"`
+	Reason string `json:"reason"`
+}
+
+// AdjustStock godoc
+// @Summary Add to or remove from product stock
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "product id"
+// @Param request body AdjustStockRequest true "signed quantity change and reason"
+// @Success 200 {object} ProductResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/products/{id}/stock [post]
+func (h *Handler) AdjustStock(c echo.Context) error {
+	var req AdjustStockRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	product, err := h.products.AdjustStock(c.Request().Context(), c.Param("id"), req.Delta, strings.TrimSpace(req.Reason))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toProductResponse(product))
+}
+
+type ReorderPolicyRequest struct {
+	ReorderPoint int `json:"reorder_point"`
+	SafetyStock  int `json:"safety_stock"`
+}
+
+// SetReorderPolicy godoc
+// @Summary Set the low-stock thresholds of a product
+// @Description An alert is raised when stock falls below reorder_point; it is critical at or below safety_stock.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "product id"
+// @Param request body ReorderPolicyRequest true "reorder point and safety stock, 0 disables alerts"
+// @Success 200 {object} ProductResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/products/{id}/reorder-policy [put]
+func (h *Handler) SetReorderPolicy(c echo.Context) error {
+	var req ReorderPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	product, err := h.products.SetReorderPolicy(c.Request().Context(), c.Param("id"), req.ReorderPoint, req.SafetyStock)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toProductResponse(product))
+}
+
+type BackorderPolicyRequest struct {
+	AllowBackorder bool `json:"allow_backorder"`
+}
+
+// SetBackorderPolicy godoc
+// @Summary Allow or forbid backorders for a product
+// @Description With allow_backorder, orders may take more than is in stock; the short part is backordered and filled first-in-first-out from incoming stock.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "product id"
+// @Param request body BackorderPolicyRequest true "backorder policy"
+// @Success 200 {object} ProductResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/products/{id}/backorder-policy [put]
+func (h *Handler) SetBackorderPolicy(c echo.Context) error {
+	var req BackorderPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	product, err := h.products.SetBackorderPolicy(c.Request().Context(), c.Param("id"), req.AllowBackorder)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toProductResponse(product))
+}
+
 type CreateOrderRequest struct {
 	UserID string                `json:"user_id"`
 	Items  []CreateOrderItemBody `json:"items"`
+	// FulfillmentPolicy is all_or_nothing (default) or partial.
+	FulfillmentPolicy string `json:"fulfillment_policy"`
 }
 
 type CreateOrderItemBody struct {
@@ -201,22 +388,35 @@ type CreateOrderItemBody struct {
 }
 
 type OrderResponse struct {
-	ID         string              `json:"id"`
-	UserID     string              `json:"user_id"`
-	CreatedAt  time.Time           `json:"created_at"`
-	TotalPrice string              `json:"total_price"`
-	Items      []OrderItemResponse `json:"items"`
+	ID                string              `json:"id"`
+	UserID            string              `json:"user_id"`
+	Status            string              `json:"status"`
+	FulfillmentStatus string              `json:"fulfillment_status"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+	TotalPrice        string              `json:"total_price"`
+	Items             []OrderItemResponse `json:"items"`
+	Lines             []OrderLineResponse `json:"lines,omitempty"`
+}
+
+// OrderLineResponse shows how much of a requested line the order accepted.
+type OrderLineResponse struct {
+	ProductID         string `json:"product_id"`
+	RequestedQuantity int    `json:"requested_quantity"`
+	AcceptedQuantity  int    `json:"accepted_quantity"`
 }
 
 type OrderItemResponse struct {
-	ID        string `json:"id"`
-	ProductID string `json:"product_id"`
-	Quantity  int    `json:"quantity"`
-	Price     string `json:"price"`
+	ID                  string `json:"id"`
+	ProductID           string `json:"product_id"`
+	Quantity            int    `json:"quantity"`
+	BackorderedQuantity int    `json:"backordered_quantity"`
+	Price               string `json:"price"`
 }
 
 // CreateOrder godoc
 // @Summary Create order
+// @Description With fulfillment_policy partial, lines are cut down to the stock on hand and unavailable lines are dropped; lines lists requested against accepted quantities.
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -236,13 +436,66 @@ func (h *Handler) CreateOrder(c echo.Context) error {
 			Quantity:  item.Quantity,
 		})
 	}
-	order, err := h.orders.Create(c.Request().Context(), strings.TrimSpace(req.UserID), items)
+	policy, err := domain.ParseFulfillmentPolicy(req.FulfillmentPolicy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid fulfillment policy"})
+	}
+	setUser(c, strings.TrimSpace(req.UserID))
+	order, err := h.orders.Create(c.Request().Context(), strings.TrimSpace(req.UserID), items, service.WithFulfillmentPolicy(policy))
 	if err != nil {
 		return h.writeError(c, err)
 	}
 	return c.JSON(http.StatusCreated, toOrderResponse(order))
 }
 
+// GetOrder godoc
+// @Summary Get order by id
+// @Tags orders
+// @Produce json
+// @Param id path string true "order id"
+// @Success 200 {object} OrderResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/orders/{id} [get]
+func (h *Handler) GetOrder(c echo.Context) error {
+	order, err := h.orders.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toOrderResponse(order))
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// UpdateOrderStatus godoc
+// @Summary Change order status; cancelling returns the items to stock
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "order id"
+// @Param request body UpdateOrderStatusRequest true "paid, shipped, delivered or cancelled"
+// @Success 200 {object} OrderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/orders/{id}/status [put]
+func (h *Handler) UpdateOrderStatus(c echo.Context) error {
+	var req UpdateOrderStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	status, err := domain.ParseOrderStatus(strings.TrimSpace(req.Status))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid status"})
+	}
+	order, err := h.orders.UpdateStatus(c.Request().Context(), c.Param("id"), status)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toOrderResponse(order))
+}
+
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
@@ -251,6 +504,10 @@ func (h *Handler) writeError(c echo.Context, err error) error {
 	if err == nil {
 		return nil
 	}
+	if errors.Is(err, postgresql.ErrLocked) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(h.retryAfter))
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{Message: err.Error()})
+	}
 	status := http.StatusInternalServerError
 	switch err.Error() {
 	case "user must be at least 18",
@@ -264,18 +521,56 @@ func (h *Handler) writeError(c echo.Context, err error) error {
 		"order items are required",
 		"product id is required",
 		"quantity must be positive",
+		"invalid fulfillment policy",
+		"delta cannot be zero",
+		"reorder point cannot be negative",
+		"safety stock cannot be negative",
+		"safety stock cannot exceed reorder point",
+		"acknowledged_by is required",
+		"supplier name is required",
+		"lead time cannot be negative",
+		"supplier id is required",
+		"purchase order lines are required",
+		"duplicate product in purchase order",
+		"received_by is required",
+		"receipt lines are required",
+		"product is not on the purchase order",
+		"window must be between 1 and 365 days",
+		"return reason is required",
+		"return lines are required",
+		"order item id is required",
+		"duplicate order item in return",
+		"order item is not on the order",
+		"invalid webhook url",
+		"unknown event type",
 		"user already exists":
 		status = http.StatusBadRequest
-	case "user not found", "product not found":
+	case "user not found", "product not found", "order not found", "alert not found",
+		"supplier not found", "purchase order not found", "return not found",
+		"webhook not found", "delivery not found":
 		status = http.StatusNotFound
-	case "insufficient stock":
+	case "insufficient stock", "product is busy", "invalid status transition", "alert already acknowledged",
+		"purchase order is not open for receiving", "received quantity exceeds ordered quantity",
+		"order is backordered", "order is not delivered", "return quantity exceeds ordered quantity":
 		status = http.StatusConflict
 	default:
 		status = http.StatusInternalServerError
 	}
+	if status >= http.StatusInternalServerError {
+		sentrymw.CaptureRequestError(c, err)
+	}
 	return c.JSON(status, ErrorResponse{Message: err.Error()})
 }
 
+func setUser(c echo.Context, userID string) {
+	if userID == "" {
+		return
+	}
+	ctx := c.Request().Context()
+	tracing.SetAttributes(ctx, attribute.String("enduser.id", userID))
+	c.SetRequest(c.Request().WithContext(logging.WithUserID(ctx, userID)))
+}
+
 func toUserResponse(u *domain.User) UserResponse {
 	return UserResponse{
 		ID:        u.ID,
@@ -291,13 +586,16 @@ func toUserResponse(u *domain.User) UserResponse {
 
 func toProductResponse(p *domain.Product) ProductResponse {
 	return ProductResponse{
-		ID:          p.ID,
-		Description: p.Description,
-		Tags:        p.Tags,
-		Quantity:    p.Quantity,
-		Price:       p.Price.StringFixed(2),
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:             p.ID,
+		Description:    p.Description,
+		Tags:           p.Tags,
+		Quantity:       p.Quantity,
+		Price:          p.Price.StringFixed(2),
+		ReorderPoint:   p.ReorderPoint,
+		SafetyStock:    p.SafetyStock,
+		AllowBackorder: p.AllowBackorder,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
 	}
 }
 
@@ -305,17 +603,30 @@ func toOrderResponse(o *domain.Order) OrderResponse {
 	items := make([]OrderItemResponse, 0, len(o.Items))
 	for _, item := range o.Items {
 		items = append(items, OrderItemResponse{
-			ID:        item.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price.StringFixed(2),
+			ID:                  item.ID,
+			ProductID:           item.ProductID,
+			Quantity:            item.Quantity,
+			BackorderedQuantity: item.Backordered,
+			Price:               item.Price.StringFixed(2),
+		})
+	}
+	var lines []OrderLineResponse
+	for _, line := range o.Lines {
+		lines = append(lines, OrderLineResponse{
+			ProductID:         line.ProductID,
+			RequestedQuantity: line.Requested,
+			AcceptedQuantity:  line.Accepted,
 		})
 	}
 	return OrderResponse{
-		ID:         o.ID,
-		UserID:     o.UserID,
-		CreatedAt:  o.CreatedAt,
-		TotalPrice: o.TotalPrice.StringFixed(2),
-		Items:      items,
+		ID:                o.ID,
+		UserID:            o.UserID,
+		Status:            string(o.Status),
+		FulfillmentStatus: string(o.Fulfillment()),
+		CreatedAt:         o.CreatedAt,
+		UpdatedAt:         o.UpdatedAt,
+		TotalPrice:        o.TotalPrice.StringFixed(2),
+		Items:             items,
+		Lines:             lines,
 	}
 }
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/logging"
+)
+
+const readinessTimeout = 2 * time.Second
+
+var healthLog = logging.Named("health")
+
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	Locked() error
+}
+
+type BuildInfo struct {
+	Branch     string `json:"branch"`
+	Version    string `json:"version"`
+	CommitHash string `json:"commit_hash"`
+	CommitDate string `json:"commit_date"`
+	BuildDate  string `json:"build_date"`
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+type ReadinessResponse struct {
+	Status       string `json:"status"`
+	Database     string `json:"database"`
+	Locked       bool   `json:"locked"`
+	ShuttingDown bool   `json:"shutting_down"`
+}
+
+func WithHealthChecker(hc HealthChecker) ServerOption {
+	return func(s *Server) {
+		s.health = hc
+	}
+}
+
+func WithBuildInfo(info BuildInfo) ServerOption {
+	return func(s *Server) {
+		s.buildInfo = info
+	}
+}
+
+func (s *Server) registerHealth(e *echo.Echo) {
+	e.GET("/healthz", s.Healthz)
+	e.GET("/readyz", s.Readyz)
+	e.GET("/version", s.Version)
+}
+
+// Healthz godoc
+// @Summary Liveness probe
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (s *Server) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func (s *Server) Readyz(c echo.Context) error {
+	resp := ReadinessResponse{
+		Status:       "ready",
+		Database:     "ok",
+		ShuttingDown: s.draining.Load(),
+	}
+	if s.health != nil {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), readinessTimeout)
+		defer cancel()
+		if err := s.health.Ping(ctx); err != nil {
+			// The probe is unauthenticated, so the cause is only logged.
+			healthLog.WarnCtx(ctx, "readiness database ping failed", zap.Error(err))
+			resp.Database = "unavailable"
+		}
+		resp.Locked = s.health.Locked() != nil
+	}
+	if resp.ShuttingDown || resp.Database != "ok" {
+		resp.Status = "not ready"
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Version godoc
+// @Summary Build information
+// @Tags health
+// @Produce json
+// @Success 200 {object} BuildInfo
+// @Router /version [get]
+func (s *Server) Version(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.buildInfo)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/csv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/service"
+)
+
+// WithReorderSuggestions enables the reorder suggestion endpoint.
+func WithReorderSuggestions(r *service.ReorderService) ServerOption {
+	return func(s *Server) {
+		s.reorder = r
+	}
+}
+
+type ReorderSuggestionResponse struct {
+	ProductID         string   `json:"product_id"`
+	Description       string   `json:"description"`
+	SupplierID        string   `json:"supplier_id,omitempty"`
+	SupplierName      string   `json:"supplier_name,omitempty"`
+	Quantity          int      `json:"quantity"`
+	OnOrder           int      `json:"on_order"`
+	UnitsSold         int      `json:"units_sold"`
+	DailyVelocity     float64  `json:"daily_velocity"`
+	LeadTimeDays      int      `json:"lead_time_days"`
+	DaysOfCover       *float64 `json:"days_of_cover"`
+	ReorderLevel      int      `json:"reorder_level"`
+	ReorderBy         string   `json:"reorder_by"`
+	SuggestedQuantity int      `json:"suggested_quantity"`
+}
+
+var reorderCSVHeader = []string{
+	"product_id", "description", "supplier_id", "supplier_name", "quantity", "on_order", "units_sold",
+	"daily_velocity", "lead_time_days", "days_of_cover", "reorder_level", "reorder_by", "suggested_quantity",
+}
+
+// ListReorderSuggestions godoc
+// @Summary Suggest what to reorder and when, from sales velocity and supplier lead time
+// @Tags inventory
+// @Produce json
+// @Produce text/csv
+// @Param window_days query int false "sales velocity window in days, at most 365"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} ReorderSuggestionResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/inventory/reorder-suggestions [get]
+func (h *Handler) ListReorderSuggestions(c echo.Context) error {
+	window, err := strconv.Atoi(c.QueryParam("window_days"))
+	if err != nil && c.QueryParam("window_days") != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid window"})
+	}
+	format := c.QueryParam("format")
+	if format == "" && strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid format"})
+	}
+
+	suggestions, err := h.reorder.Suggestions(c.Request().Context(), window)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	resp := make([]ReorderSuggestionResponse, 0, len(suggestions))
+	for i := range suggestions {
+		resp = append(resp, toReorderSuggestionResponse(&suggestions[i]))
+	}
+	if format != "csv" {
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="reorder-suggestions.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+	w := csv.NewWriter(c.Response())
+	if err := w.Write(reorderCSVHeader); err != nil {
+		return err
+	}
+	for _, s := range resp {
+		cover := ""
+		if s.DaysOfCover != nil {
+			cover = strconv.FormatFloat(*s.DaysOfCover, 'f', 1, 64)
+		}
+		if err := w.Write(csvSafe([]string{
+			s.ProductID, s.Description, s.SupplierID, s.SupplierName,
+			strconv.Itoa(s.Quantity), strconv.Itoa(s.OnOrder), strconv.Itoa(s.UnitsSold),
+			strconv.FormatFloat(s.DailyVelocity, 'f', 2, 64), strconv.Itoa(s.LeadTimeDays), cover,
+			strconv.Itoa(s.ReorderLevel), s.ReorderBy, strconv.Itoa(s.SuggestedQuantity),
+		})); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvSafe quotes cells a spreadsheet would otherwise evaluate as a formula.
+func csvSafe(record []string) []string {
+	for i, cell := range record {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			record[i] = "'" + cell
+		}
+	}
+	return record
+}
+
+func toReorderSuggestionResponse(s *domain.ReorderSuggestion) ReorderSuggestionResponse {
+	resp := ReorderSuggestionResponse{
+		ProductID:         s.ProductID,
+		Description:       s.Description,
+		SupplierID:        s.SupplierID,
+		SupplierName:      s.SupplierName,
+		Quantity:          s.Quantity,
+		OnOrder:           s.OnOrder,
+		UnitsSold:         s.UnitsSold,
+		DailyVelocity:     math.Round(s.DailyVelocity*100) / 100,
+		LeadTimeDays:      s.LeadTimeDays,
+		ReorderLevel:      s.ReorderLevel,
+		ReorderBy:         s.ReorderBy.Format(dateLayout),
+		SuggestedQuantity: s.SuggestedQuantity,
+	}
+	if s.DaysOfCover != nil {
+		cover := math.Round(*s.DaysOfCover*10) / 10
+		resp.DaysOfCover = &cover
+	}
+	return resp
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/postgresql"
+)
+
+const defaultRetryAfter = 30
+
+type MaintenanceController interface {
+	Maintenance() postgresql.MaintenanceState
+	SetMaintenance(ctx context.Context, locked bool, reason string) error
+}
+
+type MaintenanceRequest struct {
+	Locked bool   `json:"locked"`
+	Reason string `json:"reason"`
+}
+
+// WithMaintenance enables the admin maintenance endpoints. retryAfter is the
+// Retry-After value in seconds sent with writes rejected during maintenance.
+func WithMaintenance(m MaintenanceController, retryAfter int) ServerOption {
+	return func(s *Server) {
+		s.maintenance = m
+		if retryAfter > 0 {
+			s.retryAfter = retryAfter
+		}
+	}
+}
+
+// GetMaintenance godoc
+// @Summary Get maintenance mode state
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} postgresql.MaintenanceState
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/maintenance [get]
+func (s *Server) GetMaintenance(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.maintenance.Maintenance())
+}
+
+// SetMaintenance godoc
+// @Summary Turn maintenance mode on or off; writes fail with 503 while it is on
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MaintenanceRequest true "maintenance state"
+// @Success 200 {object} postgresql.MaintenanceState
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/maintenance [put]
+func (s *Server) SetMaintenance(c echo.Context) error {
+	var req MaintenanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	ctx := c.Request().Context()
+	if err := s.maintenance.SetMaintenance(ctx, req.Locked, req.Reason); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+	}
+	logging.Info(ctx, "maintenance mode set", zap.Bool("locked", req.Locked), zap.String("reason", req.Reason))
+	return c.JSON(http.StatusOK, s.maintenance.Maintenance())
+}
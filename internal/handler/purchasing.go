@@ -0,0 +1,413 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/service"
+)
+
+const dateLayout = "2006-01-02"
+
+// WithPurchasing enables the supplier and purchase order endpoints.
+func WithPurchasing(p *service.PurchasingService) ServerOption {
+	return func(s *Server) {
+		s.purchasing = p
+	}
+}
+
+func (h *Handler) registerPurchasing(g *echo.Group) {
+	g.POST("/suppliers", h.CreateSupplier)
+	g.GET("/suppliers", h.ListSuppliers)
+	g.GET("/suppliers/:id", h.GetSupplier)
+	g.PUT("/suppliers/:id", h.UpdateSupplier)
+	g.POST("/purchase-orders", h.CreatePurchaseOrder)
+	g.GET("/purchase-orders", h.ListPurchaseOrders)
+	g.GET("/purchase-orders/:id", h.GetPurchaseOrder)
+	g.PUT("/purchase-orders/:id/status", h.UpdatePurchaseOrderStatus)
+	g.POST("/purchase-orders/:id/receipts", h.ReceivePurchaseOrder)
+	g.GET("/purchase-orders/:id/receipts", h.ListPurchaseReceipts)
+}
+
+type SupplierRequest struct {
+	Name         string `json:"name"`
+	ContactName  string `json:"contact_name"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	LeadTimeDays int    `json:"lead_time_days"`
+}
+
+type SupplierResponse struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ContactName  string    `json:"contact_name"`
+	Email        string    `json:"email"`
+	Phone        string    `json:"phone"`
+	LeadTimeDays int       `json:"lead_time_days"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type PurchaseOrderLineBody struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type CreatePurchaseOrderRequest struct {
+	SupplierID   string                  `json:"supplier_id"`
+	ExpectedDate string                  `json:"expected_date"`
+	Lines        []PurchaseOrderLineBody `json:"lines"`
+}
+
+type PurchaseOrderResponse struct {
+	ID           string                      `json:"id"`
+	SupplierID   string                      `json:"supplier_id"`
+	Status       string                      `json:"status"`
+	ExpectedDate string                      `json:"expected_date,omitempty"`
+	CreatedAt    time.Time                   `json:"created_at"`
+	UpdatedAt    time.Time                   `json:"updated_at"`
+	Lines        []PurchaseOrderLineResponse `json:"lines"`
+}
+
+type PurchaseOrderLineResponse struct {
+	ID               string `json:"id"`
+	ProductID        string `json:"product_id"`
+	Quantity         int    `json:"quantity"`
+	ReceivedQuantity int    `json:"received_quantity"`
+}
+
+type UpdatePurchaseOrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type ReceivePurchaseOrderRequest struct {
+	ReceivedBy string                  `json:"received_by"`
+	Lines      []PurchaseOrderLineBody `json:"lines"`
+}
+
+type PurchaseReceiptResponse struct {
+	ID              string                  `json:"id"`
+	PurchaseOrderID string                  `json:"purchase_order_id"`
+	ReceivedBy      string                  `json:"received_by"`
+	ReceivedAt      time.Time               `json:"received_at"`
+	Lines           []PurchaseOrderLineBody `json:"lines"`
+}
+
+type ReceivePurchaseOrderResponse struct {
+	Receipt       PurchaseReceiptResponse `json:"receipt"`
+	PurchaseOrder PurchaseOrderResponse   `json:"purchase_order"`
+}
+
+// CreateSupplier godoc
+// @Summary Create supplier
+// @Tags purchasing
+// @Accept json
+// @Produce json
+// @Param request body SupplierRequest true "supplier with contact details and lead time in days"
+// @Success 201 {object} SupplierResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/suppliers [post]
+func (h *Handler) CreateSupplier(c echo.Context) error {
+	var req SupplierRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	supplier, err := h.purchasing.CreateSupplier(c.Request().Context(), toSupplierInput(req))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toSupplierResponse(supplier))
+}
+
+// ListSuppliers godoc
+// @Summary List suppliers by name
+// @Tags purchasing
+// @Produce json
+// @Success 200 {array} SupplierResponse
+// @Router /api/v1/suppliers [get]
+func (h *Handler) ListSuppliers(c echo.Context) error {
+	suppliers, err := h.purchasing.ListSuppliers(c.Request().Context())
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	resp := make([]SupplierResponse, 0, len(suppliers))
+	for i := range suppliers {
+		resp = append(resp, toSupplierResponse(&suppliers[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetSupplier godoc
+// @Summary Get supplier by id
+// @Tags purchasing
+// @Produce json
+// @Param id path string true "supplier id"
+// @Success 200 {object} SupplierResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/suppliers/{id} [get]
+func (h *Handler) GetSupplier(c echo.Context) error {
+	supplier, err := h.purchasing.GetSupplier(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toSupplierResponse(supplier))
+}
+
+// UpdateSupplier godoc
+// @Summary Replace supplier details
+// @Tags purchasing
+// @Accept json
+// @Produce json
+// @Param id path string true "supplier id"
+// @Param request body SupplierRequest true "supplier"
+// @Success 200 {object} SupplierResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/suppliers/{id} [put]
+func (h *Handler) UpdateSupplier(c echo.Context) error {
+	var req SupplierRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	supplier, err := h.purchasing.UpdateSupplier(c.Request().Context(), c.Param("id"), toSupplierInput(req))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toSupplierResponse(supplier))
+}
+
+// CreatePurchaseOrder godoc
+// @Summary Create a draft purchase order
+// @Tags purchasing
+// @Accept json
+// @Produce json
+// @Param request body CreatePurchaseOrderRequest true "supplier, expected date (YYYY-MM-DD) and one line per product"
+// @Success 201 {object} PurchaseOrderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/purchase-orders [post]
+func (h *Handler) CreatePurchaseOrder(c echo.Context) error {
+	var req CreatePurchaseOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	input := service.PurchaseOrderInput{
+		SupplierID: strings.TrimSpace(req.SupplierID),
+		Lines:      toPurchaseOrderLineInputs(req.Lines),
+	}
+	if req.ExpectedDate != "" {
+		expected, err := time.Parse(dateLayout, req.ExpectedDate)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid expected date"})
+		}
+		input.ExpectedDate = &expected
+	}
+	po, err := h.purchasing.CreatePurchaseOrder(c.Request().Context(), input)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toPurchaseOrderResponse(po))
+}
+
+// ListPurchaseOrders godoc
+// @Summary List purchase orders, newest first
+// @Tags purchasing
+// @Produce json
+// @Param status query string false "draft, sent, partially_received or received"
+// @Param limit query int false "at most 100"
+// @Success 200 {array} PurchaseOrderResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/purchase-orders [get]
+func (h *Handler) ListPurchaseOrders(c echo.Context) error {
+	var status domain.PurchaseOrderStatus
+	if v := c.QueryParam("status"); v != "" {
+		var err error
+		if status, err = domain.ParsePurchaseOrderStatus(v); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid status"})
+		}
+	}
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil && c.QueryParam("limit") != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid limit"})
+	}
+	orders, err := h.purchasing.ListPurchaseOrders(c.Request().Context(), status, limit)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	resp := make([]PurchaseOrderResponse, 0, len(orders))
+	for i := range orders {
+		resp = append(resp, toPurchaseOrderResponse(&orders[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetPurchaseOrder godoc
+// @Summary Get purchase order with received quantities
+// @Tags purchasing
+// @Produce json
+// @Param id path string true "purchase order id"
+// @Success 200 {object} PurchaseOrderResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/purchase-orders/{id} [get]
+func (h *Handler) GetPurchaseOrder(c echo.Context) error {
+	po, err := h.purchasing.GetPurchaseOrder(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toPurchaseOrderResponse(po))
+}
+
+// UpdatePurchaseOrderStatus godoc
+// @Summary Send a draft purchase order to the supplier
+// @Description Only draft → sent is allowed here; receiving moves the order to partially_received and received.
+// @Tags purchasing
+// @Accept json
+// @Produce json
+// @Param id path string true "purchase order id"
+// @Param request body UpdatePurchaseOrderStatusRequest true "sent"
+// @Success 200 {object} PurchaseOrderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/purchase-orders/{id}/status [put]
+func (h *Handler) UpdatePurchaseOrderStatus(c echo.Context) error {
+	var req UpdatePurchaseOrderStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	status, err := domain.ParsePurchaseOrderStatus(strings.TrimSpace(req.Status))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid status"})
+	}
+	po, err := h.purchasing.UpdateStatus(c.Request().Context(), c.Param("id"), status)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toPurchaseOrderResponse(po))
+}
+
+// ReceivePurchaseOrder godoc
+// @Summary Receive goods against a sent purchase order
+// @Description Adds the received quantities to stock and records who received them. Partial deliveries leave the
+// @Description order partially_received until every line is received in full.
+// @Tags purchasing
+// @Accept json
+// @Produce json
+// @Param id path string true "purchase order id"
+// @Param request body ReceivePurchaseOrderRequest true "receiver and received quantities per product"
+// @Success 201 {object} ReceivePurchaseOrderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/purchase-orders/{id}/receipts [post]
+func (h *Handler) ReceivePurchaseOrder(c echo.Context) error {
+	var req ReceivePurchaseOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	receipt, po, err := h.purchasing.Receive(c.Request().Context(), c.Param("id"), service.ReceiveInput{
+		ReceivedBy: strings.TrimSpace(req.ReceivedBy),
+		Lines:      toPurchaseOrderLineInputs(req.Lines),
+	})
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusCreated, ReceivePurchaseOrderResponse{
+		Receipt:       toPurchaseReceiptResponse(receipt),
+		PurchaseOrder: toPurchaseOrderResponse(po),
+	})
+}
+
+// ListPurchaseReceipts godoc
+// @Summary List receipts of a purchase order, oldest first
+// @Tags purchasing
+// @Produce json
+// @Param id path string true "purchase order id"
+// @Success 200 {array} PurchaseReceiptResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/purchase-orders/{id}/receipts [get]
+func (h *Handler) ListPurchaseReceipts(c echo.Context) error {
+	receipts, err := h.purchasing.ListReceipts(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	resp := make([]PurchaseReceiptResponse, 0, len(receipts))
+	for i := range receipts {
+		resp = append(resp, toPurchaseReceiptResponse(&receipts[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func toSupplierInput(req SupplierRequest) service.SupplierInput {
+	return service.SupplierInput{
+		Name:         strings.TrimSpace(req.Name),
+		ContactName:  strings.TrimSpace(req.ContactName),
+		Email:        strings.TrimSpace(req.Email),
+		Phone:        strings.TrimSpace(req.Phone),
+		LeadTimeDays: req.LeadTimeDays,
+	}
+}
+
+func toPurchaseOrderLineInputs(lines []PurchaseOrderLineBody) []service.PurchaseOrderLineInput {
+	result := make([]service.PurchaseOrderLineInput, 0, len(lines))
+	for _, l := range lines {
+		result = append(result, service.PurchaseOrderLineInput{ProductID: strings.TrimSpace(l.ProductID), Quantity: l.Quantity})
+	}
+	return result
+}
+
+func toSupplierResponse(s *domain.Supplier) SupplierResponse {
+	return SupplierResponse{
+		ID:           s.ID,
+		Name:         s.Name,
+		ContactName:  s.ContactName,
+		Email:        s.Email,
+		Phone:        s.Phone,
+		LeadTimeDays: s.LeadTimeDays,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+	}
+}
+
+func toPurchaseOrderResponse(po *domain.PurchaseOrder) PurchaseOrderResponse {
+	lines := make([]PurchaseOrderLineResponse, 0, len(po.Lines))
+	for _, l := range po.Lines {
+		lines = append(lines, PurchaseOrderLineResponse{
+			ID:               l.ID,
+			ProductID:        l.ProductID,
+			Quantity:         l.Quantity,
+			ReceivedQuantity: l.ReceivedQuantity,
+		})
+	}
+	resp := PurchaseOrderResponse{
+		ID:         po.ID,
+		SupplierID: po.SupplierID,
+		Status:     string(po.Status),
+		CreatedAt:  po.CreatedAt,
+		UpdatedAt:  po.UpdatedAt,
+		Lines:      lines,
+	}
+	if po.ExpectedDate != nil {
+		resp.ExpectedDate = po.ExpectedDate.Format(dateLayout)
+	}
+	return resp
+}
+
+func toPurchaseReceiptResponse(r *domain.PurchaseReceipt) PurchaseReceiptResponse {
+	lines := make([]PurchaseOrderLineBody, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, PurchaseOrderLineBody{ProductID: l.ProductID, Quantity: l.Quantity})
+	}
+	return PurchaseReceiptResponse{
+		ID:              r.ID,
+		PurchaseOrderID: r.PurchaseOrderID,
+		ReceivedBy:      r.ReceivedBy,
+		ReceivedAt:      r.ReceivedAt,
+		Lines:           lines,
+	}
+}
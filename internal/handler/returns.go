@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/service"
+)
+
+// WithReturns enables the return authorization (RMA) endpoints.
+func WithReturns(r *service.ReturnService) ServerOption {
+	return func(s *Server) {
+		s.returns = r
+	}
+}
+
+func (h *Handler) registerReturns(g *echo.Group) {
+	g.POST("/orders/:id/returns", h.CreateReturn)
+	g.GET("/orders/:id/returns", h.ListReturns)
+	g.GET("/returns/:id", h.GetReturn)
+	g.PUT("/returns/:id/status", h.UpdateReturnStatus)
+}
+
+type ReturnLineBody struct {
+	OrderItemID string `json:"order_item_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+type CreateReturnRequest struct {
+	Reason string           `json:"reason"`
+	Lines  []ReturnLineBody `json:"lines"`
+}
+
+type ReturnResponse struct {
+	ID         string               `json:"id"`
+	OrderID    string               `json:"order_id"`
+	Reason     string               `json:"reason"`
+	Status     string               `json:"status"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	ReceivedAt *time.Time           `json:"received_at,omitempty"`
+	ResolvedAt *time.Time           `json:"resolved_at,omitempty"`
+	Lines      []ReturnLineResponse `json:"lines"`
+	Refund     *RefundResponse      `json:"refund,omitempty"`
+}
+
+type ReturnLineResponse struct {
+	ID          string `json:"id"`
+	OrderItemID string `json:"order_item_id"`
+	ProductID   string `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	Price       string `json:"price"`
+}
+
+type RefundResponse struct {
+	ID        string    `json:"id"`
+	Amount    string    `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UpdateReturnStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// CreateReturn godoc
+// @Summary Authorize the return of items of a delivered order
+// @Description An order item cannot be returned more often than it was ordered, counting earlier returns.
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path string true "order id"
+// @Param request body CreateReturnRequest true "reason and returned quantity per order item"
+// @Success 201 {object} ReturnResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/orders/{id}/returns [post]
+func (h *Handler) CreateReturn(c echo.Context) error {
+	var req CreateReturnRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	lines := make([]service.ReturnLineInput, 0, len(req.Lines))
+	for _, l := range req.Lines {
+		lines = append(lines, service.ReturnLineInput{OrderItemID: strings.TrimSpace(l.OrderItemID), Quantity: l.Quantity})
+	}
+	ret, err := h.returns.Create(c.Request().Context(), c.Param("id"), service.ReturnInput{
+		Reason: strings.TrimSpace(req.Reason),
+		Lines:  lines,
+	})
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toReturnResponse(ret))
+}
+
+// ListReturns godoc
+// @Summary List returns of an order, oldest first
+// @Tags returns
+// @Produce json
+// @Param id path string true "order id"
+// @Success 200 {array} ReturnResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/orders/{id}/returns [get]
+func (h *Handler) ListReturns(c echo.Context) error {
+	returns, err := h.returns.List(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	resp := make([]ReturnResponse, 0, len(returns))
+	for i := range returns {
+		resp = append(resp, toReturnResponse(&returns[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetReturn godoc
+// @Summary Get return with its refund
+// @Tags returns
+// @Produce json
+// @Param id path string true "return id"
+// @Success 200 {object} ReturnResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/returns/{id} [get]
+func (h *Handler) GetReturn(c echo.Context) error {
+	ret, err := h.returns.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toReturnResponse(ret))
+}
+
+// UpdateReturnStatus godoc
+// @Summary Receive a return, then restock it or write it off
+// @Description authorized → received records the refund at the ordered prices; received → restocked puts the goods
+// @Description back into stock; received → written_off leaves stock unchanged.
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path string true "return id"
+// @Param request body UpdateReturnStatusRequest true "received, restocked or written_off"
+// @Success 200 {object} ReturnResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/v1/returns/{id}/status [put]
+func (h *Handler) UpdateReturnStatus(c echo.Context) error {
+	var req UpdateReturnStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	status, err := domain.ParseReturnStatus(strings.TrimSpace(req.Status))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid status"})
+	}
+	ret, err := h.returns.UpdateStatus(c.Request().Context(), c.Param("id"), status)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toReturnResponse(ret))
+}
+
+func toReturnResponse(r *domain.Return) ReturnResponse {
+	lines := make([]ReturnLineResponse, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, ReturnLineResponse{
+			ID:          l.ID,
+			OrderItemID: l.OrderItemID,
+			ProductID:   l.ProductID,
+			Quantity:    l.Quantity,
+			Price:       l.Price.StringFixed(2),
+		})
+	}
+	resp := ReturnResponse{
+		ID:         r.ID,
+		OrderID:    r.OrderID,
+		Reason:     r.Reason,
+		Status:     string(r.Status),
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		ReceivedAt: r.ReceivedAt,
+		ResolvedAt: r.ResolvedAt,
+		Lines:      lines,
+	}
+	if r.Refund != nil {
+		resp.Refund = &RefundResponse{
+			ID:        r.Refund.ID,
+			Amount:    r.Refund.Amount.StringFixed(2),
+			CreatedAt: r.Refund.CreatedAt,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/service"
+	"stockpilot/pkg/gonerve/sse"
+)
+
+const (
+	maxStreamProducts = 100
+	defaultHeartbeat  = 15 * time.Second
+)
+
+// WithStockStream enables GET /api/v1/stream/stock. Idle streams get a
+// comment line every heartbeat so proxies keep them open.
+func WithStockStream(stream *service.StockStream, heartbeat time.Duration) ServerOption {
+	return func(s *Server) {
+		s.stream = stream
+		if heartbeat > 0 {
+			s.heartbeat = heartbeat
+		}
+	}
+}
+
+// StreamStock godoc
+// @Summary Stream stock levels as server-sent events
+// @Description Sends a "stock" event with {product_id, quantity, updated_at} after every committed stock change.
+// @Description Listed products first get their current level as events without an id; reconnecting with
+// @Description Last-Event-ID replays what was missed, or the current levels again when the gap is too old,
+// @Description of every product when no product_ids are given.
+// @Tags products
+// @Produce text/event-stream
+// @Param product_ids query string false "comma separated product ids, all products when empty"
+// @Param Last-Event-ID header string false "id of the last event received"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/stream/stock [get]
+func (h *Handler) StreamStock(c echo.Context) error {
+	ctx := c.Request().Context()
+	var ids []string
+	for _, id := range strings.Split(c.QueryParam("product_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxStreamProducts {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "too many product ids"})
+	}
+
+	lastID, resume := sse.ParseLastEventID(c.Request().Header)
+	sub := h.stream.Subscribe(ids, lastID, resume)
+	defer sub.Close()
+
+	// Subscribing first means no change between the snapshot and the stream
+	// is lost; at worst a level is sent twice.
+	var snapshot []domain.StockLevel
+	switch {
+	case len(ids) == 0 && sub.Missed:
+		// Without a filter there is no snapshot on connect, but a gap in the
+		// replay still has to be covered with every current level.
+		levels, err := h.products.StockLevels(ctx)
+		if err != nil {
+			return h.writeError(c, err)
+		}
+		snapshot = levels
+	case !resume || sub.Missed:
+		for _, id := range ids {
+			p, err := h.products.GetByID(ctx, id)
+			if err != nil {
+				return h.writeError(c, err)
+			}
+			if p == nil {
+				return c.JSON(http.StatusNotFound, ErrorResponse{Message: "product not found"})
+			}
+			snapshot = append(snapshot, domain.StockLevel{ProductID: p.ID, Quantity: p.Quantity, UpdatedAt: p.UpdatedAt})
+		}
+	}
+
+	w := c.Response()
+	sse.SetHeaders(w.Header())
+	w.WriteHeader(http.StatusOK)
+	for _, l := range snapshot {
+		data, _ := json.Marshal(l)
+		if err := sse.Write(w, sse.Event{Type: service.StockEventType, Data: data}); err != nil {
+			return nil
+		}
+	}
+	if err := sse.WriteComment(w, "connected"); err != nil {
+		return nil
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.streamsDone:
+			return nil
+		case e, ok := <-sub.C:
+			if !ok {
+				// Too slow to keep up: the client reconnects and resumes.
+				return nil
+			}
+			err = sse.Write(w, e)
+		case <-heartbeat.C:
+			err = sse.WriteComment(w, "heartbeat")
+		}
+		if err != nil {
+			return nil
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/service"
+)
+
+// WithWebhooks enables the admin webhook subscription endpoints.
+func WithWebhooks(w *service.WebhookService) ServerOption {
+	return func(s *Server) {
+		s.webhooks = w
+	}
+}
+
+func (s *Server) registerWebhooks(g *echo.Group) {
+	g.POST("/webhooks", s.CreateWebhook)
+	g.GET("/webhooks", s.ListWebhooks)
+	g.GET("/webhooks/:id", s.GetWebhook)
+	g.PUT("/webhooks/:id", s.UpdateWebhook)
+	g.DELETE("/webhooks/:id", s.DeleteWebhook)
+	g.GET("/webhooks/:id/deliveries", s.ListWebhookDeliveries)
+	g.GET("/webhook-deliveries/:id", s.GetWebhookDelivery)
+	g.POST("/webhook-deliveries/:id/replay", s.ReplayWebhookDelivery)
+}
+
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"event_types"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Description string    `json:"description"`
+	EventTypes  []string  `json:"event_types"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type WebhookAttemptResponse struct {
+	Attempt         int       `json:"attempt"`
+	StatusCode      int       `json:"status_code"`
+	LatencyMS       int64     `json:"latency_ms"`
+	ResponseSnippet string    `json:"response_snippet"`
+	Error           string    `json:"error"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type WebhookDeliveryDetailResponse struct {
+	WebhookDeliveryResponse
+	Log []WebhookAttemptResponse `json:"log"`
+}
+
+// CreateWebhook godoc
+// @Summary Subscribe a URL to events; the secret is generated when omitted and only returned here
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WebhookRequest true "subscription; empty event_types means all events"
+// @Success 201 {object} WebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/webhooks [post]
+func (s *Server) CreateWebhook(c echo.Context) error {
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	w, err := s.webhooks.Create(c.Request().Context(), toWebhookInput(req))
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	resp := toWebhookResponse(w)
+	resp.Secret = w.Secret
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// ListWebhooks godoc
+// @Summary List webhook subscriptions
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} WebhookResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/webhooks [get]
+func (s *Server) ListWebhooks(c echo.Context) error {
+	items, err := s.webhooks.List(c.Request().Context())
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	resp := make([]WebhookResponse, 0, len(items))
+	for i := range items {
+		resp = append(resp, toWebhookResponse(&items[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook subscription
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "subscription id"
+// @Success 200 {object} WebhookResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{id} [get]
+func (s *Server) GetWebhook(c echo.Context) error {
+	w, err := s.webhooks.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toWebhookResponse(w))
+}
+
+// UpdateWebhook godoc
+// @Summary Replace a webhook subscription; an empty secret keeps the current one
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "subscription id"
+// @Param request body WebhookRequest true "subscription"
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{id} [put]
+func (s *Server) UpdateWebhook(c echo.Context) error {
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request"})
+	}
+	w, err := s.webhooks.Update(c.Request().Context(), c.Param("id"), toWebhookInput(req))
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, toWebhookResponse(w))
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook subscription and its delivery log
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "subscription id"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{id} [delete]
+func (s *Server) DeleteWebhook(c echo.Context) error {
+	if err := s.webhooks.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return s.handler.writeError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List the latest deliveries of a subscription
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "subscription id"
+// @Param limit query int false "at most 100"
+// @Success 200 {array} WebhookDeliveryResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (s *Server) ListWebhookDeliveries(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	items, err := s.webhooks.Deliveries(c.Request().Context(), c.Param("id"), limit)
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	resp := make([]WebhookDeliveryResponse, 0, len(items))
+	for i := range items {
+		resp = append(resp, toWebhookDeliveryResponse(&items[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetWebhookDelivery godoc
+// @Summary Get a delivery with its attempt log
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "delivery id"
+// @Success 200 {object} WebhookDeliveryDetailResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhook-deliveries/{id} [get]
+func (s *Server) GetWebhookDelivery(c echo.Context) error {
+	d, attempts, err := s.webhooks.Delivery(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	resp := WebhookDeliveryDetailResponse{
+		WebhookDeliveryResponse: toWebhookDeliveryResponse(d),
+		Log:                     make([]WebhookAttemptResponse, 0, len(attempts)),
+	}
+	for _, a := range attempts {
+		resp.Log = append(resp.Log, WebhookAttemptResponse{
+			Attempt:         a.Attempt,
+			StatusCode:      a.StatusCode,
+			LatencyMS:       a.Latency.Milliseconds(),
+			ResponseSnippet: a.ResponseSnippet,
+			Error:           a.Error,
+			CreatedAt:       a.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary Send a delivery again with a fresh attempt budget
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "delivery id"
+// @Success 202 {object} WebhookDeliveryResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhook-deliveries/{id}/replay [post]
+func (s *Server) ReplayWebhookDelivery(c echo.Context) error {
+	d, err := s.webhooks.Replay(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return s.handler.writeError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, toWebhookDeliveryResponse(d))
+}
+
+func toWebhookInput(req WebhookRequest) service.WebhookInput {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return service.WebhookInput{
+		URL:         strings.TrimSpace(req.URL),
+		Secret:      req.Secret,
+		Description: strings.TrimSpace(req.Description),
+		EventTypes:  req.EventTypes,
+		Active:      active,
+	}
+}
+
+func toWebhookResponse(w *domain.WebhookSubscription) WebhookResponse {
+	eventTypes := w.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return WebhookResponse{
+		ID:          w.ID,
+		URL:         w.URL,
+		Description: w.Description,
+		EventTypes:  eventTypes,
+		Active:      w.Active,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(d *domain.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitSweepSize = 10000
+	rateLimitIdleTTL   = time.Minute
+)
+
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	clients map[string]*rateLimitClient
+}
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a per client IP limiter. A non-positive rps disables limiting.
+func NewRateLimiter(rps, burst int) *RateLimiter {
+	l := &RateLimiter{clients: map[string]*rateLimitClient{}}
+	l.Update(rps, burst)
+	return l
+}
+
+func (l *RateLimiter) Update(rps, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = rate.Limit(rps)
+	if burst <= 0 {
+		burst = rps
+	}
+	l.burst = burst
+	l.clients = map[string]*rateLimitClient{}
+}
+
+func (l *RateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return true
+	}
+	now := time.Now()
+	if len(l.clients) >= rateLimitSweepSize {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > rateLimitIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+	}
+	c, ok := l.clients[key]
+	if !ok {
+		c = &rateLimitClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter.AllowN(now, 1)
+}
+
+func (l *RateLimiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !l.allow(c.RealIP()) {
+				return c.JSON(http.StatusTooManyRequests, map[string]string{"message": "rate limit exceeded"})
+			}
+			return next(c)
+		}
+	}
+}
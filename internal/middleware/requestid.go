@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"stockpilot/pkg/gonerve/genuuid"
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/tracing"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID honors an incoming X-Request-ID header, generating one when it is
+// missing or malformed, stores it in the request context and echoes it back.
+func RequestID(ug genuuid.GeneratorUUID) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = ug.V4()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			ctx := logging.WithRequestID(req.Context(), id)
+			tracing.SetAttributes(ctx, attribute.String("http.request_id", id))
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -20,28 +20,52 @@ type DBUser struct {
 }
 
 type DBProduct struct {
-	ID          string          `db:"id"`
-	Description string          `db:"description"`
-	Tags        []string        `db:"tags"`
-	Quantity    int             `db:"quantity"`
-	Price       decimal.Decimal `db:"price"`
-	CreatedAt   time.Time       `db:"created_at"`
-	UpdatedAt   time.Time       `db:"updated_at"`
+	ID             string          `db:"id"`
+	Description    string          `db:"description"`
+	Tags           []string        `db:"tags"`
+	Quantity       int             `db:"quantity"`
+	Price          decimal.Decimal `db:"price"`
+	ReorderPoint   int             `db:"reorder_point"`
+	SafetyStock    int             `db:"safety_stock"`
+	AllowBackorder bool            `db:"allow_backorder"`
+	CreatedAt      time.Time       `db:"created_at"`
+	UpdatedAt      time.Time       `db:"updated_at"`
+}
+
+type DBStockLevel struct {
+	ProductID string    `db:"id"`
+	Quantity  int       `db:"quantity"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
 
 type DBOrder struct {
 	ID         string          `db:"id"`
 	UserID     string          `db:"user_id"`
+	Status     string          `db:"status"`
 	CreatedAt  time.Time       `db:"created_at"`
+	UpdatedAt  time.Time       `db:"updated_at"`
 	TotalPrice decimal.Decimal `db:"total_price"`
 }
 
 type DBOrderItem struct {
-	ID        string          `db:"id"`
-	OrderID   string          `db:"order_id"`
-	ProductID string          `db:"product_id"`
-	Quantity  int             `db:"quantity"`
-	Price     decimal.Decimal `db:"price"`
+	ID          string          `db:"id"`
+	OrderID     string          `db:"order_id"`
+	ProductID   string          `db:"product_id"`
+	Quantity    int             `db:"quantity"`
+	Price       decimal.Decimal `db:"price"`
+	Backordered int             `db:"backordered"`
+}
+
+type DBBackorder struct {
+	ID             string     `db:"id"`
+	OrderID        string     `db:"order_id"`
+	OrderItemID    string     `db:"order_item_id"`
+	ProductID      string     `db:"product_id"`
+	Quantity       int        `db:"quantity"`
+	FilledQuantity int        `db:"filled_quantity"`
+	Status         string     `db:"status"`
+	CreatedAt      time.Time  `db:"created_at"`
+	ClosedAt       *time.Time `db:"closed_at"`
 }
 
 func UserFromDomain(u domain.User) DBUser {
@@ -72,25 +96,35 @@ func UserToDomain(u DBUser) domain.User {
 
 func ProductFromDomain(p domain.Product) DBProduct {
 	return DBProduct{
-		ID:          p.ID,
-		Description: p.Description,
-		Tags:        p.Tags,
-		Quantity:    p.Quantity,
-		Price:       p.Price,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:             p.ID,
+		Description:    p.Description,
+		Tags:           p.Tags,
+		Quantity:       p.Quantity,
+		Price:          p.Price,
+		ReorderPoint:   p.ReorderPoint,
+		SafetyStock:    p.SafetyStock,
+		AllowBackorder: p.AllowBackorder,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
 	}
 }
 
+func StockLevelToDomain(l DBStockLevel) domain.StockLevel {
+	return domain.StockLevel{ProductID: l.ProductID, Quantity: l.Quantity, UpdatedAt: l.UpdatedAt}
+}
+
 func ProductToDomain(p DBProduct) domain.Product {
 	return domain.Product{
-		ID:          p.ID,
-		Description: p.Description,
-		Tags:        p.Tags,
-		Quantity:    p.Quantity,
-		Price:       p.Price,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:             p.ID,
+		Description:    p.Description,
+		Tags:           p.Tags,
+		Quantity:       p.Quantity,
+		Price:          p.Price,
+		ReorderPoint:   p.ReorderPoint,
+		SafetyStock:    p.SafetyStock,
+		AllowBackorder: p.AllowBackorder,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
 	}
 }
 
@@ -98,7 +132,9 @@ func OrderFromDomain(o domain.Order) DBOrder {
 	return DBOrder{
 		ID:         o.ID,
 		UserID:     o.UserID,
+		Status:     string(o.Status),
 		CreatedAt:  o.CreatedAt,
+		UpdatedAt:  o.UpdatedAt,
 		TotalPrice: o.TotalPrice,
 	}
 }
@@ -107,7 +143,9 @@ func OrderToDomain(o DBOrder, items []domain.OrderItem) domain.Order {
 	return domain.Order{
 		ID:         o.ID,
 		UserID:     o.UserID,
+		Status:     domain.OrderStatus(o.Status),
 		CreatedAt:  o.CreatedAt,
+		UpdatedAt:  o.UpdatedAt,
 		TotalPrice: o.TotalPrice,
 		Items:      items,
 	}
@@ -125,10 +163,357 @@ func OrderItemFromDomain(i domain.OrderItem) DBOrderItem {
 
 func OrderItemToDomain(i DBOrderItem) domain.OrderItem {
 	return domain.OrderItem{
-		ID:        i.ID,
-		OrderID:   i.OrderID,
-		ProductID: i.ProductID,
-		Quantity:  i.Quantity,
-		Price:     i.Price,
+		ID:          i.ID,
+		OrderID:     i.OrderID,
+		ProductID:   i.ProductID,
+		Quantity:    i.Quantity,
+		Price:       i.Price,
+		Backordered: i.Backordered,
+	}
+}
+
+func BackorderToDomain(b DBBackorder) domain.Backorder {
+	return domain.Backorder{
+		ID:             b.ID,
+		OrderID:        b.OrderID,
+		OrderItemID:    b.OrderItemID,
+		ProductID:      b.ProductID,
+		Quantity:       b.Quantity,
+		FilledQuantity: b.FilledQuantity,
+		Status:         domain.BackorderStatus(b.Status),
+		CreatedAt:      b.CreatedAt,
+		ClosedAt:       b.ClosedAt,
+	}
+}
+
+type DBWebhookSubscription struct {
+	ID          string    `db:"id"`
+	URL         string    `db:"url"`
+	Secret      string    `db:"secret"`
+	Description string    `db:"description"`
+	EventTypes  []string  `db:"event_types"`
+	Active      bool      `db:"active"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+type DBWebhookDelivery struct {
+	ID             string    `db:"id"`
+	SubscriptionID string    `db:"subscription_id"`
+	EventID        string    `db:"event_id"`
+	EventType      string    `db:"event_type"`
+	Payload        []byte    `db:"payload"`
+	Status         string    `db:"status"`
+	Attempts       int       `db:"attempts"`
+	NextAttemptAt  time.Time `db:"next_attempt_at"`
+	LastStatusCode int       `db:"last_status_code"`
+	LastError      string    `db:"last_error"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+	URL            string    `db:"url"`
+	Secret         string    `db:"secret"`
+}
+
+type DBWebhookAttempt struct {
+	DeliveryID      string    `db:"delivery_id"`
+	Attempt         int       `db:"attempt"`
+	StatusCode      int       `db:"status_code"`
+	LatencyMS       int       `db:"latency_ms"`
+	ResponseSnippet string    `db:"response_snippet"`
+	Error           string    `db:"error"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+func WebhookSubscriptionFromDomain(w domain.WebhookSubscription) DBWebhookSubscription {
+	eventTypes := w.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return DBWebhookSubscription{
+		ID:          w.ID,
+		URL:         w.URL,
+		Secret:      w.Secret,
+		Description: w.Description,
+		EventTypes:  eventTypes,
+		Active:      w.Active,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+func WebhookSubscriptionToDomain(w DBWebhookSubscription) domain.WebhookSubscription {
+	return domain.WebhookSubscription{
+		ID:          w.ID,
+		URL:         w.URL,
+		Secret:      w.Secret,
+		Description: w.Description,
+		EventTypes:  w.EventTypes,
+		Active:      w.Active,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+func WebhookDeliveryToDomain(d DBWebhookDelivery) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         domain.WebhookDeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		URL:            d.URL,
+		Secret:         d.Secret,
+	}
+}
+
+func WebhookAttemptToDomain(a DBWebhookAttempt) domain.WebhookAttempt {
+	return domain.WebhookAttempt{
+		DeliveryID:      a.DeliveryID,
+		Attempt:         a.Attempt,
+		StatusCode:      a.StatusCode,
+		Latency:         time.Duration(a.LatencyMS) * time.Millisecond,
+		ResponseSnippet: a.ResponseSnippet,
+		Error:           a.Error,
+		CreatedAt:       a.CreatedAt,
+	}
+}
+
+type DBLowStockAlert struct {
+	ID             string     `db:"id"`
+	ProductID      string     `db:"product_id"`
+	Severity       string     `db:"severity"`
+	Quantity       int        `db:"quantity"`
+	ReorderPoint   int        `db:"reorder_point"`
+	SafetyStock    int        `db:"safety_stock"`
+	CreatedAt      time.Time  `db:"created_at"`
+	AcknowledgedAt *time.Time `db:"acknowledged_at"`
+	AcknowledgedBy string     `db:"acknowledged_by"`
+}
+
+func LowStockAlertToDomain(a DBLowStockAlert) domain.LowStockAlert {
+	return domain.LowStockAlert{
+		ID:             a.ID,
+		ProductID:      a.ProductID,
+		Severity:       domain.AlertSeverity(a.Severity),
+		Quantity:       a.Quantity,
+		ReorderPoint:   a.ReorderPoint,
+		SafetyStock:    a.SafetyStock,
+		CreatedAt:      a.CreatedAt,
+		AcknowledgedAt: a.AcknowledgedAt,
+		AcknowledgedBy: a.AcknowledgedBy,
+	}
+}
+
+type DBSupplier struct {
+	ID           string    `db:"id"`
+	Name         string    `db:"name"`
+	ContactName  string    `db:"contact_name"`
+	Email        string    `db:"email"`
+	Phone        string    `db:"phone"`
+	LeadTimeDays int       `db:"lead_time_days"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+type DBPurchaseOrder struct {
+	ID           string     `db:"id"`
+	SupplierID   string     `db:"supplier_id"`
+	Status       string     `db:"status"`
+	ExpectedDate *time.Time `db:"expected_date"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+}
+
+type DBPurchaseOrderLine struct {
+	ID               string `db:"id"`
+	PurchaseOrderID  string `db:"purchase_order_id"`
+	ProductID        string `db:"product_id"`
+	Quantity         int    `db:"quantity"`
+	ReceivedQuantity int    `db:"received_quantity"`
+}
+
+type DBPurchaseReceipt struct {
+	ID              string    `db:"id"`
+	PurchaseOrderID string    `db:"purchase_order_id"`
+	ReceivedBy      string    `db:"received_by"`
+	ReceivedAt      time.Time `db:"received_at"`
+}
+
+type DBPurchaseReceiptLine struct {
+	ReceiptID string `db:"receipt_id"`
+	LineID    string `db:"line_id"`
+	ProductID string `db:"product_id"`
+	Quantity  int    `db:"quantity"`
+}
+
+func SupplierFromDomain(s domain.Supplier) DBSupplier {
+	return DBSupplier{
+		ID:           s.ID,
+		Name:         s.Name,
+		ContactName:  s.ContactName,
+		Email:        s.Email,
+		Phone:        s.Phone,
+		LeadTimeDays: s.LeadTimeDays,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+	}
+}
+
+func SupplierToDomain(s DBSupplier) domain.Supplier {
+	return domain.Supplier{
+		ID:           s.ID,
+		Name:         s.Name,
+		ContactName:  s.ContactName,
+		Email:        s.Email,
+		Phone:        s.Phone,
+		LeadTimeDays: s.LeadTimeDays,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+	}
+}
+
+func PurchaseOrderToDomain(po DBPurchaseOrder, lines []domain.PurchaseOrderLine) domain.PurchaseOrder {
+	return domain.PurchaseOrder{
+		ID:           po.ID,
+		SupplierID:   po.SupplierID,
+		Status:       domain.PurchaseOrderStatus(po.Status),
+		ExpectedDate: po.ExpectedDate,
+		CreatedAt:    po.CreatedAt,
+		UpdatedAt:    po.UpdatedAt,
+		Lines:        lines,
+	}
+}
+
+func PurchaseOrderLineToDomain(l DBPurchaseOrderLine) domain.PurchaseOrderLine {
+	return domain.PurchaseOrderLine{
+		ID:               l.ID,
+		PurchaseOrderID:  l.PurchaseOrderID,
+		ProductID:        l.ProductID,
+		Quantity:         l.Quantity,
+		ReceivedQuantity: l.ReceivedQuantity,
+	}
+}
+
+func PurchaseReceiptToDomain(r DBPurchaseReceipt, lines []domain.PurchaseReceiptLine) domain.PurchaseReceipt {
+	return domain.PurchaseReceipt{
+		ID:              r.ID,
+		PurchaseOrderID: r.PurchaseOrderID,
+		ReceivedBy:      r.ReceivedBy,
+		ReceivedAt:      r.ReceivedAt,
+		Lines:           lines,
+	}
+}
+
+func PurchaseReceiptLineToDomain(l DBPurchaseReceiptLine) domain.PurchaseReceiptLine {
+	return domain.PurchaseReceiptLine{
+		LineID:    l.LineID,
+		ProductID: l.ProductID,
+		Quantity:  l.Quantity,
+	}
+}
+
+type DBReorderCandidate struct {
+	ProductID    string `db:"product_id"`
+	Description  string `db:"description"`
+	Quantity     int    `db:"quantity"`
+	ReorderPoint int    `db:"reorder_point"`
+	SafetyStock  int    `db:"safety_stock"`
+	UnitsSold    int    `db:"units_sold"`
+	OnOrder      int    `db:"on_order"`
+	Backordered  int    `db:"backordered"`
+	SupplierID   string `db:"supplier_id"`
+	SupplierName string `db:"supplier_name"`
+	LeadTimeDays *int   `db:"lead_time_days"`
+}
+
+func ReorderCandidateToDomain(c DBReorderCandidate) domain.ReorderCandidate {
+	return domain.ReorderCandidate{
+		ProductID:    c.ProductID,
+		Description:  c.Description,
+		Quantity:     c.Quantity,
+		ReorderPoint: c.ReorderPoint,
+		SafetyStock:  c.SafetyStock,
+		UnitsSold:    c.UnitsSold,
+		OnOrder:      c.OnOrder,
+		Backordered:  c.Backordered,
+		SupplierID:   c.SupplierID,
+		SupplierName: c.SupplierName,
+		LeadTimeDays: c.LeadTimeDays,
+	}
+}
+
+type DBReturn struct {
+	ID         string     `db:"id"`
+	OrderID    string     `db:"order_id"`
+	Reason     string     `db:"reason"`
+	Status     string     `db:"status"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+	ReceivedAt *time.Time `db:"received_at"`
+	ResolvedAt *time.Time `db:"resolved_at"`
+}
+
+type DBReturnLine struct {
+	ID          string          `db:"id"`
+	ReturnID    string          `db:"return_id"`
+	OrderItemID string          `db:"order_item_id"`
+	ProductID   string          `db:"product_id"`
+	Quantity    int             `db:"quantity"`
+	Price       decimal.Decimal `db:"price"`
+}
+
+type DBRefund struct {
+	ID        string          `db:"id"`
+	ReturnID  string          `db:"return_id"`
+	OrderID   string          `db:"order_id"`
+	Amount    decimal.Decimal `db:"amount"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+func ReturnToDomain(r DBReturn, lines []domain.ReturnLine, refund *domain.Refund) domain.Return {
+	return domain.Return{
+		ID:         r.ID,
+		OrderID:    r.OrderID,
+		Reason:     r.Reason,
+		Status:     domain.ReturnStatus(r.Status),
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		ReceivedAt: r.ReceivedAt,
+		ResolvedAt: r.ResolvedAt,
+		Lines:      lines,
+		Refund:     refund,
 	}
 }
+
+func ReturnLineToDomain(l DBReturnLine) domain.ReturnLine {
+	return domain.ReturnLine{
+		ID:          l.ID,
+		ReturnID:    l.ReturnID,
+		OrderItemID: l.OrderItemID,
+		ProductID:   l.ProductID,
+		Quantity:    l.Quantity,
+		Price:       l.Price,
+	}
+}
+
+func RefundToDomain(r DBRefund) domain.Refund {
+	return domain.Refund{
+		ID:        r.ID,
+		ReturnID:  r.ReturnID,
+		OrderID:   r.OrderID,
+		Amount:    r.Amount,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+type DBReturnedQuantity struct {
+	OrderItemID string `db:"order_item_id"`
+	Quantity    int    `db:"quantity"`
+}
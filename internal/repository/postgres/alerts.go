@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/dto"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+const lowStockAlertColumns = `id, product_id, severity, quantity, reorder_point, safety_stock, created_at, acknowledged_at, acknowledged_by`
+
+// The product row is locked while its below_reorder_point flag is updated,
+// so only the check that sees the downward crossing inserts an alert. The
+// partial unique index on open alerts still keeps one open alert per product.
+const raiseLowStockAlertQuery = `
+WITH p AS (
+	SELECT id, quantity, reorder_point, safety_stock, below_reorder_point AS was_below,
+		reorder_point > 0 AND quantity < reorder_point AS below
+	FROM products
+	WHERE id = $1
+	FOR UPDATE
+), flag AS (
+	UPDATE products SET below_reorder_point = p.below
+	FROM p
+	WHERE products.id = p.id AND products.below_reorder_point <> p.below
+)
+INSERT INTO low_stock_alerts (id, product_id, severity, quantity, reorder_point, safety_stock, created_at)
+SELECT $2, p.id,
+	CASE WHEN p.quantity <= p.safety_stock THEN 'critical' ELSE 'low' END,
+	p.quantity, p.reorder_point, p.safety_stock, $3
+FROM p
+WHERE p.below AND NOT p.was_below
+ON CONFLICT (product_id) WHERE acknowledged_at IS NULL DO NOTHING
+RETURNING ` + lowStockAlertColumns
+
+func (r *Repository) RaiseLowStockAlert(ctx context.Context, tx pgx.Tx, productID string) (*domain.LowStockAlert, error) {
+	a, err := query.GetOne[dto.DBLowStockAlert](ctx, tx, raiseLowStockAlertQuery, productID, r.ug.V4(), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "raise low stock alert")
+	}
+	result := dto.LowStockAlertToDomain(*a)
+	return &result, nil
+}
+
+const listOpenLowStockAlertsQuery = `
+SELECT ` + lowStockAlertColumns + `
+FROM low_stock_alerts
+WHERE acknowledged_at IS NULL
+ORDER BY created_at DESC, id
+LIMIT $1
+`
+
+const listLowStockAlertsQuery = `
+SELECT ` + lowStockAlertColumns + `
+FROM low_stock_alerts
+ORDER BY created_at DESC, id
+LIMIT $1
+`
+
+func (r *Repository) ListLowStockAlerts(ctx context.Context, includeAcknowledged bool, limit int) ([]domain.LowStockAlert, error) {
+	q := listOpenLowStockAlertsQuery
+	if includeAcknowledged {
+		q = listLowStockAlertsQuery
+	}
+	items, err := query.GetAll[dto.DBLowStockAlert](ctx, r.ReadConn(), q, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "list low stock alerts")
+	}
+	result := make([]domain.LowStockAlert, 0, len(items))
+	for _, a := range items {
+		result = append(result, dto.LowStockAlertToDomain(a))
+	}
+	return result, nil
+}
+
+const acknowledgeLowStockAlertQuery = `
+UPDATE low_stock_alerts
+SET acknowledged_at = $2, acknowledged_by = $3
+WHERE id = $1 AND acknowledged_at IS NULL
+RETURNING ` + lowStockAlertColumns
+
+const lowStockAlertExistsQuery = `SELECT EXISTS (SELECT 1 FROM low_stock_alerts WHERE id = $1)`
+
+func (r *Repository) AcknowledgeLowStockAlert(ctx context.Context, id, by string) (*domain.LowStockAlert, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	a, err := query.GetOne[dto.DBLowStockAlert](ctx, r.Conn, acknowledgeLowStockAlertQuery, id, time.Now().UTC(), by)
+	if err == nil {
+		result := dto.LowStockAlertToDomain(*a)
+		return &result, nil
+	}
+	if !errors.Is(err, errors.ErrNotFound) {
+		return nil, errors.Wrap(err, "acknowledge low stock alert")
+	}
+	var exists bool
+	if err := r.Conn.QueryRow(ctx, lowStockAlertExistsQuery, id).Scan(&exists); err != nil {
+		return nil, errors.Wrap(err, "find low stock alert")
+	}
+	if exists {
+		return nil, errors.New("alert already acknowledged")
+	}
+	return nil, errors.New("alert not found")
+}
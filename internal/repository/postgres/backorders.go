@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/dto"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+const listOpenBackordersQuery = `
+SELECT id, order_id, order_item_id, product_id, quantity, filled_quantity, status, created_at, closed_at
+FROM backorders
+WHERE product_id = $1 AND status = 'open'
+ORDER BY created_at, id
+`
+
+func (r *Repository) ListOpenBackorders(ctx context.Context, tx pgx.Tx, productID string) ([]domain.Backorder, error) {
+	items, err := query.GetAll[dto.DBBackorder](ctx, tx, listOpenBackordersQuery, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list open backorders")
+	}
+	result := make([]domain.Backorder, 0, len(items))
+	for _, b := range items {
+		result = append(result, dto.BackorderToDomain(b))
+	}
+	return result, nil
+}
+
+const fillBackorderQuery = `
+UPDATE backorders
+SET filled_quantity = filled_quantity + $2,
+	status = CASE WHEN filled_quantity + $2 = quantity THEN 'filled' ELSE status END,
+	closed_at = CASE WHEN filled_quantity + $2 = quantity THEN $3 ELSE closed_at END
+WHERE id = $1 AND status = 'open'
+RETURNING id
+`
+
+func (r *Repository) FillBackorder(ctx context.Context, tx pgx.Tx, id string, quantity int) error {
+	err := query.Exec(ctx, tx, fillBackorderQuery, id, quantity, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return errors.New("backorder not found")
+		}
+		return errors.Wrap(err, "fill backorder")
+	}
+	return nil
+}
+
+// fulfilmentLockClass namespaces the advisory locks FulfilledOrders takes.
+const fulfilmentLockClass = 48
+
+const (
+	lockOrderFulfilmentQuery = `SELECT pg_advisory_xact_lock($1, hashtext($2))`
+	listFulfilledOrdersQuery = `
+SELECT id::text FROM unnest($1::uuid[]) AS id
+WHERE NOT EXISTS (SELECT 1 FROM backorders b WHERE b.order_id = id AND b.status = 'open')
+ORDER BY id
+`
+)
+
+// FulfilledOrders takes transaction-scoped advisory locks rather than the
+// order row, which cancellation locks before the products. The check runs as
+// a statement of its own once the locks are held, so it sees the backorders a
+// transaction that held one of them closed.
+func (r *Repository) FulfilledOrders(ctx context.Context, tx pgx.Tx, orderIDs []string) ([]string, error) {
+	ids := slices.Clone(orderIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	for _, id := range ids {
+		if _, err := tx.Exec(ctx, lockOrderFulfilmentQuery, fulfilmentLockClass, id); err != nil {
+			return nil, errors.Wrap(err, "lock order fulfilment")
+		}
+	}
+	rows, err := tx.Query(ctx, listFulfilledOrdersQuery, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "list fulfilled orders")
+	}
+	fulfilled, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, errors.Wrap(err, "list fulfilled orders")
+	}
+	return fulfilled, nil
+}
+
+const cancelBackordersQuery = `
+UPDATE backorders
+SET status = 'cancelled', closed_at = $2
+WHERE order_id = $1 AND status = 'open'
+`
+
+func (r *Repository) CancelBackorders(ctx context.Context, tx pgx.Tx, orderID string) error {
+	if _, err := tx.Exec(ctx, cancelBackordersQuery, orderID, time.Now().UTC()); err != nil {
+		return errors.Wrap(err, "cancel backorders")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/postgresql"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+const addOutboxEventQuery = `
+INSERT INTO outbox (event_id, event_type, aggregate_type, aggregate_id, payload, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+func (r *Repository) AddEvents(ctx context.Context, tx pgx.Tx, events ...outbox.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, e := range events {
+		batch.Queue(addOutboxEventQuery, e.ID, e.Type, e.AggregateType, e.AggregateID, []byte(e.Payload), e.CreatedAt)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return errors.Wrap(err, "insert outbox events")
+	}
+	// NOTIFY serializes commits, so only pay for it when relays listen.
+	if r.Subscriber() == nil {
+		return nil
+	}
+	return postgresql.Notify(ctx, tx, outbox.Channel, "")
+}
+
+type dbOutboxEvent struct {
+	EventID       string    `db:"event_id"`
+	EventType     string    `db:"event_type"`
+	AggregateType string    `db:"aggregate_type"`
+	AggregateID   string    `db:"aggregate_id"`
+	Payload       []byte    `db:"payload"`
+	CreatedAt     time.Time `db:"created_at"`
+	Attempts      int       `db:"attempts"`
+}
+
+// Due rows are claimed by pushing next_attempt_at past the lease, so relays
+// on other instances skip them without holding a transaction open while
+// delivering.
+const claimOutboxQuery = `
+WITH due AS (
+	SELECT id FROM outbox
+	WHERE delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+), claimed AS (
+	UPDATE outbox o
+	SET next_attempt_at = now() + make_interval(secs => $2)
+	FROM due
+	WHERE o.id = due.id
+	RETURNING o.id, o.event_id, o.event_type, o.aggregate_type, o.aggregate_id, o.payload, o.created_at, o.attempts
+)
+SELECT event_id, event_type, aggregate_type, aggregate_id, payload, created_at, attempts
+FROM claimed
+ORDER BY id
+`
+
+func (r *Repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Event, error) {
+	rows, err := query.GetAllNoLog[dbOutboxEvent](ctx, r.Conn, claimOutboxQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, errors.Wrap(err, "claim outbox events")
+	}
+	events := make([]outbox.Event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, outbox.Event{
+			ID:            row.EventID,
+			Type:          row.EventType,
+			AggregateType: row.AggregateType,
+			AggregateID:   row.AggregateID,
+			Payload:       row.Payload,
+			CreatedAt:     row.CreatedAt,
+			Attempts:      row.Attempts,
+		})
+	}
+	return events, nil
+}
+
+const markOutboxDeliveredQuery = `UPDATE outbox SET delivered_at = now() WHERE event_id = $1`
+
+func (r *Repository) MarkDelivered(ctx context.Context, id string) error {
+	return errors.Wrap(query.Exec(ctx, r.Conn, markOutboxDeliveredQuery, id), "mark outbox event delivered")
+}
+
+const markOutboxFailedQuery = `
+UPDATE outbox
+SET attempts = attempts + 1, last_error = $2, next_attempt_at = now() + make_interval(secs => $3)
+WHERE event_id = $1
+`
+
+func (r *Repository) MarkFailed(ctx context.Context, id string, cause string, retryIn time.Duration) error {
+	return errors.Wrap(query.Exec(ctx, r.Conn, markOutboxFailedQuery, id, cause, retryIn.Seconds()), "mark outbox event failed")
+}
+
+const markOutboxDeadQuery = `
+UPDATE outbox
+SET attempts = attempts + 1, last_error = $2, dead_at = now()
+WHERE event_id = $1
+`
+
+func (r *Repository) MarkDead(ctx context.Context, id string, cause string) error {
+	return errors.Wrap(query.Exec(ctx, r.Conn, markOutboxDeadQuery, id, cause), "mark outbox event dead")
+}
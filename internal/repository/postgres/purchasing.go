@@ -0,0 +1,338 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/dto"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+func init() {
+	query.RegisterSensitiveArgs(createSupplierQuery, 4, 5)
+	query.RegisterSensitiveArgs(updateSupplierQuery, 4, 5)
+}
+
+const supplierColumns = `id, name, contact_name, email, phone, lead_time_days, created_at, updated_at`
+
+const createSupplierQuery = `
+INSERT INTO suppliers (id, name, contact_name, email, phone, lead_time_days, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+RETURNING ` + supplierColumns
+
+func (r *Repository) CreateSupplier(ctx context.Context, s *domain.Supplier) (*domain.Supplier, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	if s.ID == "" {
+		s.ID = r.ug.V4()
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	dbs := dto.SupplierFromDomain(*s)
+	created, err := query.GetOne[dto.DBSupplier](ctx, r.Conn, createSupplierQuery, dbs.ID, dbs.Name, dbs.ContactName, dbs.Email, dbs.Phone, dbs.LeadTimeDays, dbs.CreatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "create supplier")
+	}
+	result := dto.SupplierToDomain(*created)
+	return &result, nil
+}
+
+const getSupplierQuery = `SELECT ` + supplierColumns + ` FROM suppliers WHERE id = $1`
+
+func (r *Repository) GetSupplier(ctx context.Context, id string) (*domain.Supplier, error) {
+	s, err := query.GetOne[dto.DBSupplier](ctx, r.Conn, getSupplierQuery, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get supplier")
+	}
+	result := dto.SupplierToDomain(*s)
+	return &result, nil
+}
+
+const listSuppliersQuery = `SELECT ` + supplierColumns + ` FROM suppliers ORDER BY name, id`
+
+func (r *Repository) ListSuppliers(ctx context.Context) ([]domain.Supplier, error) {
+	items, err := query.GetAll[dto.DBSupplier](ctx, r.ReadConn(), listSuppliersQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "list suppliers")
+	}
+	result := make([]domain.Supplier, 0, len(items))
+	for _, s := range items {
+		result = append(result, dto.SupplierToDomain(s))
+	}
+	return result, nil
+}
+
+const updateSupplierQuery = `
+UPDATE suppliers
+SET name = $2, contact_name = $3, email = $4, phone = $5, lead_time_days = $6, updated_at = $7
+WHERE id = $1
+RETURNING ` + supplierColumns
+
+func (r *Repository) UpdateSupplier(ctx context.Context, s *domain.Supplier) (*domain.Supplier, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	dbs := dto.SupplierFromDomain(*s)
+	updated, err := query.GetOne[dto.DBSupplier](ctx, r.Conn, updateSupplierQuery, dbs.ID, dbs.Name, dbs.ContactName, dbs.Email, dbs.Phone, dbs.LeadTimeDays, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("supplier not found")
+		}
+		return nil, errors.Wrap(err, "update supplier")
+	}
+	result := dto.SupplierToDomain(*updated)
+	return &result, nil
+}
+
+const purchaseOrderColumns = `id, supplier_id, status, expected_date, created_at, updated_at`
+
+const purchaseOrderLineColumns = `id, purchase_order_id, product_id, quantity, received_quantity`
+
+const createPurchaseOrderQuery = `
+INSERT INTO purchase_orders (id, supplier_id, status, expected_date, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5)
+RETURNING ` + purchaseOrderColumns
+
+const createPurchaseOrderLineQuery = `
+INSERT INTO purchase_order_lines (id, purchase_order_id, product_id, quantity)
+VALUES ($1, $2, $3, $4)
+`
+
+const foreignKeyViolationCode = "23503"
+
+// missingReferences turns foreign key violations into the errors the API
+// reports for unknown ids.
+var missingReferences = map[string]string{
+	"purchase_orders_supplier_id_fkey":       "supplier not found",
+	"purchase_order_lines_product_id_fkey":   "product not found",
+	"purchase_receipt_lines_product_id_fkey": "product not found",
+}
+
+func (r *Repository) CreatePurchaseOrder(ctx context.Context, tx pgx.Tx, po *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	if po.ID == "" {
+		po.ID = r.ug.V4()
+	}
+	if po.CreatedAt.IsZero() {
+		po.CreatedAt = time.Now().UTC()
+	}
+	if po.Status == "" {
+		po.Status = domain.PurchaseOrderDraft
+	}
+
+	batch := &pgx.Batch{}
+	var inserted *dto.DBPurchaseOrder
+	batch.Queue(createPurchaseOrderQuery, po.ID, po.SupplierID, string(po.Status), po.ExpectedDate, po.CreatedAt).QueryRow(func(row pgx.Row) error {
+		var dbpo dto.DBPurchaseOrder
+		if err := row.Scan(&dbpo.ID, &dbpo.SupplierID, &dbpo.Status, &dbpo.ExpectedDate, &dbpo.CreatedAt, &dbpo.UpdatedAt); err != nil {
+			return err
+		}
+		inserted = &dbpo
+		return nil
+	})
+	lines := make([]domain.PurchaseOrderLine, len(po.Lines))
+	for i, l := range po.Lines {
+		if l.ID == "" {
+			l.ID = r.ug.V4()
+		}
+		l.PurchaseOrderID = po.ID
+		l.ReceivedQuantity = 0
+		lines[i] = l
+		batch.Queue(createPurchaseOrderLineQuery, l.ID, l.PurchaseOrderID, l.ProductID, l.Quantity)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, referenceError(err, "insert purchase order")
+	}
+	result := dto.PurchaseOrderToDomain(*inserted, lines)
+	return &result, nil
+}
+
+func referenceError(err error, msg string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode {
+		if missing, ok := missingReferences[pgErr.ConstraintName]; ok {
+			return errors.New(missing)
+		}
+	}
+	return errors.Wrap(err, msg)
+}
+
+// queryConn is a pool or a transaction.
+type queryConn interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+const getPurchaseOrderQuery = `SELECT ` + purchaseOrderColumns + ` FROM purchase_orders WHERE id = $1`
+
+const getPurchaseOrderForUpdateQuery = getPurchaseOrderQuery + ` FOR UPDATE`
+
+const getPurchaseOrderLinesQuery = `
+SELECT ` + purchaseOrderLineColumns + `
+FROM purchase_order_lines
+WHERE purchase_order_id = ANY($1)
+ORDER BY purchase_order_id, product_id
+`
+
+func (r *Repository) GetPurchaseOrder(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	return r.getPurchaseOrder(ctx, r.Conn, getPurchaseOrderQuery, id)
+}
+
+func (r *Repository) GetPurchaseOrderForUpdate(ctx context.Context, tx pgx.Tx, id string) (*domain.PurchaseOrder, error) {
+	return r.getPurchaseOrder(ctx, tx, getPurchaseOrderForUpdateQuery, id)
+}
+
+func (r *Repository) getPurchaseOrder(ctx context.Context, conn queryConn, q, id string) (*domain.PurchaseOrder, error) {
+	po, err := query.GetOne[dto.DBPurchaseOrder](ctx, conn, q, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get purchase order")
+	}
+	orders, err := r.withPurchaseOrderLines(ctx, conn, []dto.DBPurchaseOrder{*po})
+	if err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+const listPurchaseOrdersQuery = `
+SELECT ` + purchaseOrderColumns + `
+FROM purchase_orders
+WHERE $1 = '' OR status = $1
+ORDER BY created_at DESC, id
+LIMIT $2
+`
+
+func (r *Repository) ListPurchaseOrders(ctx context.Context, status domain.PurchaseOrderStatus, limit int) ([]domain.PurchaseOrder, error) {
+	conn := r.ReadConn()
+	items, err := query.GetAll[dto.DBPurchaseOrder](ctx, conn, listPurchaseOrdersQuery, string(status), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "list purchase orders")
+	}
+	return r.withPurchaseOrderLines(ctx, conn, items)
+}
+
+func (r *Repository) withPurchaseOrderLines(ctx context.Context, conn queryConn, orders []dto.DBPurchaseOrder) ([]domain.PurchaseOrder, error) {
+	ids := make([]string, 0, len(orders))
+	for _, po := range orders {
+		ids = append(ids, po.ID)
+	}
+	dbLines, err := query.GetAll[dto.DBPurchaseOrderLine](ctx, conn, getPurchaseOrderLinesQuery, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "get purchase order lines")
+	}
+	lines := make(map[string][]domain.PurchaseOrderLine, len(orders))
+	for _, l := range dbLines {
+		lines[l.PurchaseOrderID] = append(lines[l.PurchaseOrderID], dto.PurchaseOrderLineToDomain(l))
+	}
+	result := make([]domain.PurchaseOrder, 0, len(orders))
+	for _, po := range orders {
+		result = append(result, dto.PurchaseOrderToDomain(po, lines[po.ID]))
+	}
+	return result, nil
+}
+
+const updatePurchaseOrderStatusQuery = `
+UPDATE purchase_orders
+SET status = $2, updated_at = $3
+WHERE id = $1
+RETURNING ` + purchaseOrderColumns
+
+func (r *Repository) UpdatePurchaseOrderStatus(ctx context.Context, tx pgx.Tx, id string, status domain.PurchaseOrderStatus) (*domain.PurchaseOrder, error) {
+	po, err := query.GetOne[dto.DBPurchaseOrder](ctx, tx, updatePurchaseOrderStatusQuery, id, string(status), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("purchase order not found")
+		}
+		return nil, errors.Wrap(err, "update purchase order status")
+	}
+	orders, err := r.withPurchaseOrderLines(ctx, tx, []dto.DBPurchaseOrder{*po})
+	if err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+const createPurchaseReceiptQuery = `
+INSERT INTO purchase_receipts (id, purchase_order_id, received_by, received_at)
+VALUES ($1, $2, $3, $4)
+`
+
+const createPurchaseReceiptLineQuery = `
+INSERT INTO purchase_receipt_lines (receipt_id, line_id, product_id, quantity)
+VALUES ($1, $2, $3, $4)
+`
+
+const addReceivedQuantityQuery = `
+UPDATE purchase_order_lines
+SET received_quantity = received_quantity + $2
+WHERE id = $1
+`
+
+func (r *Repository) RecordPurchaseReceipt(ctx context.Context, tx pgx.Tx, receipt *domain.PurchaseReceipt) (*domain.PurchaseReceipt, error) {
+	if receipt.ID == "" {
+		receipt.ID = r.ug.V4()
+	}
+	if receipt.ReceivedAt.IsZero() {
+		receipt.ReceivedAt = time.Now().UTC()
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(createPurchaseReceiptQuery, receipt.ID, receipt.PurchaseOrderID, receipt.ReceivedBy, receipt.ReceivedAt)
+	for _, l := range receipt.Lines {
+		batch.Queue(createPurchaseReceiptLineQuery, receipt.ID, l.LineID, l.ProductID, l.Quantity)
+		batch.Queue(addReceivedQuantityQuery, l.LineID, l.Quantity)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, referenceError(err, "record purchase receipt")
+	}
+	result := *receipt
+	return &result, nil
+}
+
+const listPurchaseReceiptsQuery = `
+SELECT id, purchase_order_id, received_by, received_at
+FROM purchase_receipts
+WHERE purchase_order_id = $1
+ORDER BY received_at, id
+`
+
+const listPurchaseReceiptLinesQuery = `
+SELECT l.receipt_id, l.line_id, l.product_id, l.quantity
+FROM purchase_receipt_lines l
+JOIN purchase_receipts r ON r.id = l.receipt_id
+WHERE r.purchase_order_id = $1
+ORDER BY l.product_id
+`
+
+func (r *Repository) ListPurchaseReceipts(ctx context.Context, purchaseOrderID string) ([]domain.PurchaseReceipt, error) {
+	conn := r.ReadConn()
+	receipts, err := query.GetAll[dto.DBPurchaseReceipt](ctx, conn, listPurchaseReceiptsQuery, purchaseOrderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list purchase receipts")
+	}
+	dbLines, err := query.GetAll[dto.DBPurchaseReceiptLine](ctx, conn, listPurchaseReceiptLinesQuery, purchaseOrderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list purchase receipt lines")
+	}
+	lines := make(map[string][]domain.PurchaseReceiptLine, len(receipts))
+	for _, l := range dbLines {
+		lines[l.ReceiptID] = append(lines[l.ReceiptID], dto.PurchaseReceiptLineToDomain(l))
+	}
+	result := make([]domain.PurchaseReceipt, 0, len(receipts))
+	for _, rc := range receipts {
+		result = append(result, dto.PurchaseReceiptToDomain(rc, lines[rc.ID]))
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/dto"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+// Drafts count as on order too, so a buyer who has started a purchase order
+// is not told to order the same stock again.
+const listReorderCandidatesQuery = `
+WITH sold AS (
+	SELECT oi.product_id, SUM(oi.quantity) AS units
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	WHERE o.created_at >= $1 AND o.status <> 'cancelled'
+	GROUP BY oi.product_id
+), on_order AS (
+	SELECT l.product_id, SUM(l.quantity - l.received_quantity) AS units
+	FROM purchase_order_lines l
+	JOIN purchase_orders po ON po.id = l.purchase_order_id
+	WHERE po.status IN ('draft', 'sent', 'partially_received')
+	GROUP BY l.product_id
+), backordered AS (
+	SELECT product_id, SUM(quantity - filled_quantity) AS units
+	FROM backorders
+	WHERE status = 'open'
+	GROUP BY product_id
+), last_supplier AS (
+	SELECT DISTINCT ON (l.product_id) l.product_id, s.id, s.name, s.lead_time_days
+	FROM purchase_order_lines l
+	JOIN purchase_orders po ON po.id = l.purchase_order_id
+	JOIN suppliers s ON s.id = po.supplier_id
+	ORDER BY l.product_id, po.created_at DESC, po.id
+)
+SELECT p.id AS product_id, p.description, p.quantity, p.reorder_point, p.safety_stock,
+	COALESCE(sold.units, 0)::int AS units_sold,
+	COALESCE(on_order.units, 0)::int AS on_order,
+	COALESCE(backordered.units, 0)::int AS backordered,
+	COALESCE(ls.id::text, '') AS supplier_id,
+	COALESCE(ls.name, '') AS supplier_name,
+	ls.lead_time_days
+FROM products p
+LEFT JOIN sold ON sold.product_id = p.id
+LEFT JOIN on_order ON on_order.product_id = p.id
+LEFT JOIN backordered ON backordered.product_id = p.id
+LEFT JOIN last_supplier ls ON ls.product_id = p.id
+ORDER BY p.id
+`
+
+func (r *Repository) ListReorderCandidates(ctx context.Context, tx pgx.Tx, since time.Time) ([]domain.ReorderCandidate, error) {
+	items, err := query.GetAll[dto.DBReorderCandidate](ctx, tx, listReorderCandidatesQuery, since)
+	if err != nil {
+		return nil, errors.Wrap(err, "list reorder candidates")
+	}
+	result := make([]domain.ReorderCandidate, 0, len(items))
+	for _, c := range items {
+		result = append(result, dto.ReorderCandidateToDomain(c))
+	}
+	return result, nil
+}
@@ -2,9 +2,11 @@ package postgres
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"stockpilot/internal/domain"
 	"stockpilot/internal/repository/dto"
 	"stockpilot/pkg/gonerve/errors"
@@ -16,14 +18,22 @@ import (
 type Repository struct {
 	*postgresql.Repository
 	ug genuuid.GeneratorUUID
+	// origin tells this instance's stock notifications from the others'.
+	origin string
 }
 
-func New(ctx context.Context, cfg postgresql.Config) (*Repository, error) {
-	r, err := postgresql.NewRepository(ctx, cfg.ToConnString(), postgresql.WithListenNotifications(cfg.ListenNotifications))
+func New(ctx context.Context, cfg postgresql.Config, opts ...postgresql.Option) (*Repository, error) {
+	opts = append([]postgresql.Option{
+		postgresql.WithListenNotifications(cfg.ListenNotifications),
+		postgresql.WithPool(cfg.Pool),
+		postgresql.WithReplicas(cfg.ReplicaConfig()),
+	}, opts...)
+	r, err := postgresql.NewRepository(ctx, cfg.ToConnString(), opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "postgresql.NewRepository")
 	}
-	return &Repository{Repository: r, ug: genuuid.New()}, nil
+	ug := genuuid.New()
+	return &Repository{Repository: r, ug: ug, origin: ug.V4()}, nil
 }
 
 const createUserQuery = `
@@ -59,11 +69,16 @@ FROM users
 WHERE email = $1
 `
 
+func init() {
+	query.RegisterSensitiveArgs(createUserQuery, 2, 7)
+	query.RegisterSensitiveArgs(getUserByEmailQuery, 1)
+}
+
 func (r *Repository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	conv := func(u dto.DBUser) (domain.User, error) {
 		return dto.UserToDomain(u), nil
 	}
-	u, err := query.SelectOneWithConverterError(ctx, r.Conn, getUserByEmailQuery, conv, email)
+	u, err := query.SelectOneWithConverterError(ctx, r.ReadConn(), getUserByEmailQuery, conv, email)
 	if err != nil {
 		if errors.Is(err, errors.ErrNotFound) {
 			return nil, nil
@@ -83,6 +98,7 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*domain.User, erro
 	conv := func(u dto.DBUser) (domain.User, error) {
 		return dto.UserToDomain(u), nil
 	}
+	// Stays on the primary: orders look the user up right after registration.
 	u, err := query.SelectOneWithConverterError(ctx, r.Conn, getUserByIDQuery, conv, id)
 	if err != nil {
 		if errors.Is(err, errors.ErrNotFound) {
@@ -94,9 +110,9 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*domain.User, erro
 }
 
 const createProductQuery = `
-INSERT INTO products (id, description, tags, quantity, price, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $6)
-RETURNING id, description, tags, quantity, price, created_at, updated_at
+INSERT INTO products (id, description, tags, quantity, price, reorder_point, safety_stock, allow_backorder, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING id, description, tags, quantity, price, reorder_point, safety_stock, allow_backorder, created_at, updated_at
 `
 
 func (r *Repository) CreateProduct(ctx context.Context, product *domain.Product) (*domain.Product, error) {
@@ -117,7 +133,7 @@ func (r *Repository) CreateProduct(ctx context.Context, product *domain.Product)
 	conv := func(p dto.DBProduct) (domain.Product, error) {
 		return dto.ProductToDomain(p), nil
 	}
-	p, err := query.SelectOneWithConverterError(ctx, r.Conn, createProductQuery, conv, dbProduct.ID, dbProduct.Description, dbProduct.Tags, dbProduct.Quantity, dbProduct.Price, dbProduct.CreatedAt)
+	p, err := query.SelectOneWithConverterError(ctx, r.Conn, createProductQuery, conv, dbProduct.ID, dbProduct.Description, dbProduct.Tags, dbProduct.Quantity, dbProduct.Price, dbProduct.ReorderPoint, dbProduct.SafetyStock, dbProduct.AllowBackorder, dbProduct.CreatedAt)
 	if err != nil {
 		return nil, errors.Wrap(err, "create product")
 	}
@@ -125,7 +141,7 @@ func (r *Repository) CreateProduct(ctx context.Context, product *domain.Product)
 }
 
 const getProductByIDQuery = `
-SELECT id, description, tags, quantity, price, created_at, updated_at
+SELECT id, description, tags, quantity, price, reorder_point, safety_stock, allow_backorder, created_at, updated_at
 FROM products
 WHERE id = $1
 `
@@ -134,7 +150,7 @@ func (r *Repository) GetProductByID(ctx context.Context, id string) (*domain.Pro
 	conv := func(p dto.DBProduct) (domain.Product, error) {
 		return dto.ProductToDomain(p), nil
 	}
-	p, err := query.SelectOneWithConverterError(ctx, r.Conn, getProductByIDQuery, conv, id)
+	p, err := query.SelectOneWithConverterError(ctx, r.ReadConn(), getProductByIDQuery, conv, id)
 	if err != nil {
 		if errors.Is(err, errors.ErrNotFound) {
 			return nil, nil
@@ -144,18 +160,61 @@ func (r *Repository) GetProductByID(ctx context.Context, id string) (*domain.Pro
 	return &p, nil
 }
 
+const listStockLevelsQuery = `SELECT id, quantity, updated_at FROM products ORDER BY id`
+
+func (r *Repository) ListStockLevels(ctx context.Context) ([]domain.StockLevel, error) {
+	items, err := query.GetAll[dto.DBStockLevel](ctx, r.ReadConn(), listStockLevelsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "list stock levels")
+	}
+	result := make([]domain.StockLevel, 0, len(items))
+	for _, l := range items {
+		result = append(result, dto.StockLevelToDomain(l))
+	}
+	return result, nil
+}
+
 const getProductsForUpdateQuery = `
-SELECT id, description, tags, quantity, price, created_at, updated_at
+SELECT id, description, tags, quantity, price, reorder_point, safety_stock, allow_backorder, created_at, updated_at
 FROM products
 WHERE id = ANY($1)
+ORDER BY id
 FOR UPDATE
 `
 
-func (r *Repository) GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []string) ([]domain.Product, error) {
-	items, err := query.GetAll[dto.DBProduct](ctx, tx, getProductsForUpdateQuery, ids)
+var lockClauses = map[domain.LockMode]string{
+	domain.LockNoWait:     " NOWAIT",
+	domain.LockSkipLocked: " SKIP LOCKED",
+}
+
+const lockNotAvailableCode = "55P03"
+
+const countProductsQuery = `SELECT count(*) FROM products WHERE id = ANY($1)`
+
+func (r *Repository) GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []string, mode domain.LockMode) ([]domain.Product, error) {
+	q := getProductsForUpdateQuery
+	if clause, ok := lockClauses[mode]; ok {
+		q = strings.TrimRight(q, "\n") + clause
+	}
+	items, err := query.GetAll[dto.DBProduct](ctx, tx, q, ids)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == lockNotAvailableCode {
+			return nil, errors.New("product is busy")
+		}
 		return nil, errors.Wrap(err, "get products for update")
 	}
+	if mode == domain.LockSkipLocked && len(items) < len(ids) {
+		// Skipped rows look like missing ones; tell them apart so the caller
+		// reports busy products instead of unknown ones.
+		var existing int
+		if err := tx.QueryRow(ctx, countProductsQuery, ids).Scan(&existing); err != nil {
+			return nil, errors.Wrap(err, "count products")
+		}
+		if existing > len(items) {
+			return nil, errors.New("product is busy")
+		}
+	}
 	result := make([]domain.Product, 0, len(items))
 	for _, p := range items {
 		result = append(result, dto.ProductToDomain(p))
@@ -163,6 +222,50 @@ func (r *Repository) GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []str
 	return result, nil
 }
 
+const setReorderPolicyQuery = `
+UPDATE products
+SET reorder_point = $2, safety_stock = $3, updated_at = $4
+WHERE id = $1
+RETURNING id, description, tags, quantity, price, reorder_point, safety_stock, allow_backorder, created_at, updated_at
+`
+
+func (r *Repository) SetReorderPolicy(ctx context.Context, id string, reorderPoint, safetyStock int) (*domain.Product, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	p, err := query.GetOne[dto.DBProduct](ctx, r.Conn, setReorderPolicyQuery, id, reorderPoint, safetyStock, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, errors.Wrap(err, "set reorder policy")
+	}
+	result := dto.ProductToDomain(*p)
+	return &result, nil
+}
+
+const setBackorderPolicyQuery = `
+UPDATE products
+SET allow_backorder = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, description, tags, quantity, price, reorder_point, safety_stock, allow_backorder, created_at, updated_at
+`
+
+func (r *Repository) SetBackorderPolicy(ctx context.Context, id string, allow bool) (*domain.Product, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	p, err := query.GetOne[dto.DBProduct](ctx, r.Conn, setBackorderPolicyQuery, id, allow, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, errors.Wrap(err, "set backorder policy")
+	}
+	result := dto.ProductToDomain(*p)
+	return &result, nil
+}
+
 const updateQuantityQuery = `
 UPDATE products
 SET quantity = quantity + $2, updated_at = $3
@@ -174,17 +277,56 @@ func (r *Repository) UpdateQuantity(ctx context.Context, tx pgx.Tx, id string, d
 	err := query.Exec(ctx, tx, updateQuantityQuery, id, delta, time.Now().UTC())
 	if err != nil {
 		if errors.Is(err, errors.ErrNotFound) {
-			return errors.New("insufficient stock")
+			return domain.ErrInsufficientStock
 		}
 		return errors.Wrap(err, "update quantity")
 	}
 	return nil
 }
 
+const updateQuantitiesQuery = `
+UPDATE products p
+SET quantity = p.quantity + d.delta, updated_at = $3
+FROM unnest($1::uuid[], $2::int[]) AS d(id, delta)
+WHERE p.id = d.id AND p.quantity + d.delta >= 0
+`
+
+// UpdateQuantities applies all changes with a single statement. Every
+// product must appear at most once.
+func (r *Repository) UpdateQuantities(ctx context.Context, tx pgx.Tx, changes []domain.StockChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	ids := make([]string, len(changes))
+	deltas := make([]int32, len(changes))
+	for i, c := range changes {
+		ids[i] = c.ProductID
+		deltas[i] = int32(c.Delta)
+	}
+	tag, err := tx.Exec(ctx, updateQuantitiesQuery, ids, deltas, time.Now().UTC())
+	if err != nil {
+		return errors.Wrap(err, "update quantities")
+	}
+	if tag.RowsAffected() != int64(len(changes)) {
+		return domain.ErrInsufficientStock
+	}
+	return nil
+}
+
 const createOrderQuery = `
-INSERT INTO orders (id, user_id, created_at, total_price)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, created_at, total_price
+INSERT INTO orders (id, user_id, status, created_at, updated_at, total_price)
+VALUES ($1, $2, $3, $4, $4, $5)
+RETURNING id, user_id, status, created_at, updated_at, total_price
+`
+
+const createOrderItemQuery = `
+INSERT INTO order_items (id, order_id, product_id, quantity, price)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+const createBackorderQuery = `
+INSERT INTO backorders (id, order_id, order_item_id, product_id, quantity, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
 `
 
 func (r *Repository) CreateOrder(ctx context.Context, tx pgx.Tx, order *domain.Order, items []domain.OrderItem) (*domain.Order, error) {
@@ -194,25 +336,106 @@ func (r *Repository) CreateOrder(ctx context.Context, tx pgx.Tx, order *domain.O
 	if order.CreatedAt.IsZero() {
 		order.CreatedAt = time.Now().UTC()
 	}
+	if order.Status == "" {
+		order.Status = domain.OrderCreated
+	}
 	dbOrder := dto.OrderFromDomain(*order)
+
+	// The order and all of its items go to the server in one round trip.
+	batch := &pgx.Batch{}
 	var inserted dto.DBOrder
-	err := tx.QueryRow(ctx, createOrderQuery, dbOrder.ID, dbOrder.UserID, dbOrder.CreatedAt, dbOrder.TotalPrice).Scan(&inserted.ID, &inserted.UserID, &inserted.CreatedAt, &inserted.TotalPrice)
-	if err != nil {
-		return nil, errors.Wrap(err, "insert order")
-	}
+	batch.Queue(createOrderQuery, dbOrder.ID, dbOrder.UserID, dbOrder.Status, dbOrder.CreatedAt, dbOrder.TotalPrice).QueryRow(func(row pgx.Row) error {
+		return row.Scan(&inserted.ID, &inserted.UserID, &inserted.Status, &inserted.CreatedAt, &inserted.UpdatedAt, &inserted.TotalPrice)
+	})
 	for i := range items {
 		if items[i].ID == "" {
 			items[i].ID = r.ug.V4()
 		}
-		items[i].OrderID = inserted.ID
+		items[i].OrderID = dbOrder.ID
 		dbItem := dto.OrderItemFromDomain(items[i])
-		if err := query.Exec(ctx, tx, `
-INSERT INTO order_items (id, order_id, product_id, quantity, price)
-VALUES ($1, $2, $3, $4, $5)
-`, dbItem.ID, dbItem.OrderID, dbItem.ProductID, dbItem.Quantity, dbItem.Price); err != nil {
-			return nil, errors.Wrap(err, "insert order item")
+		batch.Queue(createOrderItemQuery, dbItem.ID, dbItem.OrderID, dbItem.ProductID, dbItem.Quantity, dbItem.Price)
+		if items[i].Backordered > 0 {
+			batch.Queue(createBackorderQuery, r.ug.V4(), dbItem.OrderID, dbItem.ID, dbItem.ProductID, items[i].Backordered, dbOrder.CreatedAt)
 		}
 	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, errors.Wrap(err, "insert order")
+	}
 	conv := dto.OrderToDomain(inserted, items)
 	return &conv, nil
 }
+
+const getOrderQuery = `
+SELECT id, user_id, status, created_at, updated_at, total_price
+FROM orders
+WHERE id = $1
+`
+
+const getOrderForUpdateQuery = getOrderQuery + `FOR UPDATE
+`
+
+const getOrderItemsQuery = `
+SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price,
+	COALESCE(b.quantity - b.filled_quantity, 0) AS backordered
+FROM order_items oi
+LEFT JOIN backorders b ON b.order_item_id = oi.id AND b.status = 'open'
+WHERE oi.order_id = $1
+ORDER BY oi.id
+`
+
+// GetOrder reads from the primary, as orders are usually read right after
+// they change.
+func (r *Repository) GetOrder(ctx context.Context, id string) (*domain.Order, error) {
+	order, err := r.getOrder(ctx, r.Conn, getOrderQuery, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get order")
+	}
+	return order, nil
+}
+
+func (r *Repository) GetOrderForUpdate(ctx context.Context, tx pgx.Tx, id string) (*domain.Order, error) {
+	order, err := r.getOrder(ctx, tx, getOrderForUpdateQuery, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get order for update")
+	}
+	return order, nil
+}
+
+func (r *Repository) getOrder(ctx context.Context, conn queryConn, q, id string) (*domain.Order, error) {
+	o, err := query.GetOne[dto.DBOrder](ctx, conn, q, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	dbItems, err := query.GetAll[dto.DBOrderItem](ctx, conn, getOrderItemsQuery, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get order items")
+	}
+	items := make([]domain.OrderItem, 0, len(dbItems))
+	for _, item := range dbItems {
+		items = append(items, dto.OrderItemToDomain(item))
+	}
+	order := dto.OrderToDomain(*o, items)
+	return &order, nil
+}
+
+const updateOrderStatusQuery = `
+UPDATE orders
+SET status = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, user_id, status, created_at, updated_at, total_price
+`
+
+func (r *Repository) UpdateOrderStatus(ctx context.Context, tx pgx.Tx, id string, status domain.OrderStatus) (*domain.Order, error) {
+	o, err := query.GetOne[dto.DBOrder](ctx, tx, updateOrderStatusQuery, id, string(status), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("order not found")
+		}
+		return nil, errors.Wrap(err, "update order status")
+	}
+	order := dto.OrderToDomain(*o, nil)
+	return &order, nil
+}
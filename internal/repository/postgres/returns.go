@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/dto"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+const returnColumns = `id, order_id, reason, status, created_at, updated_at, received_at, resolved_at`
+
+const createReturnQuery = `
+INSERT INTO returns (id, order_id, reason, status, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5)
+`
+
+const createReturnLineQuery = `
+INSERT INTO return_lines (id, return_id, order_item_id, product_id, quantity, price)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+func (r *Repository) CreateReturn(ctx context.Context, tx pgx.Tx, ret *domain.Return) (*domain.Return, error) {
+	result := *ret
+	if result.ID == "" {
+		result.ID = r.ug.V4()
+	}
+	if result.CreatedAt.IsZero() {
+		result.CreatedAt = time.Now().UTC()
+	}
+	if result.Status == "" {
+		result.Status = domain.ReturnAuthorized
+	}
+	result.UpdatedAt = result.CreatedAt
+
+	batch := &pgx.Batch{}
+	batch.Queue(createReturnQuery, result.ID, result.OrderID, result.Reason, string(result.Status), result.CreatedAt)
+	result.Lines = make([]domain.ReturnLine, len(ret.Lines))
+	for i, l := range ret.Lines {
+		if l.ID == "" {
+			l.ID = r.ug.V4()
+		}
+		l.ReturnID = result.ID
+		result.Lines[i] = l
+		batch.Queue(createReturnLineQuery, l.ID, l.ReturnID, l.OrderItemID, l.ProductID, l.Quantity, l.Price)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, errors.Wrap(err, "insert return")
+	}
+	return &result, nil
+}
+
+const returnedQuantitiesQuery = `
+SELECT l.order_item_id, SUM(l.quantity) AS quantity
+FROM return_lines l
+JOIN returns r ON r.id = l.return_id
+WHERE r.order_id = $1
+GROUP BY l.order_item_id
+`
+
+func (r *Repository) ReturnedQuantities(ctx context.Context, tx pgx.Tx, orderID string) (map[string]int, error) {
+	items, err := query.GetAll[dto.DBReturnedQuantity](ctx, tx, returnedQuantitiesQuery, orderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get returned quantities")
+	}
+	result := make(map[string]int, len(items))
+	for _, item := range items {
+		result[item.OrderItemID] = item.Quantity
+	}
+	return result, nil
+}
+
+const getReturnQuery = `SELECT ` + returnColumns + ` FROM returns WHERE id = $1`
+
+const getReturnForUpdateQuery = getReturnQuery + ` FOR UPDATE`
+
+const getReturnLinesQuery = `
+SELECT id, return_id, order_item_id, product_id, quantity, price
+FROM return_lines
+WHERE return_id = ANY($1)
+ORDER BY return_id, order_item_id
+`
+
+const getRefundsQuery = `
+SELECT id, return_id, order_id, amount, created_at
+FROM refunds
+WHERE return_id = ANY($1)
+`
+
+func (r *Repository) GetReturn(ctx context.Context, id string) (*domain.Return, error) {
+	return r.getReturn(ctx, r.ReadConn(), getReturnQuery, id)
+}
+
+func (r *Repository) GetReturnForUpdate(ctx context.Context, tx pgx.Tx, id string) (*domain.Return, error) {
+	return r.getReturn(ctx, tx, getReturnForUpdateQuery, id)
+}
+
+func (r *Repository) getReturn(ctx context.Context, conn queryConn, q, id string) (*domain.Return, error) {
+	ret, err := query.GetOne[dto.DBReturn](ctx, conn, q, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get return")
+	}
+	returns, err := r.withReturnDetails(ctx, conn, []dto.DBReturn{*ret})
+	if err != nil {
+		return nil, err
+	}
+	return &returns[0], nil
+}
+
+const listReturnsQuery = `
+SELECT ` + returnColumns + `
+FROM returns
+WHERE order_id = $1
+ORDER BY created_at, id
+`
+
+func (r *Repository) ListReturns(ctx context.Context, orderID string) ([]domain.Return, error) {
+	conn := r.ReadConn()
+	items, err := query.GetAll[dto.DBReturn](ctx, conn, listReturnsQuery, orderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list returns")
+	}
+	return r.withReturnDetails(ctx, conn, items)
+}
+
+func (r *Repository) withReturnDetails(ctx context.Context, conn queryConn, returns []dto.DBReturn) ([]domain.Return, error) {
+	ids := make([]string, 0, len(returns))
+	for _, ret := range returns {
+		ids = append(ids, ret.ID)
+	}
+	dbLines, err := query.GetAll[dto.DBReturnLine](ctx, conn, getReturnLinesQuery, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "get return lines")
+	}
+	lines := make(map[string][]domain.ReturnLine, len(returns))
+	for _, l := range dbLines {
+		lines[l.ReturnID] = append(lines[l.ReturnID], dto.ReturnLineToDomain(l))
+	}
+	dbRefunds, err := query.GetAll[dto.DBRefund](ctx, conn, getRefundsQuery, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "get refunds")
+	}
+	refunds := make(map[string]*domain.Refund, len(dbRefunds))
+	for _, rf := range dbRefunds {
+		refund := dto.RefundToDomain(rf)
+		refunds[rf.ReturnID] = &refund
+	}
+	result := make([]domain.Return, 0, len(returns))
+	for _, ret := range returns {
+		result = append(result, dto.ReturnToDomain(ret, lines[ret.ID], refunds[ret.ID]))
+	}
+	return result, nil
+}
+
+const updateReturnStatusQuery = `
+UPDATE returns
+SET status = $2, updated_at = $3,
+	received_at = CASE WHEN $2 = 'received' THEN $3 ELSE received_at END,
+	resolved_at = CASE WHEN $2 IN ('restocked', 'written_off') THEN $3 ELSE resolved_at END
+WHERE id = $1
+RETURNING ` + returnColumns
+
+func (r *Repository) UpdateReturnStatus(ctx context.Context, tx pgx.Tx, id string, status domain.ReturnStatus) (*domain.Return, error) {
+	ret, err := query.GetOne[dto.DBReturn](ctx, tx, updateReturnStatusQuery, id, string(status), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("return not found")
+		}
+		return nil, errors.Wrap(err, "update return status")
+	}
+	returns, err := r.withReturnDetails(ctx, tx, []dto.DBReturn{*ret})
+	if err != nil {
+		return nil, err
+	}
+	return &returns[0], nil
+}
+
+const createRefundQuery = `
+INSERT INTO refunds (id, return_id, order_id, amount, created_at)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+func (r *Repository) CreateRefund(ctx context.Context, tx pgx.Tx, refund *domain.Refund) (*domain.Refund, error) {
+	result := *refund
+	if result.ID == "" {
+		result.ID = r.ug.V4()
+	}
+	if result.CreatedAt.IsZero() {
+		result.CreatedAt = time.Now().UTC()
+	}
+	if _, err := tx.Exec(ctx, createRefundQuery, result.ID, result.ReturnID, result.OrderID, result.Amount, result.CreatedAt); err != nil {
+		return nil, errors.Wrap(err, "insert refund")
+	}
+	return &result, nil
+}
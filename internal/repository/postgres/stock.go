@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+)
+
+// stockChannel carries committed stock levels to the other instances.
+const stockChannel = "stock_levels"
+
+var stockLog = logging.Named("stock_notify")
+
+type stockNotification struct {
+	// Origin is the instance that changed the level; it has already
+	// published it to its own subscribers.
+	Origin string            `json:"origin"`
+	Level  domain.StockLevel `json:"level"`
+}
+
+// One notification per level keeps each payload far below the 8000 byte
+// limit however many products a change touches.
+const notifyStockQuery = `SELECT pg_notify($1, payload) FROM unnest($2::text[]) AS payload`
+
+// NotifyStock sends the levels as part of tx, or right away when tx is nil.
+// Like AddEvents it skips the NOTIFY unless the instances listen.
+func (r *Repository) NotifyStock(ctx context.Context, tx pgx.Tx, levels ...domain.StockLevel) error {
+	if r.Subscriber() == nil || len(levels) == 0 {
+		return nil
+	}
+	payloads := make([]string, 0, len(levels))
+	for _, l := range levels {
+		payload, err := json.Marshal(stockNotification{Origin: r.origin, Level: l})
+		if err != nil {
+			return errors.Wrap(err, "marshal stock level")
+		}
+		payloads = append(payloads, string(payload))
+	}
+	var conn queryConn = r.Conn
+	if tx != nil {
+		conn = tx
+	}
+	if _, err := conn.Exec(ctx, notifyStockQuery, stockChannel, payloads); err != nil {
+		return errors.Wrap(err, "notify stock levels")
+	}
+	return nil
+}
+
+// SubscribeStock hands h the stock levels other instances commit. It does
+// nothing unless the repository listens for notifications.
+func (r *Repository) SubscribeStock(h func(levels ...domain.StockLevel)) {
+	sub := r.Subscriber()
+	if sub == nil {
+		return
+	}
+	sub.Subscribe(stockChannel, func(ctx context.Context, n *pgconn.Notification) {
+		var msg stockNotification
+		if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			stockLog.WarnCtx(ctx, "invalid stock notification", zap.Error(err))
+			return
+		}
+		if msg.Origin == r.origin {
+			return
+		}
+		h(msg.Level)
+	})
+}
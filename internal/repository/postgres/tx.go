@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/postgresql"
+)
+
+// WithTx runs f in a transaction of the embedded repository, with the domain
+// options mapped to postgresql ones.
+func (r *Repository) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error, opts ...domain.TxOption) error {
+	return r.Repository.WithTx(ctx, f, txOptions(domain.NewTxOptions(opts...))...)
+}
+
+// txOptions leaves retries at the postgresql default.
+func txOptions(o domain.TxOptions) []postgresql.TxOption {
+	var result []postgresql.TxOption
+	switch o.Isolation {
+	case domain.IsolationReadCommitted:
+		result = append(result, postgresql.WithIsolation(pgx.ReadCommitted))
+	case domain.IsolationRepeatableRead:
+		result = append(result, postgresql.WithIsolation(pgx.RepeatableRead))
+	case domain.IsolationSerializable:
+		result = append(result, postgresql.WithIsolation(pgx.Serializable))
+	}
+	if o.ReadOnly {
+		result = append(result, postgresql.ReadOnly())
+	}
+	if o.Deferrable {
+		result = append(result, postgresql.Deferrable())
+	}
+	if o.Replica {
+		result = append(result, postgresql.OnReplica())
+	}
+	if o.StatementTimeout > 0 {
+		result = append(result, postgresql.WithStatementTimeout(o.StatementTimeout))
+	}
+	if o.LockTimeout > 0 {
+		result = append(result, postgresql.WithLockTimeout(o.LockTimeout))
+	}
+	return result
+}
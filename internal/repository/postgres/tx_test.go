@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/postgresql"
+)
+
+func TestTxOptionsMapsDomainOptions(t *testing.T) {
+	o := domain.NewTxOptions(domain.WithIsolation(domain.IsolationSerializable), domain.ReadOnly(), domain.Deferrable(),
+		domain.OnReplica(), domain.WithStatementTimeout(time.Second), domain.WithLockTimeout(100*time.Millisecond))
+
+	cfg := postgresql.NewTxConfig(txOptions(o)...)
+	require.Equal(t, pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly, DeferrableMode: pgx.Deferrable}, cfg.TxOptions)
+	require.True(t, cfg.Replica)
+	require.Equal(t, time.Second, cfg.StatementTimeout)
+	require.Equal(t, 100*time.Millisecond, cfg.LockTimeout)
+
+	require.Equal(t, postgresql.NewTxConfig(), postgresql.NewTxConfig(txOptions(domain.NewTxOptions())...))
+}
@@ -0,0 +1,262 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/internal/repository/dto"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/postgresql/query"
+)
+
+func init() {
+	query.RegisterSensitiveArgs(createWebhookQuery, 3)
+	query.RegisterSensitiveArgs(updateWebhookQuery, 3)
+}
+
+const webhookColumns = `id, url, secret, description, event_types, active, created_at, updated_at`
+
+const createWebhookQuery = `
+INSERT INTO webhook_subscriptions (id, url, secret, description, event_types, active, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+RETURNING ` + webhookColumns
+
+func (r *Repository) CreateWebhook(ctx context.Context, w *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	if w.ID == "" {
+		w.ID = r.ug.V4()
+	}
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now().UTC()
+	}
+	dbw := dto.WebhookSubscriptionFromDomain(*w)
+	created, err := query.GetOne[dto.DBWebhookSubscription](ctx, r.Conn, createWebhookQuery, dbw.ID, dbw.URL, dbw.Secret, dbw.Description, dbw.EventTypes, dbw.Active, dbw.CreatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "create webhook")
+	}
+	result := dto.WebhookSubscriptionToDomain(*created)
+	return &result, nil
+}
+
+const getWebhookQuery = `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+func (r *Repository) GetWebhook(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	w, err := query.GetOne[dto.DBWebhookSubscription](ctx, r.ReadConn(), getWebhookQuery, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get webhook")
+	}
+	result := dto.WebhookSubscriptionToDomain(*w)
+	return &result, nil
+}
+
+const listWebhooksQuery = `SELECT ` + webhookColumns + ` FROM webhook_subscriptions ORDER BY created_at, id`
+
+func (r *Repository) ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	items, err := query.GetAll[dto.DBWebhookSubscription](ctx, r.ReadConn(), listWebhooksQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "list webhooks")
+	}
+	result := make([]domain.WebhookSubscription, 0, len(items))
+	for _, w := range items {
+		result = append(result, dto.WebhookSubscriptionToDomain(w))
+	}
+	return result, nil
+}
+
+const updateWebhookQuery = `
+UPDATE webhook_subscriptions
+SET url = $2, secret = $3, description = $4, event_types = $5, active = $6, updated_at = $7
+WHERE id = $1
+RETURNING ` + webhookColumns
+
+func (r *Repository) UpdateWebhook(ctx context.Context, w *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	dbw := dto.WebhookSubscriptionFromDomain(*w)
+	updated, err := query.GetOne[dto.DBWebhookSubscription](ctx, r.Conn, updateWebhookQuery, dbw.ID, dbw.URL, dbw.Secret, dbw.Description, dbw.EventTypes, dbw.Active, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, errors.Wrap(err, "update webhook")
+	}
+	result := dto.WebhookSubscriptionToDomain(*updated)
+	return &result, nil
+}
+
+const deleteWebhookQuery = `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+func (r *Repository) DeleteWebhook(ctx context.Context, id string) error {
+	if err := r.Locked(); err != nil {
+		return err
+	}
+	if err := query.Exec(ctx, r.Conn, deleteWebhookQuery, id); err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return errors.New("webhook not found")
+		}
+		return errors.Wrap(err, "delete webhook")
+	}
+	return nil
+}
+
+const enqueueWebhookDeliveriesQuery = `
+INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload)
+SELECT gen_random_uuid(), id, $1, $2, $3
+FROM webhook_subscriptions
+WHERE active AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+ON CONFLICT (subscription_id, event_id) DO NOTHING
+`
+
+func (r *Repository) EnqueueWebhookDeliveries(ctx context.Context, e outbox.Event) (int, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return 0, errors.Wrap(err, "marshal event")
+	}
+	tag, err := r.Conn.Exec(ctx, enqueueWebhookDeliveriesQuery, e.ID, e.Type, payload)
+	if err != nil {
+		return 0, errors.Wrap(err, "enqueue webhook deliveries")
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, updated_at`
+
+// Claiming pushes next_attempt_at past the lease instead of holding row
+// locks while the requests are in flight. Deliveries of inactive
+// subscriptions stay pending until the subscription is activated again.
+const claimWebhookDeliveriesQuery = `
+WITH due AS (
+	SELECT d.id FROM webhook_deliveries d
+	JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND s.active
+	ORDER BY d.next_attempt_at, d.id
+	LIMIT $1
+	FOR UPDATE OF d SKIP LOCKED
+), claimed AS (
+	UPDATE webhook_deliveries d
+	SET next_attempt_at = now() + make_interval(secs => $2)
+	FROM due
+	WHERE d.id = due.id
+	RETURNING d.*
+)
+SELECT c.id, c.subscription_id, c.event_id, c.event_type, c.payload, c.status, c.attempts, c.next_attempt_at,
+	c.last_status_code, c.last_error, c.created_at, c.updated_at, s.url, s.secret
+FROM claimed c
+JOIN webhook_subscriptions s ON s.id = c.subscription_id
+ORDER BY c.created_at, c.id
+`
+
+func (r *Repository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	items, err := query.GetAllNoLog[dto.DBWebhookDelivery](ctx, r.Conn, claimWebhookDeliveriesQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, errors.Wrap(err, "claim webhook deliveries")
+	}
+	return webhookDeliveriesToDomain(items), nil
+}
+
+const updateWebhookDeliveryQuery = `
+UPDATE webhook_deliveries
+SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5, last_error = $6, updated_at = now()
+WHERE id = $1
+`
+
+const insertWebhookAttemptQuery = `
+INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, latency_ms, response_snippet, error, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+func (r *Repository) RecordWebhookAttempt(ctx context.Context, d domain.WebhookDelivery, a domain.WebhookAttempt) error {
+	// Not WithTx: the dispatcher keeps recording attempts in maintenance mode.
+	err := pgx.BeginFunc(ctx, r.Conn, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		batch.Queue(updateWebhookDeliveryQuery, d.ID, string(d.Status), d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError)
+		batch.Queue(insertWebhookAttemptQuery, a.DeliveryID, a.Attempt, a.StatusCode, a.Latency.Milliseconds(), a.ResponseSnippet, a.Error, a.CreatedAt)
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	return errors.Wrap(err, "record webhook attempt")
+}
+
+const listWebhookDeliveriesQuery = `
+SELECT ` + webhookDeliveryColumns + `
+FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC, id
+LIMIT $2
+`
+
+func (r *Repository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]domain.WebhookDelivery, error) {
+	items, err := query.GetAll[dto.DBWebhookDelivery](ctx, r.ReadConn(), listWebhookDeliveriesQuery, subscriptionID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "list webhook deliveries")
+	}
+	return webhookDeliveriesToDomain(items), nil
+}
+
+const getWebhookDeliveryQuery = `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+const listWebhookAttemptsQuery = `
+SELECT delivery_id, attempt, status_code, latency_ms, response_snippet, error, created_at
+FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY id
+`
+
+func (r *Repository) GetWebhookDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, []domain.WebhookAttempt, error) {
+	d, err := query.GetOne[dto.DBWebhookDelivery](ctx, r.Conn, getWebhookDeliveryQuery, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.Wrap(err, "get webhook delivery")
+	}
+	items, err := query.GetAll[dto.DBWebhookAttempt](ctx, r.Conn, listWebhookAttemptsQuery, id)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "list webhook attempts")
+	}
+	attempts := make([]domain.WebhookAttempt, 0, len(items))
+	for _, a := range items {
+		attempts = append(attempts, dto.WebhookAttemptToDomain(a))
+	}
+	delivery := dto.WebhookDeliveryToDomain(*d)
+	return &delivery, attempts, nil
+}
+
+const replayWebhookDeliveryQuery = `
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now()
+WHERE id = $1
+RETURNING ` + webhookDeliveryColumns
+
+func (r *Repository) ReplayWebhookDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	if err := r.Locked(); err != nil {
+		return nil, err
+	}
+	d, err := query.GetOne[dto.DBWebhookDelivery](ctx, r.Conn, replayWebhookDeliveryQuery, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.New("delivery not found")
+		}
+		return nil, errors.Wrap(err, "replay webhook delivery")
+	}
+	delivery := dto.WebhookDeliveryToDomain(*d)
+	return &delivery, nil
+}
+
+func webhookDeliveriesToDomain(items []dto.DBWebhookDelivery) []domain.WebhookDelivery {
+	result := make([]domain.WebhookDelivery, 0, len(items))
+	for _, d := range items {
+		result = append(result, dto.WebhookDeliveryToDomain(d))
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+)
+
+// backorderFills hands incoming stock of locked products to their open
+// backorders within one transaction.
+type backorderFills struct {
+	backorders domain.BackorderRepository
+	outbox     domain.OutboxRepository
+	tx         pgx.Tx
+	// closed are the orders a backorder was filled for.
+	closed []string
+}
+
+func newBackorderFills(backorders domain.BackorderRepository, outbox domain.OutboxRepository, tx pgx.Tx) *backorderFills {
+	return &backorderFills{backorders: backorders, outbox: outbox, tx: tx}
+}
+
+// fill hands incoming stock of a locked product to its open backorders,
+// oldest first, and returns what is left for the shelf.
+func (f *backorderFills) fill(ctx context.Context, productID string, incoming int) (int, error) {
+	if f.backorders == nil || incoming <= 0 {
+		return incoming, nil
+	}
+	open, err := f.backorders.ListOpenBackorders(ctx, f.tx, productID)
+	if err != nil {
+		return 0, err
+	}
+	for _, b := range open {
+		if incoming == 0 {
+			break
+		}
+		n := min(b.Outstanding(), incoming)
+		if err := f.backorders.FillBackorder(ctx, f.tx, b.ID, n); err != nil {
+			return 0, err
+		}
+		incoming -= n
+		backorderUnitsFilledTotal.Add(float64(n))
+		if n == b.Outstanding() && !slices.Contains(f.closed, b.OrderID) {
+			f.closed = append(f.closed, b.OrderID)
+		}
+	}
+	return incoming, nil
+}
+
+// recordFulfilled records order.fulfilled for the orders left without open
+// backorders. It waits for other transactions closing backorders of the same
+// orders, so call it once the transaction holds all its row locks.
+func (f *backorderFills) recordFulfilled(ctx context.Context) error {
+	if f.outbox == nil || len(f.closed) == 0 {
+		return nil
+	}
+	fulfilled, err := f.backorders.FulfilledOrders(ctx, f.tx, f.closed)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, id := range fulfilled {
+		err := addEvent(ctx, f.outbox, f.tx, domain.EventOrderFulfilled, domain.AggregateOrder, id, domain.OrderFulfilledEvent{
+			OrderID:     id,
+			FulfilledAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
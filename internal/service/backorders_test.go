@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+)
+
+type backorderRepoMock struct {
+	items []domain.Backorder
+}
+
+func (m *backorderRepoMock) ListOpenBackorders(ctx context.Context, tx pgx.Tx, productID string) ([]domain.Backorder, error) {
+	var result []domain.Backorder
+	for _, b := range m.items {
+		if b.ProductID == productID && b.Status == domain.BackorderOpen {
+			result = append(result, b)
+		}
+	}
+	return result, nil
+}
+
+func (m *backorderRepoMock) FillBackorder(ctx context.Context, tx pgx.Tx, id string, quantity int) error {
+	for i := range m.items {
+		if m.items[i].ID == id {
+			m.items[i].FilledQuantity += quantity
+			if m.items[i].Outstanding() == 0 {
+				m.items[i].Status = domain.BackorderFilled
+			}
+		}
+	}
+	return nil
+}
+
+func (m *backorderRepoMock) FulfilledOrders(ctx context.Context, tx pgx.Tx, orderIDs []string) ([]string, error) {
+	var result []string
+	for _, id := range orderIDs {
+		if !slices.ContainsFunc(m.items, func(b domain.Backorder) bool {
+			return b.OrderID == id && b.Status == domain.BackorderOpen
+		}) {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+func (m *backorderRepoMock) CancelBackorders(ctx context.Context, tx pgx.Tx, orderID string) error {
+	for i := range m.items {
+		if m.items[i].OrderID == orderID && m.items[i].Status == domain.BackorderOpen {
+			m.items[i].Status = domain.BackorderCancelled
+		}
+	}
+	return nil
+}
+
+func TestOrderCreateBackordersShortPart(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 2, Price: decimal.NewFromInt(10), AllowBackorder: true},
+		},
+	}
+	svc := NewOrderService(products, &orderRepoMock{}, orderUserRepoMock{user: &domain.User{ID: "u1"}}, txManagerMock{tx: txMock{}},
+		WithOrderBackorders(&backorderRepoMock{}))
+
+	order, err := svc.Create(context.Background(), "u1", []OrderItemInput{{ProductID: "p1", Quantity: 5}})
+	require.NoError(t, err)
+	require.Equal(t, 0, products.items["p1"].Quantity)
+	require.Equal(t, 3, order.Items[0].Backordered)
+	require.Equal(t, domain.OrderBackordered, order.Fulfillment())
+	require.Equal(t, decimal.NewFromInt(50), order.TotalPrice)
+}
+
+func TestAdjustStockFillsBackordersOldestFirst(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{"p1": {ID: "p1", AllowBackorder: true}},
+	}
+	backorders := &backorderRepoMock{items: []domain.Backorder{
+		{ID: "b1", OrderID: "o1", ProductID: "p1", Quantity: 3, Status: domain.BackorderOpen},
+		{ID: "b2", OrderID: "o2", ProductID: "p1", Quantity: 4, Status: domain.BackorderOpen},
+	}}
+	svc := NewProductService(products, txManagerMock{tx: txMock{}}, WithProductBackorders(backorders))
+
+	product, err := svc.AdjustStock(context.Background(), "p1", 5, "delivery")
+	require.NoError(t, err)
+	require.Equal(t, 0, product.Quantity)
+	require.Equal(t, domain.BackorderFilled, backorders.items[0].Status)
+	require.Equal(t, 2, backorders.items[1].FilledQuantity)
+
+	product, err = svc.AdjustStock(context.Background(), "p1", 5, "delivery")
+	require.NoError(t, err)
+	require.Equal(t, 3, product.Quantity)
+	require.Equal(t, domain.BackorderFilled, backorders.items[1].Status)
+}
+
+func TestAdjustStockRecordsOrderFulfilledOnLastBackorder(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", AllowBackorder: true},
+			"p2": {ID: "p2", AllowBackorder: true},
+		},
+	}
+	backorders := &backorderRepoMock{items: []domain.Backorder{
+		{ID: "b1", OrderID: "o1", ProductID: "p1", Quantity: 3, Status: domain.BackorderOpen},
+		{ID: "b2", OrderID: "o1", ProductID: "p2", Quantity: 2, Status: domain.BackorderOpen},
+	}}
+	events := &outboxMock{}
+	svc := NewProductService(products, txManagerMock{tx: txMock{}}, WithProductBackorders(backorders), WithProductEvents(events))
+
+	_, err := svc.AdjustStock(context.Background(), "p1", 3, "delivery")
+	require.NoError(t, err)
+	require.Len(t, events.events, 1)
+	require.Equal(t, domain.EventStockAdjusted, events.events[0].Type)
+
+	_, err = svc.AdjustStock(context.Background(), "p2", 1, "delivery")
+	require.NoError(t, err)
+	require.Len(t, events.events, 2)
+
+	_, err = svc.AdjustStock(context.Background(), "p2", 1, "delivery")
+	require.NoError(t, err)
+	require.Len(t, events.events, 4)
+	require.Equal(t, domain.EventOrderFulfilled, events.events[2].Type)
+	require.Equal(t, "o1", events.events[2].AggregateID)
+}
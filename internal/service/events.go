@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/postgresql"
+)
+
+// addEvent records an event in tx. Services built without an outbox skip it.
+func addEvent(ctx context.Context, repo domain.OutboxRepository, tx pgx.Tx, eventType, aggregateType, aggregateID string, payload any) error {
+	if repo == nil {
+		return nil
+	}
+	e, err := outbox.NewEvent(eventType, aggregateType, aggregateID, payload)
+	if err != nil {
+		return err
+	}
+	return repo.AddEvents(ctx, tx, e)
+}
+
+// publishStock hands levels to the publishers once the transaction in ctx
+// commits, or right away outside a transaction. Publishers that are also a
+// domain.StockNotifier are told in tx first, which is nil outside one.
+func publishStock(ctx context.Context, tx pgx.Tx, publishers []domain.StockPublisher, levels ...domain.StockLevel) error {
+	if len(publishers) == 0 || len(levels) == 0 {
+		return nil
+	}
+	for _, p := range publishers {
+		if n, ok := p.(domain.StockNotifier); ok {
+			if err := n.NotifyStock(ctx, tx, levels...); err != nil {
+				return err
+			}
+		}
+	}
+	postgresql.AfterCommit(ctx, func() {
+		for _, p := range publishers {
+			p.PublishStock(levels...)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/webhook"
+)
+
+// LogNotifier writes alerts to the application log.
+type LogNotifier struct{}
+
+func (LogNotifier) Name() string { return "log" }
+
+func (LogNotifier) NotifyLowStock(ctx context.Context, alert domain.LowStockAlert) error {
+	alertLog.WarnCtx(ctx, "product stock is low",
+		zap.String("alert_id", alert.ID),
+		zap.String("product_id", alert.ProductID),
+		zap.String("severity", string(alert.Severity)),
+		zap.Int("quantity", alert.Quantity),
+		zap.Int("reorder_point", alert.ReorderPoint),
+		zap.Int("safety_stock", alert.SafetyStock))
+	return nil
+}
+
+// WebhookNotifier POSTs alerts to a fixed URL as stock.low events, signed
+// like webhook deliveries when a secret is set. It does not retry; webhook
+// subscriptions get the same event with retries through the outbox.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	sender *webhook.Sender
+}
+
+func NewWebhookNotifier(url, secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{url: url, secret: secret, sender: webhook.NewSender(timeout)}
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) NotifyLowStock(ctx context.Context, alert domain.LowStockAlert) error {
+	body, err := json.Marshal(stockLowEvent(alert))
+	if err != nil {
+		return err
+	}
+	res := n.sender.Send(ctx, webhook.Request{
+		URL:        n.url,
+		Secret:     n.secret,
+		Event:      domain.EventStockLow,
+		DeliveryID: alert.ID,
+		Body:       body,
+	})
+	if res.Err != nil {
+		return res.Err
+	}
+	if !res.OK() {
+		return errors.New("low stock webhook returned " + strconv.Itoa(res.StatusCode))
+	}
+	return nil
+}
+
+// MemoryNotifier keeps alerts in memory, for tests.
+type MemoryNotifier struct {
+	mu     sync.Mutex
+	alerts []domain.LowStockAlert
+}
+
+func (n *MemoryNotifier) Name() string { return "memory" }
+
+func (n *MemoryNotifier) NotifyLowStock(_ context.Context, alert domain.LowStockAlert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// Alerts returns the alerts notified so far, oldest first.
+func (n *MemoryNotifier) Alerts() []domain.LowStockAlert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]domain.LowStockAlert(nil), n.alerts...)
+}
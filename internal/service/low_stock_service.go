@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/tracing"
+)
+
+var alertLog = logging.Named("alerts")
+
+var (
+	lowStockAlertsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stockpilot_low_stock_alerts_total",
+		Help: "Low-stock alerts raised by severity.",
+	}, []string{"severity"})
+	lowStockNotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stockpilot_low_stock_notifications_total",
+		Help: "Low-stock alert notifications by notifier and result (sent, failed).",
+	}, []string{"notifier", "result"})
+)
+
+// LowStockService raises low-stock alerts from committed stock levels and
+// lets operators list and acknowledge them.
+//
+// Levels are not checked right away: a product is checked once, debounce
+// after its first change, so a quantity dipping below the reorder point
+// and recovering in between raises nothing. An alert is only raised when a
+// check finds the product below its reorder point after one that did not,
+// and never while another alert for it is open.
+//
+// Scheduled checks are kept in memory only: a restart within debounce of
+// a change drops its check, and a crossing it would have found is only
+// noticed by the next change of that product.
+type LowStockService struct {
+	alerts    domain.LowStockAlertRepository
+	tx        domain.TxManager
+	events    domain.OutboxRepository
+	notifiers []domain.LowStockNotifier
+	debounce  time.Duration
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+	wake    chan struct{}
+}
+
+type LowStockServiceOption func(s *LowStockService)
+
+func WithLowStockDebounce(d time.Duration) LowStockServiceOption {
+	return func(s *LowStockService) {
+		if d >= 0 {
+			s.debounce = d
+		}
+	}
+}
+
+// WithLowStockEvents records a stock.low event in the outbox for every raised
+// alert, so webhook subscribers get it like any other event.
+func WithLowStockEvents(repo domain.OutboxRepository) LowStockServiceOption {
+	return func(s *LowStockService) {
+		s.events = repo
+	}
+}
+
+func WithLowStockNotifiers(notifiers ...domain.LowStockNotifier) LowStockServiceOption {
+	return func(s *LowStockService) {
+		s.notifiers = append(s.notifiers, notifiers...)
+	}
+}
+
+func NewLowStockService(alerts domain.LowStockAlertRepository, tx domain.TxManager, opts ...LowStockServiceOption) *LowStockService {
+	s := &LowStockService{
+		alerts:   alerts,
+		tx:       tx,
+		debounce: 30 * time.Second,
+		pending:  map[string]time.Time{},
+		wake:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// PublishStock schedules a check of every changed product.
+func (s *LowStockService) PublishStock(levels ...domain.StockLevel) {
+	ids := make([]string, 0, len(levels))
+	for _, l := range levels {
+		ids = append(ids, l.ProductID)
+	}
+	s.ScheduleCheck(ids...)
+}
+
+// ScheduleCheck schedules a check of the products, debounced like stock
+// changes.
+func (s *LowStockService) ScheduleCheck(productIDs ...string) {
+	s.mu.Lock()
+	due := time.Now().Add(s.debounce)
+	for _, id := range productIDs {
+		if _, ok := s.pending[id]; !ok {
+			s.pending[id] = due
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks scheduled products when they are due until ctx is done.
+func (s *LowStockService) Run(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		next := s.checkDue(ctx)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// checkDue checks the products that are due and returns how long to wait
+// for the next one.
+func (s *LowStockService) checkDue(ctx context.Context) time.Duration {
+	now := time.Now()
+	next := time.Hour
+	var due []string
+	s.mu.Lock()
+	for id, at := range s.pending {
+		if wait := at.Sub(now); wait > 0 {
+			next = min(next, wait)
+			continue
+		}
+		due = append(due, id)
+		delete(s.pending, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range due {
+		if _, err := s.Check(ctx, id); err != nil && ctx.Err() == nil {
+			alertLog.WarnCtx(ctx, "low stock check failed", zap.String("product_id", id), zap.Error(err))
+		}
+	}
+	return next
+}
+
+// Check raises an alert for the product if it crossed below its reorder
+// point and has no open alert, and notifies about it. It returns nil
+// otherwise.
+func (s *LowStockService) Check(ctx context.Context, productID string) (*domain.LowStockAlert, error) {
+	var alert *domain.LowStockAlert
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		var err error
+		alert, err = s.alerts.RaiseLowStockAlert(ctx, tx, productID)
+		if err != nil || alert == nil {
+			return err
+		}
+		return addEvent(ctx, s.events, tx, domain.EventStockLow, domain.AggregateProduct, productID, stockLowEvent(*alert))
+	})
+	if err != nil || alert == nil {
+		return nil, err
+	}
+	lowStockAlertsTotal.WithLabelValues(string(alert.Severity)).Inc()
+	for _, n := range s.notifiers {
+		// The alert is stored and listed either way, so a failed notification
+		// is only logged.
+		if err := n.NotifyLowStock(ctx, *alert); err != nil {
+			lowStockNotificationsTotal.WithLabelValues(n.Name(), "failed").Inc()
+			alertLog.WarnCtx(ctx, "low stock notification failed", zap.String("notifier", n.Name()), zap.String("alert_id", alert.ID), zap.Error(err))
+			continue
+		}
+		lowStockNotificationsTotal.WithLabelValues(n.Name(), "sent").Inc()
+	}
+	return alert, nil
+}
+
+func (s *LowStockService) List(ctx context.Context, includeAcknowledged bool, limit int) ([]domain.LowStockAlert, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.alerts.ListLowStockAlerts(ctx, includeAcknowledged, limit)
+}
+
+func (s *LowStockService) Acknowledge(ctx context.Context, id, by string) (*domain.LowStockAlert, error) {
+	ctx = tracing.StartSpan(ctx, "LowStockService.Acknowledge")
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	if by == "" {
+		return nil, errors.New("acknowledged_by is required")
+	}
+	alert, err := s.alerts.AcknowledgeLowStockAlert(ctx, id, by)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return alert, nil
+}
+
+// stockLowEvent is the payload of the outbox event and the webhook notifier.
+func stockLowEvent(alert domain.LowStockAlert) domain.StockLowEvent {
+	return domain.StockLowEvent{
+		AlertID:      alert.ID,
+		ProductID:    alert.ProductID,
+		Severity:     alert.Severity,
+		Quantity:     alert.Quantity,
+		ReorderPoint: alert.ReorderPoint,
+		SafetyStock:  alert.SafetyStock,
+		RaisedAt:     alert.CreatedAt,
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/webhook"
+)
+
+type alertRepoMock struct {
+	domain.LowStockAlertRepository
+	mu     sync.Mutex
+	levels map[string]int
+	checks []domain.StockLevel
+	open   map[string]bool
+	below  map[string]bool
+}
+
+func (m *alertRepoMock) RaiseLowStockAlert(_ context.Context, _ pgx.Tx, productID string) (*domain.LowStockAlert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	qty := m.levels[productID]
+	m.checks = append(m.checks, domain.StockLevel{ProductID: productID, Quantity: qty})
+	p := domain.Product{Quantity: qty, ReorderPoint: 5, SafetyStock: 1}
+	wasBelow := m.below[productID]
+	m.below[productID] = p.LowStock()
+	if !p.LowStock() || wasBelow || m.open[productID] {
+		return nil, nil
+	}
+	m.open[productID] = true
+	return &domain.LowStockAlert{ID: "a-" + productID, ProductID: productID, Quantity: qty, Severity: domain.LowStockSeverity(qty, p.SafetyStock)}, nil
+}
+
+func (m *alertRepoMock) set(productID string, qty int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.levels[productID] = qty
+}
+
+func (m *alertRepoMock) acknowledge(productID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.open, productID)
+}
+
+func (m *alertRepoMock) checked() []domain.StockLevel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]domain.StockLevel(nil), m.checks...)
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Name() string { return "failing" }
+
+func (failingNotifier) NotifyLowStock(context.Context, domain.LowStockAlert) error {
+	return errors.New("unreachable")
+}
+
+func TestLowStockChecksOnceAfterDebounce(t *testing.T) {
+	repo := &alertRepoMock{levels: map[string]int{}, open: map[string]bool{}, below: map[string]bool{}}
+	notifier := &MemoryNotifier{}
+	events := &outboxMock{}
+	svc := NewLowStockService(repo, txManagerMock{tx: txMock{}},
+		WithLowStockDebounce(50*time.Millisecond),
+		WithLowStockNotifiers(failingNotifier{}, notifier),
+		WithLowStockEvents(events),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.Run(ctx)
+
+	// A dip that recovers before the window ends raises nothing.
+	repo.set("p1", 2)
+	svc.PublishStock(domain.StockLevel{ProductID: "p1"})
+	repo.set("p1", 8)
+	svc.PublishStock(domain.StockLevel{ProductID: "p1"})
+	require.Eventually(t, func() bool { return len(repo.checked()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Empty(t, notifier.Alerts())
+
+	repo.set("p1", 1)
+	svc.PublishStock(domain.StockLevel{ProductID: "p1"})
+	require.Eventually(t, func() bool { return len(notifier.Alerts()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, domain.AlertCritical, notifier.Alerts()[0].Severity)
+	require.Len(t, events.events, 1)
+	require.Equal(t, domain.EventStockLow, events.events[0].Type)
+	require.Equal(t, "p1", events.events[0].AggregateID)
+
+	// Staying below raises nothing, also once the alert is acknowledged.
+	repo.acknowledge("p1")
+	repo.set("p1", 0)
+	svc.PublishStock(domain.StockLevel{ProductID: "p1"})
+	require.Eventually(t, func() bool { return len(repo.checked()) == 3 }, time.Second, 5*time.Millisecond)
+	require.Len(t, notifier.Alerts(), 1)
+
+	// Recovering and falling again is a new crossing.
+	repo.set("p1", 6)
+	svc.PublishStock(domain.StockLevel{ProductID: "p1"})
+	require.Eventually(t, func() bool { return len(repo.checked()) == 4 }, time.Second, 5*time.Millisecond)
+	repo.set("p1", 3)
+	svc.PublishStock(domain.StockLevel{ProductID: "p1"})
+	require.Eventually(t, func() bool { return len(notifier.Alerts()) == 2 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, domain.AlertLow, notifier.Alerts()[1].Severity)
+}
+
+func TestLowStockAcknowledgeValidates(t *testing.T) {
+	svc := NewLowStockService(&alertRepoMock{}, txManagerMock{tx: txMock{}})
+	_, err := svc.Acknowledge(context.Background(), "a1", "")
+	require.EqualError(t, err, "acknowledged_by is required")
+	_, err = svc.Acknowledge(context.Background(), "", "ops")
+	require.EqualError(t, err, "id is required")
+}
+
+type stockPublisherMock struct {
+	levels []domain.StockLevel
+}
+
+func (m *stockPublisherMock) PublishStock(levels ...domain.StockLevel) {
+	m.levels = append(m.levels, levels...)
+}
+
+func TestSetReorderPolicyChecksWithoutPublishing(t *testing.T) {
+	repo := &alertRepoMock{levels: map[string]int{"p1": 4}, open: map[string]bool{}, below: map[string]bool{}}
+	notifier := &MemoryNotifier{}
+	lowStock := NewLowStockService(repo, txManagerMock{tx: txMock{}},
+		WithLowStockDebounce(10*time.Millisecond),
+		WithLowStockNotifiers(notifier),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go lowStock.Run(ctx)
+
+	stream := &stockPublisherMock{}
+	products := &productRepoMock{items: map[string]domain.Product{"p1": {ID: "p1", Quantity: 4}}}
+	svc := NewProductService(products, txManagerMock{tx: txMock{}},
+		WithProductStockPublisher(stream),
+		WithProductLowStockChecks(lowStock),
+	)
+	_, err := svc.SetReorderPolicy(context.Background(), "p1", 5, 1)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(notifier.Alerts()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Empty(t, stream.levels)
+}
+
+func TestWebhookNotifierSendsSignedEvent(t *testing.T) {
+	var got domain.StockLowEvent
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		err := webhook.Verify("s3cret", r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, time.Minute, time.Now())
+		if err != nil || json.Unmarshal(body, &got) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	alert := domain.LowStockAlert{ID: "a1", ProductID: "p1", Severity: domain.AlertLow, Quantity: 4, ReorderPoint: 5}
+	require.NoError(t, NewWebhookNotifier(srv.URL, "s3cret", time.Second).NotifyLowStock(context.Background(), alert))
+	require.Equal(t, domain.EventStockLow, headers.Get(webhook.HeaderEvent))
+	require.Equal(t, "a1", headers.Get(webhook.HeaderDelivery))
+	require.Equal(t, "p1", got.ProductID)
+	require.Equal(t, 4, got.Quantity)
+
+	err := NewWebhookNotifier(srv.URL, "wrong", time.Second).NotifyLowStock(context.Background(), alert)
+	require.Error(t, err)
+}
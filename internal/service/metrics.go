@@ -0,0 +1,33 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	ordersCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stockpilot_orders_created_total",
+		Help: "Orders successfully created.",
+	})
+	insufficientStockTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stockpilot_insufficient_stock_total",
+		Help: "Orders rejected because of insufficient stock.",
+	})
+	unitsSoldTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stockpilot_units_sold_total",
+		Help: "Product units sold through created orders.",
+	})
+	unavailableUnitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stockpilot_unavailable_units_total",
+		Help: "Units dropped from partially accepted orders for lack of stock.",
+	})
+	backorderedUnitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stockpilot_backordered_units_total",
+		Help: "Ordered units that were out of stock and went on backorder.",
+	})
+	backorderUnitsFilledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stockpilot_backorder_units_filled_total",
+		Help: "Incoming units that filled backorders.",
+	})
+)
@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
 
 	"stockpilot/internal/domain"
 	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/tracing"
 )
 
 type OrderItemInput struct {
@@ -16,28 +20,104 @@ type OrderItemInput struct {
 }
 
 type OrderService struct {
-	products domain.ProductRepository
-	orders   domain.OrderRepository
-	users    domain.UserRepository
-	tx       domain.TxManager
+	products   domain.ProductRepository
+	orders     domain.OrderRepository
+	users      domain.UserRepository
+	tx         domain.TxManager
+	outbox     domain.OutboxRepository
+	backorders domain.BackorderRepository
+	stock      []domain.StockPublisher
+	lockMode   domain.LockMode
 }
 
-func NewOrderService(products domain.ProductRepository, orders domain.OrderRepository, users domain.UserRepository, tx domain.TxManager) *OrderService {
-	return &OrderService{
+type OrderServiceOption func(s *OrderService)
+
+// WithLockMode makes checkout fail fast with "product is busy" instead of
+// waiting when another order holds the product rows.
+func WithLockMode(mode domain.LockMode) OrderServiceOption {
+	return func(s *OrderService) {
+		s.lockMode = mode
+	}
+}
+
+// WithOrderEvents records order.created and order.status_changed events in
+// the outbox, in the same transaction as the order change.
+func WithOrderEvents(outbox domain.OutboxRepository) OrderServiceOption {
+	return func(s *OrderService) {
+		s.outbox = outbox
+	}
+}
+
+// WithOrderBackorders lets orders backorder products that allow it, and
+// hands the stock of cancelled orders to open backorders first.
+func WithOrderBackorders(backorders domain.BackorderRepository) OrderServiceOption {
+	return func(s *OrderService) {
+		s.backorders = backorders
+	}
+}
+
+// WithOrderStockPublisher publishes the new levels of the products an order
+// takes or returns after the change commits. It can be given more than
+// once.
+func WithOrderStockPublisher(p domain.StockPublisher) OrderServiceOption {
+	return func(s *OrderService) {
+		s.stock = append(s.stock, p)
+	}
+}
+
+func NewOrderService(products domain.ProductRepository, orders domain.OrderRepository, users domain.UserRepository, tx domain.TxManager, opts ...OrderServiceOption) *OrderService {
+	s := &OrderService{
 		products: products,
 		orders:   orders,
 		users:    users,
 		tx:       tx,
+		lockMode: domain.LockWait,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type createOrderOptions struct {
+	policy domain.FulfillmentPolicy
+}
+
+type CreateOrderOption func(*createOrderOptions)
+
+// WithFulfillmentPolicy overrides the default FulfillAllOrNothing.
+func WithFulfillmentPolicy(policy domain.FulfillmentPolicy) CreateOrderOption {
+	return func(o *createOrderOptions) {
+		o.policy = policy
 	}
 }
 
-func (s *OrderService) Create(ctx context.Context, userID string, items []OrderItemInput) (*domain.Order, error) {
+// Create takes the ordered stock under row locks. With FulfillPartial each
+// line is cut down to the stock on hand and lines with none left are
+// dropped; otherwise the order fails unless the short products allow
+// backorders.
+func (s *OrderService) Create(ctx context.Context, userID string, items []OrderItemInput, opts ...CreateOrderOption) (*domain.Order, error) {
+	o := createOrderOptions{policy: domain.FulfillAllOrNothing}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	policy := o.policy
+	ctx = tracing.StartSpan(ctx, "OrderService.Create",
+		attribute.String("enduser.id", userID),
+		attribute.Int("order.lines", len(items)),
+		attribute.String("order.fulfillment_policy", string(policy)),
+	)
+	defer tracing.EndSpan(ctx)
+
 	if userID == "" {
 		return nil, errors.New("user id is required")
 	}
 	if len(items) == 0 {
 		return nil, errors.New("order items are required")
 	}
+	if policy != domain.FulfillAllOrNothing && policy != domain.FulfillPartial {
+		return nil, errors.New("invalid fulfillment policy")
+	}
 	for _, item := range items {
 		if item.ProductID == "" {
 			return nil, errors.New("product id is required")
@@ -48,6 +128,7 @@ func (s *OrderService) Create(ctx context.Context, userID string, items []OrderI
 	}
 	user, err := s.users.GetByID(ctx, userID)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		return nil, err
 	}
 	if user == nil {
@@ -63,7 +144,10 @@ func (s *OrderService) Create(ctx context.Context, userID string, items []OrderI
 				ids = append(ids, item.ProductID)
 			}
 		}
-		products, err := s.products.GetByIDsForUpdate(ctx, tx, ids)
+		// Rows are always locked in id order, so concurrent orders over the
+		// same products cannot deadlock.
+		sort.Strings(ids)
+		products, err := s.products.GetByIDsForUpdate(ctx, tx, ids, s.lockMode)
 		if err != nil {
 			return err
 		}
@@ -74,34 +158,232 @@ func (s *OrderService) Create(ctx context.Context, userID string, items []OrderI
 		for _, p := range products {
 			productMap[p.ID] = p
 		}
+		requested := make(map[string]int, len(ids))
+		for _, item := range items {
+			requested[item.ProductID] += item.Quantity
+		}
+		changes := make([]domain.StockChange, 0, len(ids))
+		available := make(map[string]int, len(ids))
+		for _, id := range ids {
+			product := productMap[id]
+			if product.Quantity < requested[id] && policy == domain.FulfillAllOrNothing && (!product.AllowBackorder || s.backorders == nil) {
+				return domain.ErrInsufficientStock
+			}
+			available[id] = product.Quantity
+			if taken := min(product.Quantity, requested[id]); taken > 0 {
+				changes = append(changes, domain.StockChange{ProductID: id, Delta: -taken})
+			}
+		}
+		if err := s.products.UpdateQuantities(ctx, tx, changes); err != nil {
+			return err
+		}
+		if err := publishStock(ctx, tx, s.stock, stockLevels(products, changes)...); err != nil {
+			return err
+		}
+
 		total := decimal.Zero
 		orderItems := make([]domain.OrderItem, 0, len(items))
+		lines := make([]domain.OrderLine, 0, len(items))
 		for _, item := range items {
-			product, ok := productMap[item.ProductID]
-			if !ok {
-				return errors.New("product not found")
+			product := productMap[item.ProductID]
+			// Stock goes to the lines in order; whatever is short is
+			// dropped in partial mode and backordered otherwise.
+			allocated := min(available[product.ID], item.Quantity)
+			available[product.ID] -= allocated
+			quantity := item.Quantity
+			if policy == domain.FulfillPartial {
+				quantity = allocated
 			}
-			if product.Quantity < item.Quantity {
-				return errors.New("insufficient stock")
+			lines = append(lines, domain.OrderLine{ProductID: product.ID, Requested: item.Quantity, Accepted: quantity})
+			if quantity == 0 {
+				continue
 			}
-			if err := s.products.UpdateQuantity(ctx, tx, product.ID, -item.Quantity); err != nil {
-				return err
-			}
-			linePrice := product.Price.Mul(decimal.NewFromInt(int64(item.Quantity)))
-			total = total.Add(linePrice)
+			total = total.Add(product.Price.Mul(decimal.NewFromInt(int64(quantity))))
 			orderItems = append(orderItems, domain.OrderItem{
-				ProductID: product.ID,
-				Quantity:  item.Quantity,
-				Price:     product.Price,
+				ProductID:   product.ID,
+				Quantity:    quantity,
+				Price:       product.Price,
+				Backordered: quantity - allocated,
 			})
 		}
+		if len(orderItems) == 0 {
+			return domain.ErrInsufficientStock
+		}
 		order := domain.Order{
 			UserID:     userID,
+			Status:     domain.OrderCreated,
 			TotalPrice: total,
 			Items:      orderItems,
 		}
 		created, err = s.orders.CreateOrder(ctx, tx, &order, orderItems)
-		return err
+		if err != nil {
+			return err
+		}
+		created.Lines = lines
+		return addEvent(ctx, s.outbox, tx, domain.EventOrderCreated, domain.AggregateOrder, created.ID, orderCreatedEvent(created))
 	})
-	return created, err
+	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientStock) {
+			insufficientStockTotal.Inc()
+		}
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	tracing.SetAttributes(ctx, attribute.String("order.id", created.ID))
+	ordersCreatedTotal.Inc()
+	for _, item := range created.Items {
+		unitsSoldTotal.Add(float64(item.Quantity))
+		backorderedUnitsTotal.Add(float64(item.Backordered))
+	}
+	for _, line := range created.Lines {
+		unavailableUnitsTotal.Add(float64(line.Requested - line.Accepted))
+	}
+	return created, nil
+}
+
+func (s *OrderService) Get(ctx context.Context, id string) (*domain.Order, error) {
+	ctx = tracing.StartSpan(ctx, "OrderService.Get", attribute.String("order.id", id))
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	order, err := s.orders.GetOrder(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	return order, nil
+}
+
+// UpdateStatus moves an order along created -> paid -> shipped -> delivered.
+// A backordered order cannot ship until its backorders are filled.
+// Cancelling a created or paid order puts its items back in stock and
+// cancels its backorders.
+func (s *OrderService) UpdateStatus(ctx context.Context, id string, status domain.OrderStatus) (*domain.Order, error) {
+	ctx = tracing.StartSpan(ctx, "OrderService.UpdateStatus",
+		attribute.String("order.id", id),
+		attribute.String("order.status", string(status)),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	var updated *domain.Order
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		order, err := s.orders.GetOrderForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New("order not found")
+		}
+		if !order.Status.CanTransition(status) {
+			return errors.New("invalid status transition")
+		}
+		if status == domain.OrderShipped && order.Fulfillment() == domain.OrderBackordered {
+			return errors.New("order is backordered")
+		}
+		if status == domain.OrderCancelled {
+			if err := s.restock(ctx, tx, order); err != nil {
+				return err
+			}
+		}
+		updated, err = s.orders.UpdateOrderStatus(ctx, tx, id, status)
+		if err != nil {
+			return err
+		}
+		updated.Items = order.Items
+		return addEvent(ctx, s.outbox, tx, domain.EventOrderStatusChanged, domain.AggregateOrder, id, domain.OrderStatusChangedEvent{
+			OrderID:   id,
+			From:      order.Status,
+			To:        status,
+			ChangedAt: updated.UpdatedAt,
+		})
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *OrderService) restock(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	returned := make(map[string]int, len(order.Items))
+	ids := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		if _, ok := returned[item.ProductID]; !ok {
+			ids = append(ids, item.ProductID)
+		}
+		returned[item.ProductID] += item.Quantity - item.Backordered
+	}
+	sort.Strings(ids)
+	// Same lock order as Create. Backordered products are locked too, as
+	// their backorders change.
+	products, err := s.products.GetByIDsForUpdate(ctx, tx, ids, domain.LockWait)
+	if err != nil {
+		return err
+	}
+	if s.backorders != nil && order.Fulfillment() == domain.OrderBackordered {
+		if err := s.backorders.CancelBackorders(ctx, tx, order.ID); err != nil {
+			return err
+		}
+	}
+	changes := make([]domain.StockChange, 0, len(ids))
+	fills := newBackorderFills(s.backorders, s.outbox, tx)
+	for _, id := range ids {
+		left, err := fills.fill(ctx, id, returned[id])
+		if err != nil {
+			return err
+		}
+		if left > 0 {
+			changes = append(changes, domain.StockChange{ProductID: id, Delta: left})
+		}
+	}
+	if err := s.products.UpdateQuantities(ctx, tx, changes); err != nil {
+		return err
+	}
+	if err := fills.recordFulfilled(ctx); err != nil {
+		return err
+	}
+	return publishStock(ctx, tx, s.stock, stockLevels(products, changes)...)
+}
+
+// stockLevels applies changes to the quantities of locked products.
+func stockLevels(locked []domain.Product, changes []domain.StockChange) []domain.StockLevel {
+	quantities := make(map[string]int, len(locked))
+	for _, p := range locked {
+		quantities[p.ID] = p.Quantity
+	}
+	now := time.Now().UTC()
+	levels := make([]domain.StockLevel, 0, len(changes))
+	for _, c := range changes {
+		levels = append(levels, domain.StockLevel{ProductID: c.ProductID, Quantity: quantities[c.ProductID] + c.Delta, UpdatedAt: now})
+	}
+	return levels
+}
+
+func orderCreatedEvent(o *domain.Order) domain.OrderCreatedEvent {
+	items := make([]domain.OrderCreatedEventItem, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, domain.OrderCreatedEventItem{
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			Backordered: item.Backordered,
+			Price:       item.Price.StringFixed(2),
+		})
+	}
+	return domain.OrderCreatedEvent{
+		OrderID:     o.ID,
+		UserID:      o.UserID,
+		Status:      o.Status,
+		Fulfillment: o.Fulfillment(),
+		TotalPrice:  o.TotalPrice.StringFixed(2),
+		Items:       items,
+		CreatedAt:   o.CreatedAt,
+	}
 }
@@ -6,11 +6,13 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 
 	"stockpilot/internal/domain"
 	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/outbox"
 )
 
 type txMock struct{}
@@ -40,12 +42,14 @@ type txManagerMock struct {
 	tx pgx.Tx
 }
 
-func (m txManagerMock) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error) error {
+func (m txManagerMock) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error, _ ...domain.TxOption) error {
 	return f(ctx, m.tx)
 }
 
 type productRepoMock struct {
-	items map[string]domain.Product
+	items     map[string]domain.Product
+	lockedIDs []string
+	lockMode  domain.LockMode
 }
 
 func (m *productRepoMock) CreateProduct(ctx context.Context, product *domain.Product) (*domain.Product, error) {
@@ -59,7 +63,9 @@ func (m *productRepoMock) GetProductByID(ctx context.Context, id string) (*domai
 	return nil, nil
 }
 
-func (m *productRepoMock) GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []string) ([]domain.Product, error) {
+func (m *productRepoMock) GetByIDsForUpdate(ctx context.Context, tx pgx.Tx, ids []string, mode domain.LockMode) ([]domain.Product, error) {
+	m.lockedIDs = append([]string(nil), ids...)
+	m.lockMode = mode
 	result := make([]domain.Product, 0, len(ids))
 	for _, id := range ids {
 		if p, ok := m.items[id]; ok {
@@ -75,24 +81,88 @@ func (m *productRepoMock) UpdateQuantity(ctx context.Context, tx pgx.Tx, id stri
 		return errors.New("product not found")
 	}
 	if p.Quantity+delta < 0 {
-		return errors.New("insufficient stock")
+		return domain.ErrInsufficientStock
 	}
 	p.Quantity += delta
 	m.items[id] = p
 	return nil
 }
 
+func (m *productRepoMock) SetReorderPolicy(ctx context.Context, id string, reorderPoint, safetyStock int) (*domain.Product, error) {
+	p, ok := m.items[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	p.ReorderPoint, p.SafetyStock = reorderPoint, safetyStock
+	m.items[id] = p
+	return &p, nil
+}
+
+func (m *productRepoMock) ListStockLevels(ctx context.Context) ([]domain.StockLevel, error) {
+	return nil, nil
+}
+
+func (m *productRepoMock) SetBackorderPolicy(ctx context.Context, id string, allow bool) (*domain.Product, error) {
+	p, ok := m.items[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	p.AllowBackorder = allow
+	m.items[id] = p
+	return &p, nil
+}
+
+func (m *productRepoMock) UpdateQuantities(ctx context.Context, tx pgx.Tx, changes []domain.StockChange) error {
+	for _, c := range changes {
+		if err := m.UpdateQuantity(ctx, tx, c.ProductID, c.Delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type orderRepoMock struct {
 	created *domain.Order
 }
 
 func (m *orderRepoMock) CreateOrder(ctx context.Context, tx pgx.Tx, order *domain.Order, items []domain.OrderItem) (*domain.Order, error) {
 	o := *order
+	if o.ID == "" {
+		o.ID = "o1"
+	}
 	o.Items = items
 	m.created = &o
 	return m.created, nil
 }
 
+func (m *orderRepoMock) GetOrderForUpdate(ctx context.Context, tx pgx.Tx, id string) (*domain.Order, error) {
+	if m.created == nil || m.created.ID != id {
+		return nil, nil
+	}
+	o := *m.created
+	return &o, nil
+}
+
+func (m *orderRepoMock) GetOrder(ctx context.Context, id string) (*domain.Order, error) {
+	return m.GetOrderForUpdate(ctx, nil, id)
+}
+
+func (m *orderRepoMock) UpdateOrderStatus(ctx context.Context, tx pgx.Tx, id string, status domain.OrderStatus) (*domain.Order, error) {
+	m.created.Status = status
+	o := *m.created
+	o.Items = nil
+	return &o, nil
+}
+
+type outboxMock struct {
+	events []outbox.Event
+}
+
+func (m *outboxMock) AddEvents(ctx context.Context, tx pgx.Tx, events ...outbox.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
 type orderUserRepoMock struct {
 	user *domain.User
 }
@@ -148,3 +218,141 @@ func TestOrderCreateSuccess(t *testing.T) {
 	require.Equal(t, 2, order.Items[0].Quantity)
 	require.True(t, order.Items[0].Price.Equal(decimal.NewFromInt(15)))
 }
+
+func TestOrderCreateLocksProductsInIDOrder(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 5, Price: decimal.NewFromInt(1)},
+			"p2": {ID: "p2", Quantity: 5, Price: decimal.NewFromInt(1)},
+			"p3": {ID: "p3", Quantity: 5, Price: decimal.NewFromInt(1)},
+		},
+	}
+	svc := NewOrderService(products, &orderRepoMock{}, orderUserRepoMock{user: &domain.User{ID: "u1"}}, txManagerMock{tx: txMock{}},
+		WithLockMode(domain.LockNoWait))
+
+	_, err := svc.Create(context.Background(), "u1", []OrderItemInput{
+		{ProductID: "p3", Quantity: 1},
+		{ProductID: "p1", Quantity: 1},
+		{ProductID: "p2", Quantity: 1},
+		{ProductID: "p1", Quantity: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"p1", "p2", "p3"}, products.lockedIDs)
+	require.Equal(t, domain.LockNoWait, products.lockMode)
+}
+
+func TestOrderCreateMetrics(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 3, Price: decimal.NewFromInt(5)},
+		},
+	}
+	svc := NewOrderService(products, &orderRepoMock{}, orderUserRepoMock{user: &domain.User{ID: "u1"}}, txManagerMock{tx: txMock{}})
+
+	created := testutil.ToFloat64(ordersCreatedTotal)
+	sold := testutil.ToFloat64(unitsSoldTotal)
+	conflicts := testutil.ToFloat64(insufficientStockTotal)
+
+	_, err := svc.Create(context.Background(), "u1", []OrderItemInput{{ProductID: "p1", Quantity: 2}})
+	require.NoError(t, err)
+	_, err = svc.Create(context.Background(), "u1", []OrderItemInput{{ProductID: "p1", Quantity: 2}})
+	require.Error(t, err)
+
+	require.Equal(t, created+1, testutil.ToFloat64(ordersCreatedTotal))
+	require.Equal(t, sold+2, testutil.ToFloat64(unitsSoldTotal))
+	require.Equal(t, conflicts+1, testutil.ToFloat64(insufficientStockTotal))
+}
+
+func TestOrderCreateRecordsEvent(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 5, Price: decimal.NewFromInt(15)},
+		},
+	}
+	events := &outboxMock{}
+	svc := NewOrderService(products, &orderRepoMock{}, orderUserRepoMock{user: &domain.User{ID: "u1"}}, txManagerMock{tx: txMock{}},
+		WithOrderEvents(events))
+
+	order, err := svc.Create(context.Background(), "u1", []OrderItemInput{{ProductID: "p1", Quantity: 2}})
+	require.NoError(t, err)
+	require.Len(t, events.events, 1)
+	e := events.events[0]
+	require.Equal(t, domain.EventOrderCreated, e.Type)
+	require.Equal(t, order.ID, e.AggregateID)
+	require.JSONEq(t, `{"order_id":"o1","user_id":"u1","status":"created","fulfillment_status":"fulfilled","total_price":"30.00",
+		"items":[{"product_id":"p1","quantity":2,"price":"15.00"}],"created_at":"0001-01-01T00:00:00Z"}`, string(e.Payload))
+}
+
+func TestOrderCancelRestocksAndRecordsEvent(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 5, Price: decimal.NewFromInt(1)},
+			"p2": {ID: "p2", Quantity: 5, Price: decimal.NewFromInt(1)},
+		},
+	}
+	orders := &orderRepoMock{}
+	events := &outboxMock{}
+	svc := NewOrderService(products, orders, orderUserRepoMock{user: &domain.User{ID: "u1"}}, txManagerMock{tx: txMock{}},
+		WithOrderEvents(events))
+
+	order, err := svc.Create(context.Background(), "u1", []OrderItemInput{
+		{ProductID: "p2", Quantity: 1},
+		{ProductID: "p1", Quantity: 2},
+		{ProductID: "p2", Quantity: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, products.items["p1"].Quantity)
+	require.Equal(t, 3, products.items["p2"].Quantity)
+
+	_, err = svc.UpdateStatus(context.Background(), order.ID, domain.OrderDelivered)
+	require.EqualError(t, err, "invalid status transition")
+
+	cancelled, err := svc.UpdateStatus(context.Background(), order.ID, domain.OrderCancelled)
+	require.NoError(t, err)
+	require.Equal(t, domain.OrderCancelled, cancelled.Status)
+	require.Len(t, cancelled.Items, 3)
+	require.Equal(t, 5, products.items["p1"].Quantity)
+	require.Equal(t, 5, products.items["p2"].Quantity)
+	require.Equal(t, []string{"p1", "p2"}, products.lockedIDs)
+
+	require.Len(t, events.events, 2)
+	require.Equal(t, domain.EventOrderStatusChanged, events.events[1].Type)
+	require.JSONEq(t, `{"order_id":"o1","from":"created","to":"cancelled","changed_at":"0001-01-01T00:00:00Z"}`, string(events.events[1].Payload))
+
+	_, err = svc.UpdateStatus(context.Background(), order.ID, domain.OrderPaid)
+	require.EqualError(t, err, "invalid status transition")
+	_, err = svc.UpdateStatus(context.Background(), "missing", domain.OrderPaid)
+	require.EqualError(t, err, "order not found")
+}
+
+func TestOrderCreatePartialAcceptsStockOnHand(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 3, Price: decimal.NewFromInt(10)},
+			"p2": {ID: "p2", Quantity: 0, Price: decimal.NewFromInt(7), AllowBackorder: true},
+		},
+	}
+	svc := NewOrderService(products, &orderRepoMock{}, orderUserRepoMock{user: &domain.User{ID: "u1"}}, txManagerMock{tx: txMock{}},
+		WithOrderBackorders(&backorderRepoMock{}))
+
+	order, err := svc.Create(context.Background(), "u1", []OrderItemInput{
+		{ProductID: "p1", Quantity: 2},
+		{ProductID: "p2", Quantity: 4},
+		{ProductID: "p1", Quantity: 2},
+	}, WithFulfillmentPolicy(domain.FulfillPartial))
+	require.NoError(t, err)
+	require.Equal(t, []domain.OrderLine{
+		{ProductID: "p1", Requested: 2, Accepted: 2},
+		{ProductID: "p2", Requested: 4, Accepted: 0},
+		{ProductID: "p1", Requested: 2, Accepted: 1},
+	}, order.Lines)
+	require.Len(t, order.Items, 2)
+	require.Equal(t, 1, order.Items[1].Quantity)
+	require.Zero(t, order.Items[1].Backordered)
+	require.Equal(t, decimal.NewFromInt(30), order.TotalPrice)
+	require.Equal(t, 0, products.items["p1"].Quantity)
+
+	_, err = svc.Create(context.Background(), "u1", []OrderItemInput{{ProductID: "p1", Quantity: 1}}, WithFulfillmentPolicy(domain.FulfillPartial))
+	require.EqualError(t, err, "insufficient stock")
+	require.ErrorIs(t, err, domain.ErrInsufficientStock)
+}
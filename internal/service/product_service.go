@@ -2,29 +2,83 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 
 	"stockpilot/internal/domain"
 	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/tracing"
 )
 
 type CreateProductInput struct {
-	Description string
-	Tags        []string
-	Quantity    int
-	Price       decimal.Decimal
+	Description    string
+	Tags           []string
+	Quantity       int
+	Price          decimal.Decimal
+	ReorderPoint   int
+	SafetyStock    int
+	AllowBackorder bool
 }
 
 type ProductService struct {
-	products domain.ProductRepository
+	products   domain.ProductRepository
+	tx         domain.TxManager
+	outbox     domain.OutboxRepository
+	backorders domain.BackorderRepository
+	stock      []domain.StockPublisher
+	lowStock   domain.LowStockChecker
 }
 
-func NewProductService(products domain.ProductRepository) *ProductService {
-	return &ProductService{products: products}
+type ProductServiceOption func(s *ProductService)
+
+// WithProductEvents records stock.adjusted events in the outbox, in the same
+// transaction as the adjustment.
+func WithProductEvents(outbox domain.OutboxRepository) ProductServiceOption {
+	return func(s *ProductService) {
+		s.outbox = outbox
+	}
+}
+
+// WithProductBackorders makes positive stock adjustments fill open
+// backorders first.
+func WithProductBackorders(backorders domain.BackorderRepository) ProductServiceOption {
+	return func(s *ProductService) {
+		s.backorders = backorders
+	}
+}
+
+// WithProductStockPublisher publishes the level of created and adjusted
+// products after the change commits. It can be given more than once.
+func WithProductStockPublisher(p domain.StockPublisher) ProductServiceOption {
+	return func(s *ProductService) {
+		s.stock = append(s.stock, p)
+	}
+}
+
+// WithProductLowStockChecks re-evaluates the low-stock alert of a product
+// when its reorder policy changes.
+func WithProductLowStockChecks(c domain.LowStockChecker) ProductServiceOption {
+	return func(s *ProductService) {
+		s.lowStock = c
+	}
+}
+
+func NewProductService(products domain.ProductRepository, tx domain.TxManager, opts ...ProductServiceOption) *ProductService {
+	s := &ProductService{products: products, tx: tx}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *ProductService) Create(ctx context.Context, input CreateProductInput) (*domain.Product, error) {
+	ctx = tracing.StartSpan(ctx, "ProductService.Create")
+	defer tracing.EndSpan(ctx)
+
 	if input.Description == "" {
 		return nil, errors.New("description is required")
 	}
@@ -34,22 +88,175 @@ func (s *ProductService) Create(ctx context.Context, input CreateProductInput) (
 	if input.Price.LessThanOrEqual(decimal.Zero) {
 		return nil, errors.New("price must be positive")
 	}
+	if err := validateReorderPolicy(input.ReorderPoint, input.SafetyStock); err != nil {
+		return nil, err
+	}
 	product := domain.Product{
-		Description: input.Description,
-		Tags:        input.Tags,
-		Quantity:    input.Quantity,
-		Price:       input.Price,
+		Description:    input.Description,
+		Tags:           input.Tags,
+		Quantity:       input.Quantity,
+		Price:          input.Price,
+		ReorderPoint:   input.ReorderPoint,
+		SafetyStock:    input.SafetyStock,
+		AllowBackorder: input.AllowBackorder,
 	}
 	created, err := s.products.CreateProduct(ctx, &product)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		return nil, err
 	}
+	// The product is stored already; other instances only miss its first
+	// level.
+	err = publishStock(ctx, nil, s.stock, domain.StockLevel{ProductID: created.ID, Quantity: created.Quantity, UpdatedAt: created.UpdatedAt})
+	if err != nil {
+		stockLog.WarnCtx(ctx, "notify stock level", zap.Error(err))
+	}
 	return created, nil
 }
 
+// SetReorderPolicy changes the low-stock thresholds of a product and has its
+// alert checked again, so a product already below the new reorder point
+// raises one. The stock level did not change and is not published.
+func (s *ProductService) SetReorderPolicy(ctx context.Context, id string, reorderPoint, safetyStock int) (*domain.Product, error) {
+	ctx = tracing.StartSpan(ctx, "ProductService.SetReorderPolicy", attribute.String("product.id", id))
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	if err := validateReorderPolicy(reorderPoint, safetyStock); err != nil {
+		return nil, err
+	}
+	updated, err := s.products.SetReorderPolicy(ctx, id, reorderPoint, safetyStock)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	if s.lowStock != nil {
+		s.lowStock.ScheduleCheck(updated.ID)
+	}
+	return updated, nil
+}
+
+// SetBackorderPolicy sets whether orders may take more of the product than
+// is in stock. Existing backorders stay open either way.
+func (s *ProductService) SetBackorderPolicy(ctx context.Context, id string, allow bool) (*domain.Product, error) {
+	ctx = tracing.StartSpan(ctx, "ProductService.SetBackorderPolicy",
+		attribute.String("product.id", id),
+		attribute.Bool("product.allow_backorder", allow),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	updated, err := s.products.SetBackorderPolicy(ctx, id, allow)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func validateReorderPolicy(reorderPoint, safetyStock int) error {
+	if reorderPoint < 0 {
+		return errors.New("reorder point cannot be negative")
+	}
+	if safetyStock < 0 {
+		return errors.New("safety stock cannot be negative")
+	}
+	if safetyStock > reorderPoint {
+		return errors.New("safety stock cannot exceed reorder point")
+	}
+	return nil
+}
+
 func (s *ProductService) GetByID(ctx context.Context, id string) (*domain.Product, error) {
+	ctx = tracing.StartSpan(ctx, "ProductService.GetByID", attribute.String("product.id", id))
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	product, err := s.products.GetProductByID(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// StockLevels returns the current level of every product, for stream
+// snapshots.
+func (s *ProductService) StockLevels(ctx context.Context) ([]domain.StockLevel, error) {
+	ctx = tracing.StartSpan(ctx, "ProductService.StockLevels")
+	defer tracing.EndSpan(ctx)
+
+	levels, err := s.products.ListStockLevels(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return levels, nil
+}
+
+// AdjustStock adds delta, which may be negative, to the product quantity.
+// Stock coming in fills open backorders before it goes on the shelf.
+func (s *ProductService) AdjustStock(ctx context.Context, id string, delta int, reason string) (*domain.Product, error) {
+	ctx = tracing.StartSpan(ctx, "ProductService.AdjustStock",
+		attribute.String("product.id", id),
+		attribute.Int("stock.delta", delta),
+	)
+	defer tracing.EndSpan(ctx)
+
 	if id == "" {
 		return nil, errors.New("id is required")
 	}
-	return s.products.GetProductByID(ctx, id)
+	if delta == 0 {
+		return nil, errors.New("delta cannot be zero")
+	}
+	var adjusted domain.Product
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		products, err := s.products.GetByIDsForUpdate(ctx, tx, []string{id}, domain.LockWait)
+		if err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			return errors.New("product not found")
+		}
+		if products[0].Quantity+delta < 0 {
+			return domain.ErrInsufficientStock
+		}
+		fills := newBackorderFills(s.backorders, s.outbox, tx)
+		shelved, err := fills.fill(ctx, id, delta)
+		if err != nil {
+			return err
+		}
+		if err := s.products.UpdateQuantity(ctx, tx, id, shelved); err != nil {
+			return err
+		}
+		if err := fills.recordFulfilled(ctx); err != nil {
+			return err
+		}
+		adjusted = products[0]
+		adjusted.Quantity += shelved
+		adjusted.UpdatedAt = time.Now().UTC()
+		err = publishStock(ctx, tx, s.stock, domain.StockLevel{ProductID: id, Quantity: adjusted.Quantity, UpdatedAt: adjusted.UpdatedAt})
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, s.outbox, tx, domain.EventStockAdjusted, domain.AggregateProduct, id, domain.StockAdjustedEvent{
+			ProductID:   id,
+			Delta:       delta,
+			Backordered: delta - shelved,
+			Quantity:    adjusted.Quantity,
+			Reason:      reason,
+			AdjustedAt:  adjusted.UpdatedAt,
+		})
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return &adjusted, nil
 }
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/tracing"
+)
+
+type SupplierInput struct {
+	Name         string
+	ContactName  string
+	Email        string
+	Phone        string
+	LeadTimeDays int
+}
+
+type PurchaseOrderInput struct {
+	SupplierID   string
+	ExpectedDate *time.Time
+	Lines        []PurchaseOrderLineInput
+}
+
+type PurchaseOrderLineInput struct {
+	ProductID string
+	Quantity  int
+}
+
+type ReceiveInput struct {
+	ReceivedBy string
+	Lines      []PurchaseOrderLineInput
+}
+
+type PurchasingService struct {
+	purchasing domain.PurchasingRepository
+	products   domain.ProductRepository
+	tx         domain.TxManager
+	outbox     domain.OutboxRepository
+	backorders domain.BackorderRepository
+	stock      []domain.StockPublisher
+}
+
+type PurchasingServiceOption func(s *PurchasingService)
+
+// WithPurchasingEvents records purchase_order.received events in the outbox,
+// in the same transaction as the receipt.
+func WithPurchasingEvents(outbox domain.OutboxRepository) PurchasingServiceOption {
+	return func(s *PurchasingService) {
+		s.outbox = outbox
+	}
+}
+
+// WithPurchasingBackorders makes received stock fill open backorders
+// before it goes on the shelf.
+func WithPurchasingBackorders(backorders domain.BackorderRepository) PurchasingServiceOption {
+	return func(s *PurchasingService) {
+		s.backorders = backorders
+	}
+}
+
+// WithPurchasingStockPublisher publishes the levels of received products
+// after the receipt commits. It can be given more than once.
+func WithPurchasingStockPublisher(p domain.StockPublisher) PurchasingServiceOption {
+	return func(s *PurchasingService) {
+		s.stock = append(s.stock, p)
+	}
+}
+
+func NewPurchasingService(purchasing domain.PurchasingRepository, products domain.ProductRepository, tx domain.TxManager, opts ...PurchasingServiceOption) *PurchasingService {
+	s := &PurchasingService{purchasing: purchasing, products: products, tx: tx}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *PurchasingService) CreateSupplier(ctx context.Context, input SupplierInput) (*domain.Supplier, error) {
+	ctx = tracing.StartSpan(ctx, "PurchasingService.CreateSupplier")
+	defer tracing.EndSpan(ctx)
+
+	if err := validateSupplier(input); err != nil {
+		return nil, err
+	}
+	created, err := s.purchasing.CreateSupplier(ctx, &domain.Supplier{
+		Name:         input.Name,
+		ContactName:  input.ContactName,
+		Email:        input.Email,
+		Phone:        input.Phone,
+		LeadTimeDays: input.LeadTimeDays,
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *PurchasingService) UpdateSupplier(ctx context.Context, id string, input SupplierInput) (*domain.Supplier, error) {
+	ctx = tracing.StartSpan(ctx, "PurchasingService.UpdateSupplier", attribute.String("supplier.id", id))
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	if err := validateSupplier(input); err != nil {
+		return nil, err
+	}
+	updated, err := s.purchasing.UpdateSupplier(ctx, &domain.Supplier{
+		ID:           id,
+		Name:         input.Name,
+		ContactName:  input.ContactName,
+		Email:        input.Email,
+		Phone:        input.Phone,
+		LeadTimeDays: input.LeadTimeDays,
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *PurchasingService) GetSupplier(ctx context.Context, id string) (*domain.Supplier, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	supplier, err := s.purchasing.GetSupplier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier == nil {
+		return nil, errors.New("supplier not found")
+	}
+	return supplier, nil
+}
+
+func (s *PurchasingService) ListSuppliers(ctx context.Context) ([]domain.Supplier, error) {
+	return s.purchasing.ListSuppliers(ctx)
+}
+
+func validateSupplier(input SupplierInput) error {
+	if input.Name == "" {
+		return errors.New("supplier name is required")
+	}
+	if input.LeadTimeDays < 0 {
+		return errors.New("lead time cannot be negative")
+	}
+	return nil
+}
+
+// CreatePurchaseOrder creates a draft purchase order. Each product may
+// appear on one line only.
+func (s *PurchasingService) CreatePurchaseOrder(ctx context.Context, input PurchaseOrderInput) (*domain.PurchaseOrder, error) {
+	ctx = tracing.StartSpan(ctx, "PurchasingService.CreatePurchaseOrder",
+		attribute.String("supplier.id", input.SupplierID),
+		attribute.Int("purchase_order.lines", len(input.Lines)),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if input.SupplierID == "" {
+		return nil, errors.New("supplier id is required")
+	}
+	if len(input.Lines) == 0 {
+		return nil, errors.New("purchase order lines are required")
+	}
+	seen := make(map[string]struct{}, len(input.Lines))
+	lines := make([]domain.PurchaseOrderLine, 0, len(input.Lines))
+	for _, l := range input.Lines {
+		if l.ProductID == "" {
+			return nil, errors.New("product id is required")
+		}
+		if l.Quantity <= 0 {
+			return nil, errors.New("quantity must be positive")
+		}
+		if _, ok := seen[l.ProductID]; ok {
+			return nil, errors.New("duplicate product in purchase order")
+		}
+		seen[l.ProductID] = struct{}{}
+		lines = append(lines, domain.PurchaseOrderLine{ProductID: l.ProductID, Quantity: l.Quantity})
+	}
+	var created *domain.PurchaseOrder
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		var err error
+		created, err = s.purchasing.CreatePurchaseOrder(ctx, tx, &domain.PurchaseOrder{
+			SupplierID:   input.SupplierID,
+			Status:       domain.PurchaseOrderDraft,
+			ExpectedDate: input.ExpectedDate,
+			Lines:        lines,
+		})
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	tracing.SetAttributes(ctx, attribute.String("purchase_order.id", created.ID))
+	return created, nil
+}
+
+func (s *PurchasingService) GetPurchaseOrder(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	po, err := s.purchasing.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if po == nil {
+		return nil, errors.New("purchase order not found")
+	}
+	return po, nil
+}
+
+func (s *PurchasingService) ListPurchaseOrders(ctx context.Context, status domain.PurchaseOrderStatus, limit int) ([]domain.PurchaseOrder, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.purchasing.ListPurchaseOrders(ctx, status, limit)
+}
+
+// UpdateStatus sends a draft purchase order to the supplier. The received
+// statuses are set by Receive.
+func (s *PurchasingService) UpdateStatus(ctx context.Context, id string, status domain.PurchaseOrderStatus) (*domain.PurchaseOrder, error) {
+	ctx = tracing.StartSpan(ctx, "PurchasingService.UpdateStatus",
+		attribute.String("purchase_order.id", id),
+		attribute.String("purchase_order.status", string(status)),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	if status != domain.PurchaseOrderSent {
+		return nil, errors.New("invalid status transition")
+	}
+	var updated *domain.PurchaseOrder
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		po, err := s.purchasing.GetPurchaseOrderForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if po == nil {
+			return errors.New("purchase order not found")
+		}
+		if !po.Status.CanTransition(status) {
+			return errors.New("invalid status transition")
+		}
+		updated, err = s.purchasing.UpdatePurchaseOrderStatus(ctx, tx, id, status)
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Receive books goods delivered against a sent purchase order into stock.
+// Deliveries may be partial; the order is received once every line is.
+func (s *PurchasingService) Receive(ctx context.Context, id string, input ReceiveInput) (*domain.PurchaseReceipt, *domain.PurchaseOrder, error) {
+	ctx = tracing.StartSpan(ctx, "PurchasingService.Receive",
+		attribute.String("purchase_order.id", id),
+		attribute.Int("receipt.lines", len(input.Lines)),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, nil, errors.New("id is required")
+	}
+	if input.ReceivedBy == "" {
+		return nil, nil, errors.New("received_by is required")
+	}
+	if len(input.Lines) == 0 {
+		return nil, nil, errors.New("receipt lines are required")
+	}
+	received := make(map[string]int, len(input.Lines))
+	ids := make([]string, 0, len(input.Lines))
+	for _, l := range input.Lines {
+		if l.ProductID == "" {
+			return nil, nil, errors.New("product id is required")
+		}
+		if l.Quantity <= 0 {
+			return nil, nil, errors.New("quantity must be positive")
+		}
+		if _, ok := received[l.ProductID]; !ok {
+			ids = append(ids, l.ProductID)
+		}
+		received[l.ProductID] += l.Quantity
+	}
+	// Products are locked in id order, like checkout and cancellation.
+	sort.Strings(ids)
+
+	var (
+		receipt *domain.PurchaseReceipt
+		updated *domain.PurchaseOrder
+	)
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		po, err := s.purchasing.GetPurchaseOrderForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if po == nil {
+			return errors.New("purchase order not found")
+		}
+		if !po.Status.Receivable() {
+			return errors.New("purchase order is not open for receiving")
+		}
+		lines := make(map[string]*domain.PurchaseOrderLine, len(po.Lines))
+		for i := range po.Lines {
+			lines[po.Lines[i].ProductID] = &po.Lines[i]
+		}
+		receiptLines := make([]domain.PurchaseReceiptLine, 0, len(ids))
+		for _, productID := range ids {
+			line, ok := lines[productID]
+			if !ok {
+				return errors.New("product is not on the purchase order")
+			}
+			if received[productID] > line.Outstanding() {
+				return errors.New("received quantity exceeds ordered quantity")
+			}
+			line.ReceivedQuantity += received[productID]
+			receiptLines = append(receiptLines, domain.PurchaseReceiptLine{LineID: line.ID, ProductID: productID, Quantity: received[productID]})
+		}
+
+		products, err := s.products.GetByIDsForUpdate(ctx, tx, ids, domain.LockWait)
+		if err != nil {
+			return err
+		}
+		changes := make([]domain.StockChange, 0, len(ids))
+		fills := newBackorderFills(s.backorders, s.outbox, tx)
+		for _, productID := range ids {
+			shelved, err := fills.fill(ctx, productID, received[productID])
+			if err != nil {
+				return err
+			}
+			if shelved == 0 {
+				continue
+			}
+			if err := s.products.UpdateQuantity(ctx, tx, productID, shelved); err != nil {
+				return err
+			}
+			changes = append(changes, domain.StockChange{ProductID: productID, Delta: shelved})
+		}
+		if err := fills.recordFulfilled(ctx); err != nil {
+			return err
+		}
+		if err := publishStock(ctx, tx, s.stock, stockLevels(products, changes)...); err != nil {
+			return err
+		}
+
+		receipt, err = s.purchasing.RecordPurchaseReceipt(ctx, tx, &domain.PurchaseReceipt{
+			PurchaseOrderID: id,
+			ReceivedBy:      input.ReceivedBy,
+			Lines:           receiptLines,
+		})
+		if err != nil {
+			return err
+		}
+		status := domain.PurchaseOrderPartiallyReceived
+		if po.FullyReceived() {
+			status = domain.PurchaseOrderReceived
+		}
+		updated, err = s.purchasing.UpdatePurchaseOrderStatus(ctx, tx, id, status)
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, s.outbox, tx, domain.EventPurchaseReceived, domain.AggregatePurchaseOrder, id, purchaseReceivedEvent(receipt, status))
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, nil, err
+	}
+	tracing.SetAttributes(ctx, attribute.String("purchase_order.status", string(updated.Status)))
+	return receipt, updated, nil
+}
+
+func (s *PurchasingService) ListReceipts(ctx context.Context, purchaseOrderID string) ([]domain.PurchaseReceipt, error) {
+	if _, err := s.GetPurchaseOrder(ctx, purchaseOrderID); err != nil {
+		return nil, err
+	}
+	return s.purchasing.ListPurchaseReceipts(ctx, purchaseOrderID)
+}
+
+func purchaseReceivedEvent(r *domain.PurchaseReceipt, status domain.PurchaseOrderStatus) domain.PurchaseReceivedEvent {
+	lines := make([]domain.PurchaseReceivedEventLine, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, domain.PurchaseReceivedEventLine{ProductID: l.ProductID, Quantity: l.Quantity})
+	}
+	return domain.PurchaseReceivedEvent{
+		PurchaseOrderID: r.PurchaseOrderID,
+		ReceiptID:       r.ID,
+		Status:          status,
+		ReceivedBy:      r.ReceivedBy,
+		Lines:           lines,
+		ReceivedAt:      r.ReceivedAt,
+	}
+}
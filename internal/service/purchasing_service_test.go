@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+)
+
+type purchasingRepoMock struct {
+	po       domain.PurchaseOrder
+	receipts []domain.PurchaseReceipt
+}
+
+func (m *purchasingRepoMock) CreateSupplier(ctx context.Context, s *domain.Supplier) (*domain.Supplier, error) {
+	return s, nil
+}
+
+func (m *purchasingRepoMock) GetSupplier(ctx context.Context, id string) (*domain.Supplier, error) {
+	return &domain.Supplier{ID: id}, nil
+}
+
+func (m *purchasingRepoMock) ListSuppliers(ctx context.Context) ([]domain.Supplier, error) {
+	return nil, nil
+}
+
+func (m *purchasingRepoMock) UpdateSupplier(ctx context.Context, s *domain.Supplier) (*domain.Supplier, error) {
+	return s, nil
+}
+
+func (m *purchasingRepoMock) CreatePurchaseOrder(ctx context.Context, tx pgx.Tx, po *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	m.po = *po
+	return po, nil
+}
+
+func (m *purchasingRepoMock) GetPurchaseOrder(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	return m.GetPurchaseOrderForUpdate(ctx, nil, id)
+}
+
+func (m *purchasingRepoMock) ListPurchaseOrders(ctx context.Context, status domain.PurchaseOrderStatus, limit int) ([]domain.PurchaseOrder, error) {
+	return nil, nil
+}
+
+func (m *purchasingRepoMock) GetPurchaseOrderForUpdate(ctx context.Context, tx pgx.Tx, id string) (*domain.PurchaseOrder, error) {
+	if m.po.ID != id {
+		return nil, nil
+	}
+	po := m.po
+	po.Lines = append([]domain.PurchaseOrderLine(nil), m.po.Lines...)
+	return &po, nil
+}
+
+func (m *purchasingRepoMock) UpdatePurchaseOrderStatus(ctx context.Context, tx pgx.Tx, id string, status domain.PurchaseOrderStatus) (*domain.PurchaseOrder, error) {
+	m.po.Status = status
+	return m.GetPurchaseOrderForUpdate(ctx, tx, id)
+}
+
+func (m *purchasingRepoMock) RecordPurchaseReceipt(ctx context.Context, tx pgx.Tx, receipt *domain.PurchaseReceipt) (*domain.PurchaseReceipt, error) {
+	for _, rl := range receipt.Lines {
+		for i := range m.po.Lines {
+			if m.po.Lines[i].ID == rl.LineID {
+				m.po.Lines[i].ReceivedQuantity += rl.Quantity
+			}
+		}
+	}
+	m.receipts = append(m.receipts, *receipt)
+	return receipt, nil
+}
+
+func (m *purchasingRepoMock) ListPurchaseReceipts(ctx context.Context, purchaseOrderID string) ([]domain.PurchaseReceipt, error) {
+	return m.receipts, nil
+}
+
+func TestPurchasingReceiveAddsStockAndRecordsEvent(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			"p1": {ID: "p1", Quantity: 1},
+			"p2": {ID: "p2"},
+		},
+	}
+	purchasing := &purchasingRepoMock{po: domain.PurchaseOrder{
+		ID:     "po1",
+		Status: domain.PurchaseOrderSent,
+		Lines: []domain.PurchaseOrderLine{
+			{ID: "l1", ProductID: "p1", Quantity: 5},
+			{ID: "l2", ProductID: "p2", Quantity: 2},
+		},
+	}}
+	events := &outboxMock{}
+	svc := NewPurchasingService(purchasing, products, txManagerMock{tx: txMock{}}, WithPurchasingEvents(events))
+
+	_, po, err := svc.Receive(context.Background(), "po1", ReceiveInput{
+		ReceivedBy: "dock",
+		Lines: []PurchaseOrderLineInput{
+			{ProductID: "p2", Quantity: 1},
+			{ProductID: "p1", Quantity: 2},
+			{ProductID: "p2", Quantity: 1},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.PurchaseOrderPartiallyReceived, po.Status)
+	require.Equal(t, 3, products.items["p1"].Quantity)
+	require.Equal(t, 2, products.items["p2"].Quantity)
+	require.Equal(t, []string{"p1", "p2"}, products.lockedIDs)
+	require.Len(t, purchasing.receipts[0].Lines, 2)
+
+	_, _, err = svc.Receive(context.Background(), "po1", ReceiveInput{ReceivedBy: "dock", Lines: []PurchaseOrderLineInput{{ProductID: "p1", Quantity: 4}}})
+	require.EqualError(t, err, "received quantity exceeds ordered quantity")
+	require.Equal(t, 3, products.items["p1"].Quantity)
+
+	_, po, err = svc.Receive(context.Background(), "po1", ReceiveInput{ReceivedBy: "dock", Lines: []PurchaseOrderLineInput{{ProductID: "p1", Quantity: 3}}})
+	require.NoError(t, err)
+	require.Equal(t, domain.PurchaseOrderReceived, po.Status)
+
+	_, _, err = svc.Receive(context.Background(), "po1", ReceiveInput{ReceivedBy: "dock", Lines: []PurchaseOrderLineInput{{ProductID: "p1", Quantity: 1}}})
+	require.EqualError(t, err, "purchase order is not open for receiving")
+
+	require.Len(t, events.events, 2)
+	require.Equal(t, domain.EventPurchaseReceived, events.events[0].Type)
+	require.Equal(t, "po1", events.events[0].AggregateID)
+}
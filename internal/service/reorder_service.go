@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/tracing"
+)
+
+const maxReorderWindowDays = 365
+
+// ReorderService suggests what to reorder from the sales velocity over a
+// moving window, the stock on hand and on order, and supplier lead times.
+type ReorderService struct {
+	reorder domain.ReorderRepository
+	tx      domain.TxManager
+	params  domain.ReorderParams
+	now     func() time.Time
+}
+
+type ReorderServiceOption func(s *ReorderService)
+
+func WithReorderWindow(days int) ReorderServiceOption {
+	return func(s *ReorderService) {
+		if days > 0 && days <= maxReorderWindowDays {
+			s.params.WindowDays = days
+		}
+	}
+}
+
+// WithReorderLeadTime sets the lead time of products that were never
+// ordered from a supplier.
+func WithReorderLeadTime(days int) ReorderServiceOption {
+	return func(s *ReorderService) {
+		if days >= 0 {
+			s.params.DefaultLeadTimeDays = days
+		}
+	}
+}
+
+func WithReorderCover(days int) ReorderServiceOption {
+	return func(s *ReorderService) {
+		if days >= 0 {
+			s.params.CoverDays = days
+		}
+	}
+}
+
+func NewReorderService(reorder domain.ReorderRepository, tx domain.TxManager, opts ...ReorderServiceOption) *ReorderService {
+	s := &ReorderService{
+		reorder: reorder,
+		tx:      tx,
+		params:  domain.ReorderParams{WindowDays: 28, DefaultLeadTimeDays: 7, CoverDays: 7},
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Suggestions returns the products to reorder within the cover period, the
+// most urgent first. windowDays overrides the configured window when set.
+func (s *ReorderService) Suggestions(ctx context.Context, windowDays int) ([]domain.ReorderSuggestion, error) {
+	ctx = tracing.StartSpan(ctx, "ReorderService.Suggestions", attribute.Int("reorder.window_days", windowDays))
+	defer tracing.EndSpan(ctx)
+
+	params := s.params
+	if windowDays != 0 {
+		if windowDays < 0 || windowDays > maxReorderWindowDays {
+			return nil, errors.New("window must be between 1 and 365 days")
+		}
+		params.WindowDays = windowDays
+	}
+	now := s.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Read-only, so the report also runs in maintenance mode, and from a
+	// replica when there is one.
+	var candidates []domain.ReorderCandidate
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		var err error
+		candidates, err = s.reorder.ListReorderCandidates(ctx, tx, now.AddDate(0, 0, -params.WindowDays))
+		return err
+	}, domain.ReadOnly(), domain.OnReplica())
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	result := []domain.ReorderSuggestion{}
+	for _, c := range candidates {
+		if suggestion, ok := c.Suggest(params, today); ok {
+			result = append(result, suggestion)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].ReorderBy.Equal(result[j].ReorderBy) {
+			return result[i].ReorderBy.Before(result[j].ReorderBy)
+		}
+		return result[i].ProductID < result[j].ProductID
+	})
+	tracing.SetAttributes(ctx, attribute.Int("reorder.suggestions", len(result)))
+	return result, nil
+}
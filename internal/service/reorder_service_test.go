@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+)
+
+type reorderRepoMock struct {
+	candidates []domain.ReorderCandidate
+	since      time.Time
+}
+
+func (m *reorderRepoMock) ListReorderCandidates(ctx context.Context, tx pgx.Tx, since time.Time) ([]domain.ReorderCandidate, error) {
+	m.since = since
+	return m.candidates, nil
+}
+
+func TestReorderSuggestionsMostUrgentFirst(t *testing.T) {
+	leadTime := 2
+	repo := &reorderRepoMock{candidates: []domain.ReorderCandidate{
+		// 1 a day, reorder level 7: due in 3 days.
+		{ProductID: "a", Quantity: 10, UnitsSold: 10},
+		// 1 a day over a 2 day lead time plus safety stock 3: due now.
+		{ProductID: "b", Quantity: 4, SafetyStock: 3, UnitsSold: 10, SupplierID: "s1", LeadTimeDays: &leadTime},
+		// Lasts beyond the cover period.
+		{ProductID: "c", Quantity: 40, UnitsSold: 10},
+		// Nothing sold and no reorder point.
+		{ProductID: "d"},
+	}}
+	now := time.Date(2030, 3, 4, 15, 0, 0, 0, time.UTC)
+	svc := NewReorderService(repo, txManagerMock{tx: txMock{}}, WithReorderWindow(10), WithReorderLeadTime(7), WithReorderCover(7))
+	svc.now = func() time.Time { return now }
+
+	suggestions, err := svc.Suggestions(context.Background(), 0)
+	require.NoError(t, err)
+	require.Equal(t, now.AddDate(0, 0, -10), repo.since)
+	require.Len(t, suggestions, 2)
+
+	require.Equal(t, "b", suggestions[0].ProductID)
+	require.Equal(t, 5, suggestions[0].ReorderLevel)
+	require.Equal(t, time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC), suggestions[0].ReorderBy)
+	require.Equal(t, 8, suggestions[0].SuggestedQuantity)
+
+	require.Equal(t, "a", suggestions[1].ProductID)
+	require.Equal(t, 7, suggestions[1].ReorderLevel)
+	require.Equal(t, time.Date(2030, 3, 7, 0, 0, 0, 0, time.UTC), suggestions[1].ReorderBy)
+	require.Equal(t, 7, suggestions[1].SuggestedQuantity)
+
+	_, err = svc.Suggestions(context.Background(), 400)
+	require.EqualError(t, err, "window must be between 1 and 365 days")
+}
+
+type txOptionsMock struct {
+	txManagerMock
+	options domain.TxOptions
+}
+
+func (m *txOptionsMock) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error, opts ...domain.TxOption) error {
+	m.options = domain.NewTxOptions(opts...)
+	return m.txManagerMock.WithTx(ctx, f)
+}
+
+func TestReorderSuggestionsReadOnly(t *testing.T) {
+	tx := &txOptionsMock{txManagerMock: txManagerMock{tx: txMock{}}}
+	svc := NewReorderService(&reorderRepoMock{}, tx)
+
+	_, err := svc.Suggestions(context.Background(), 0)
+	require.NoError(t, err)
+	require.True(t, tx.options.ReadOnly)
+	require.True(t, tx.options.Replica)
+}
+
+func TestReorderSuggestionsCoverOpenBackorders(t *testing.T) {
+	repo := &reorderRepoMock{candidates: []domain.ReorderCandidate{
+		// On order covers the reorder point, but not what customers wait for.
+		{ProductID: "a", ReorderPoint: 5, OnOrder: 8, Backordered: 6},
+		// On hand covers both.
+		{ProductID: "b", Quantity: 12, ReorderPoint: 5, Backordered: 6},
+	}}
+	svc := NewReorderService(repo, txManagerMock{tx: txMock{}}, WithReorderWindow(10), WithReorderLeadTime(7), WithReorderCover(7))
+
+	suggestions, err := svc.Suggestions(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	require.Equal(t, "a", suggestions[0].ProductID)
+	require.Equal(t, 3, suggestions[0].SuggestedQuantity)
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/tracing"
+)
+
+type ReturnInput struct {
+	Reason string
+	Lines  []ReturnLineInput
+}
+
+type ReturnLineInput struct {
+	OrderItemID string
+	Quantity    int
+}
+
+// ReturnService runs return authorizations (RMAs) for delivered orders:
+// authorized -> received -> restocked or written_off.
+type ReturnService struct {
+	returns    domain.ReturnRepository
+	orders     domain.OrderRepository
+	products   domain.ProductRepository
+	tx         domain.TxManager
+	outbox     domain.OutboxRepository
+	backorders domain.BackorderRepository
+	stock      []domain.StockPublisher
+}
+
+type ReturnServiceOption func(s *ReturnService)
+
+// WithReturnEvents records return.received events, and stock.adjusted
+// events for restocked returns, in the outbox.
+func WithReturnEvents(outbox domain.OutboxRepository) ReturnServiceOption {
+	return func(s *ReturnService) {
+		s.outbox = outbox
+	}
+}
+
+// WithReturnBackorders makes restocked goods fill open backorders before
+// they go on the shelf.
+func WithReturnBackorders(backorders domain.BackorderRepository) ReturnServiceOption {
+	return func(s *ReturnService) {
+		s.backorders = backorders
+	}
+}
+
+// WithReturnStockPublisher publishes the levels of restocked products after
+// the change commits. It can be given more than once.
+func WithReturnStockPublisher(p domain.StockPublisher) ReturnServiceOption {
+	return func(s *ReturnService) {
+		s.stock = append(s.stock, p)
+	}
+}
+
+func NewReturnService(returns domain.ReturnRepository, orders domain.OrderRepository, products domain.ProductRepository, tx domain.TxManager, opts ...ReturnServiceOption) *ReturnService {
+	s := &ReturnService{returns: returns, orders: orders, products: products, tx: tx}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create authorizes the return of items of a delivered order. Together with
+// the earlier returns of the order, no item may be returned more often than
+// it was ordered.
+func (s *ReturnService) Create(ctx context.Context, orderID string, input ReturnInput) (*domain.Return, error) {
+	ctx = tracing.StartSpan(ctx, "ReturnService.Create",
+		attribute.String("order.id", orderID),
+		attribute.Int("return.lines", len(input.Lines)),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if orderID == "" {
+		return nil, errors.New("id is required")
+	}
+	if input.Reason == "" {
+		return nil, errors.New("return reason is required")
+	}
+	if len(input.Lines) == 0 {
+		return nil, errors.New("return lines are required")
+	}
+	seen := make(map[string]struct{}, len(input.Lines))
+	for _, l := range input.Lines {
+		if l.OrderItemID == "" {
+			return nil, errors.New("order item id is required")
+		}
+		if l.Quantity <= 0 {
+			return nil, errors.New("quantity must be positive")
+		}
+		if _, ok := seen[l.OrderItemID]; ok {
+			return nil, errors.New("duplicate order item in return")
+		}
+		seen[l.OrderItemID] = struct{}{}
+	}
+
+	var created *domain.Return
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		// The order row lock serializes returns of the same order, so two
+		// of them cannot both pass the quantity check.
+		order, err := s.orders.GetOrderForUpdate(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return errors.New("order not found")
+		}
+		if order.Status != domain.OrderDelivered {
+			return errors.New("order is not delivered")
+		}
+		returned, err := s.returns.ReturnedQuantities(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		items := make(map[string]domain.OrderItem, len(order.Items))
+		for _, item := range order.Items {
+			items[item.ID] = item
+		}
+		lines := make([]domain.ReturnLine, 0, len(input.Lines))
+		for _, l := range input.Lines {
+			item, ok := items[l.OrderItemID]
+			if !ok {
+				return errors.New("order item is not on the order")
+			}
+			if returned[item.ID]+l.Quantity > item.Quantity {
+				return errors.New("return quantity exceeds ordered quantity")
+			}
+			lines = append(lines, domain.ReturnLine{
+				OrderItemID: item.ID,
+				ProductID:   item.ProductID,
+				Quantity:    l.Quantity,
+				Price:       item.Price,
+			})
+		}
+		created, err = s.returns.CreateReturn(ctx, tx, &domain.Return{
+			OrderID: orderID,
+			Reason:  input.Reason,
+			Status:  domain.ReturnAuthorized,
+			Lines:   lines,
+		})
+		return err
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	tracing.SetAttributes(ctx, attribute.String("return.id", created.ID))
+	return created, nil
+}
+
+func (s *ReturnService) Get(ctx context.Context, id string) (*domain.Return, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	ret, err := s.returns.GetReturn(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, errors.New("return not found")
+	}
+	return ret, nil
+}
+
+func (s *ReturnService) List(ctx context.Context, orderID string) ([]domain.Return, error) {
+	if orderID == "" {
+		return nil, errors.New("id is required")
+	}
+	order, err := s.orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	return s.returns.ListReturns(ctx, orderID)
+}
+
+// UpdateStatus moves a return along. Receiving it records the refund, priced
+// at what the items cost when they were ordered; restocking puts the goods
+// back into stock, while writing off leaves stock as it is.
+func (s *ReturnService) UpdateStatus(ctx context.Context, id string, status domain.ReturnStatus) (*domain.Return, error) {
+	ctx = tracing.StartSpan(ctx, "ReturnService.UpdateStatus",
+		attribute.String("return.id", id),
+		attribute.String("return.status", string(status)),
+	)
+	defer tracing.EndSpan(ctx)
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	var updated *domain.Return
+	err := s.tx.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		ret, err := s.returns.GetReturnForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if ret == nil {
+			return errors.New("return not found")
+		}
+		if !ret.Status.CanTransition(status) {
+			return errors.New("invalid status transition")
+		}
+		if status == domain.ReturnRestocked {
+			if err := s.restock(ctx, tx, ret); err != nil {
+				return err
+			}
+		}
+		updated, err = s.returns.UpdateReturnStatus(ctx, tx, id, status)
+		if err != nil {
+			return err
+		}
+		if status != domain.ReturnReceived {
+			return nil
+		}
+		updated.Refund, err = s.returns.CreateRefund(ctx, tx, &domain.Refund{
+			ReturnID: id,
+			OrderID:  ret.OrderID,
+			Amount:   ret.RefundAmount(),
+		})
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, s.outbox, tx, domain.EventReturnReceived, domain.AggregateReturn, id, returnReceivedEvent(updated))
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// restock credits the returned quantities to stock, filling open backorders
+// first, and records each credit as a stock adjustment of the return.
+func (s *ReturnService) restock(ctx context.Context, tx pgx.Tx, ret *domain.Return) error {
+	returned := make(map[string]int, len(ret.Lines))
+	ids := make([]string, 0, len(ret.Lines))
+	for _, l := range ret.Lines {
+		if _, ok := returned[l.ProductID]; !ok {
+			ids = append(ids, l.ProductID)
+		}
+		returned[l.ProductID] += l.Quantity
+	}
+	// Products are locked in id order, like checkout and cancellation.
+	sort.Strings(ids)
+	products, err := s.products.GetByIDsForUpdate(ctx, tx, ids, domain.LockWait)
+	if err != nil {
+		return err
+	}
+	if len(products) != len(ids) {
+		return errors.New("product not found")
+	}
+	now := time.Now().UTC()
+	changes := make([]domain.StockChange, 0, len(ids))
+	fills := newBackorderFills(s.backorders, s.outbox, tx)
+	for _, p := range products {
+		shelved, err := fills.fill(ctx, p.ID, returned[p.ID])
+		if err != nil {
+			return err
+		}
+		if shelved > 0 {
+			if err := s.products.UpdateQuantity(ctx, tx, p.ID, shelved); err != nil {
+				return err
+			}
+			changes = append(changes, domain.StockChange{ProductID: p.ID, Delta: shelved})
+		}
+		err = addEvent(ctx, s.outbox, tx, domain.EventStockAdjusted, domain.AggregateProduct, p.ID, domain.StockAdjustedEvent{
+			ProductID:   p.ID,
+			Delta:       returned[p.ID],
+			Backordered: returned[p.ID] - shelved,
+			Quantity:    p.Quantity + shelved,
+			Reason:      "return " + ret.ID,
+			AdjustedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := fills.recordFulfilled(ctx); err != nil {
+		return err
+	}
+	return publishStock(ctx, tx, s.stock, stockLevels(products, changes)...)
+}
+
+func returnReceivedEvent(r *domain.Return) domain.ReturnReceivedEvent {
+	lines := make([]domain.ReturnReceivedEventLine, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, domain.ReturnReceivedEventLine{
+			OrderItemID: l.OrderItemID,
+			ProductID:   l.ProductID,
+			Quantity:    l.Quantity,
+			Price:       l.Price.StringFixed(2),
+		})
+	}
+	e := domain.ReturnReceivedEvent{
+		ReturnID:     r.ID,
+		OrderID:      r.OrderID,
+		RefundID:     r.Refund.ID,
+		RefundAmount: r.Refund.Amount.StringFixed(2),
+		Lines:        lines,
+	}
+	if r.ReceivedAt != nil {
+		e.ReceivedAt = *r.ReceivedAt
+	}
+	return e
+}
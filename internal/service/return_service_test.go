@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+)
+
+type returnRepoMock struct {
+	returns map[string]domain.Return
+}
+
+func (m *returnRepoMock) CreateReturn(ctx context.Context, tx pgx.Tx, r *domain.Return) (*domain.Return, error) {
+	created := *r
+	created.ID = "r" + strconv.Itoa(len(m.returns)+1)
+	m.returns[created.ID] = created
+	return &created, nil
+}
+
+func (m *returnRepoMock) ReturnedQuantities(ctx context.Context, tx pgx.Tx, orderID string) (map[string]int, error) {
+	result := map[string]int{}
+	for _, r := range m.returns {
+		for _, l := range r.Lines {
+			result[l.OrderItemID] += l.Quantity
+		}
+	}
+	return result, nil
+}
+
+func (m *returnRepoMock) GetReturn(ctx context.Context, id string) (*domain.Return, error) {
+	if r, ok := m.returns[id]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (m *returnRepoMock) GetReturnForUpdate(ctx context.Context, tx pgx.Tx, id string) (*domain.Return, error) {
+	return m.GetReturn(ctx, id)
+}
+
+func (m *returnRepoMock) ListReturns(ctx context.Context, orderID string) ([]domain.Return, error) {
+	return nil, nil
+}
+
+func (m *returnRepoMock) UpdateReturnStatus(ctx context.Context, tx pgx.Tx, id string, status domain.ReturnStatus) (*domain.Return, error) {
+	r := m.returns[id]
+	r.Status = status
+	m.returns[id] = r
+	return &r, nil
+}
+
+func (m *returnRepoMock) CreateRefund(ctx context.Context, tx pgx.Tx, refund *domain.Refund) (*domain.Refund, error) {
+	created := *refund
+	created.ID = "rf1"
+	return &created, nil
+}
+
+func TestReturnLimitsQuantitiesAndRefundsOrderedPrice(t *testing.T) {
+	products := &productRepoMock{
+		items: map[string]domain.Product{
+			// The price changed after the order.
+			"p1": {ID: "p1", Quantity: 0, Price: decimal.NewFromInt(99)},
+		},
+	}
+	orders := &orderRepoMock{created: &domain.Order{
+		ID:     "o1",
+		Status: domain.OrderDelivered,
+		Items:  []domain.OrderItem{{ID: "i1", ProductID: "p1", Quantity: 3, Price: decimal.NewFromInt(10)}},
+	}}
+	repo := &returnRepoMock{returns: map[string]domain.Return{}}
+	events := &outboxMock{}
+	svc := NewReturnService(repo, orders, products, txManagerMock{tx: txMock{}}, WithReturnEvents(events))
+
+	ret, err := svc.Create(context.Background(), "o1", ReturnInput{Reason: "broken", Lines: []ReturnLineInput{{OrderItemID: "i1", Quantity: 2}}})
+	require.NoError(t, err)
+	_, err = svc.Create(context.Background(), "o1", ReturnInput{Reason: "broken", Lines: []ReturnLineInput{{OrderItemID: "i1", Quantity: 2}}})
+	require.EqualError(t, err, "return quantity exceeds ordered quantity")
+
+	_, err = svc.UpdateStatus(context.Background(), ret.ID, domain.ReturnRestocked)
+	require.EqualError(t, err, "invalid status transition")
+
+	received, err := svc.UpdateStatus(context.Background(), ret.ID, domain.ReturnReceived)
+	require.NoError(t, err)
+	require.Equal(t, "20", received.Refund.Amount.String())
+	require.Len(t, events.events, 1)
+	require.Equal(t, domain.EventReturnReceived, events.events[0].Type)
+
+	_, err = svc.UpdateStatus(context.Background(), ret.ID, domain.ReturnRestocked)
+	require.NoError(t, err)
+	require.Equal(t, 2, products.items["p1"].Quantity)
+	require.Equal(t, domain.EventStockAdjusted, events.events[1].Type)
+}
+
+func TestReturnRequiresDeliveredOrder(t *testing.T) {
+	orders := &orderRepoMock{created: &domain.Order{
+		ID:     "o1",
+		Status: domain.OrderShipped,
+		Items:  []domain.OrderItem{{ID: "i1", ProductID: "p1", Quantity: 1}},
+	}}
+	svc := NewReturnService(&returnRepoMock{returns: map[string]domain.Return{}}, orders, &productRepoMock{}, txManagerMock{tx: txMock{}})
+
+	_, err := svc.Create(context.Background(), "o1", ReturnInput{Reason: "late", Lines: []ReturnLineInput{{OrderItemID: "i1", Quantity: 1}}})
+	require.EqualError(t, err, "order is not delivered")
+}
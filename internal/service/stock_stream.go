@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/sse"
+)
+
+var stockLog = logging.Named("stock")
+
+// StockEventType is the server-sent event name of stock level updates.
+const StockEventType = "stock"
+
+// StockStream fans committed stock levels out to the stream subscribers of
+// this instance, one topic per product. With a notifier the other instances
+// hear about them too, and feed theirs back through PublishStock.
+type StockStream struct {
+	broker   *sse.Broker
+	notifier domain.StockNotifier
+}
+
+type StockStreamOption func(s *StockStream)
+
+func WithStockNotifier(n domain.StockNotifier) StockStreamOption {
+	return func(s *StockStream) {
+		s.notifier = n
+	}
+}
+
+func NewStockStream(broker *sse.Broker, opts ...StockStreamOption) *StockStream {
+	s := &StockStream{broker: broker}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// PublishStock publishes to the subscribers of this instance only.
+func (s *StockStream) PublishStock(levels ...domain.StockLevel) {
+	for _, l := range levels {
+		// StockLevel always marshals.
+		data, _ := json.Marshal(l)
+		s.broker.Publish(l.ProductID, StockEventType, data)
+	}
+}
+
+func (s *StockStream) NotifyStock(ctx context.Context, tx pgx.Tx, levels ...domain.StockLevel) error {
+	if s.notifier == nil {
+		return nil
+	}
+	return s.notifier.NotifyStock(ctx, tx, levels...)
+}
+
+// Subscribe follows the given products, or every product when none are
+// given. See sse.Broker.Subscribe for resuming.
+func (s *StockStream) Subscribe(productIDs []string, lastEventID uint64, resume bool) *sse.Subscription {
+	return s.broker.Subscribe(productIDs, lastEventID, resume)
+}
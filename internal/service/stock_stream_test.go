@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/sse"
+)
+
+type stockNotifierMock struct {
+	tx     pgx.Tx
+	levels []domain.StockLevel
+}
+
+func (m *stockNotifierMock) NotifyStock(ctx context.Context, tx pgx.Tx, levels ...domain.StockLevel) error {
+	m.tx = tx
+	m.levels = append(m.levels, levels...)
+	return nil
+}
+
+func TestStockStreamNotifiesOtherInstancesInTx(t *testing.T) {
+	notifier := &stockNotifierMock{}
+	stream := NewStockStream(sse.NewBroker(), WithStockNotifier(notifier))
+	sub := stream.Subscribe(nil, 0, false)
+	defer sub.Close()
+
+	products := &productRepoMock{items: map[string]domain.Product{"p1": {ID: "p1", Quantity: 1}}}
+	svc := NewProductService(products, txManagerMock{tx: txMock{}}, WithProductStockPublisher(stream))
+	_, err := svc.AdjustStock(context.Background(), "p1", 2, "delivery")
+	require.NoError(t, err)
+
+	require.Equal(t, txMock{}, notifier.tx)
+	require.Len(t, notifier.levels, 1)
+	require.Equal(t, 3, notifier.levels[0].Quantity)
+
+	// Local subscribers are served in process, not through the notifier.
+	e := <-sub.C
+	var level domain.StockLevel
+	require.NoError(t, json.Unmarshal(e.Data, &level))
+	require.Equal(t, "p1", level.ProductID)
+	require.Equal(t, 3, level.Quantity)
+}
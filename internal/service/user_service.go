@@ -7,6 +7,7 @@ import (
 
 	"stockpilot/internal/domain"
 	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/tracing"
 )
 
 type RegisterInput struct {
@@ -27,6 +28,9 @@ func NewUserService(users domain.UserRepository) *UserService {
 }
 
 func (s *UserService) Register(ctx context.Context, input RegisterInput) (*domain.User, error) {
+	ctx = tracing.StartSpan(ctx, "UserService.Register")
+	defer tracing.EndSpan(ctx)
+
 	if input.Age < 18 {
 		return nil, errors.New("user must be at least 18")
 	}
@@ -38,6 +42,7 @@ func (s *UserService) Register(ctx context.Context, input RegisterInput) (*domai
 	}
 	existing, err := s.users.GetByEmail(ctx, input.Email)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		return nil, err
 	}
 	if existing != nil {
@@ -57,6 +62,7 @@ func (s *UserService) Register(ctx context.Context, input RegisterInput) (*domai
 	}
 	created, err := s.users.CreateUser(ctx, &user)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		return nil, err
 	}
 	return created, nil
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/poller"
+	"stockpilot/pkg/gonerve/tracing"
+	"stockpilot/pkg/gonerve/webhook"
+)
+
+var webhookLog = logging.Named("webhooks")
+
+var webhookAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "stockpilot_webhook_attempts_total",
+	Help: "Webhook delivery attempts by result (succeeded, retry, failed).",
+}, []string{"result"})
+
+type WebhookInput struct {
+	URL         string
+	Secret      string
+	Description string
+	EventTypes  []string
+	Active      bool
+}
+
+// WebhookService manages webhook subscriptions and delivers the outbox
+// events they subscribe to.
+type WebhookService struct {
+	repo         domain.WebhookRepository
+	sender       *webhook.Sender
+	batchSize    int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	backoff      poller.Backoff
+	poller       *poller.Poller
+}
+
+type WebhookServiceOption func(s *WebhookService)
+
+func WithWebhookSender(sender *webhook.Sender) WebhookServiceOption {
+	return func(s *WebhookService) {
+		s.sender = sender
+	}
+}
+
+func WithWebhookPolling(interval time.Duration, batchSize int) WebhookServiceOption {
+	return func(s *WebhookService) {
+		if interval > 0 {
+			s.pollInterval = interval
+		}
+		if batchSize > 0 {
+			s.batchSize = batchSize
+		}
+	}
+}
+
+// WithWebhookRetries sets how many attempts a delivery gets and the delay
+// after the first failure, which doubles up to maxBackoff.
+func WithWebhookRetries(maxAttempts int, minBackoff, maxBackoff time.Duration) WebhookServiceOption {
+	return func(s *WebhookService) {
+		if maxAttempts > 0 {
+			s.maxAttempts = maxAttempts
+		}
+		if minBackoff > 0 {
+			s.backoff.Min = minBackoff
+		}
+		if maxBackoff >= s.backoff.Min {
+			s.backoff.Max = maxBackoff
+		}
+	}
+}
+
+func NewWebhookService(repo domain.WebhookRepository, opts ...WebhookServiceOption) *WebhookService {
+	s := &WebhookService{
+		repo:         repo,
+		sender:       webhook.NewSender(10 * time.Second),
+		batchSize:    50,
+		pollInterval: time.Second,
+		lease:        time.Minute,
+		maxAttempts:  8,
+		backoff:      poller.Backoff{Min: 5 * time.Second, Max: time.Hour},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.poller = poller.New("webhook", webhookLog, s.ProcessDeliveries, s.batchSize, s.pollInterval)
+	return s
+}
+
+func (s *WebhookService) Create(ctx context.Context, input WebhookInput) (*domain.WebhookSubscription, error) {
+	ctx = tracing.StartSpan(ctx, "WebhookService.Create")
+	defer tracing.EndSpan(ctx)
+
+	if err := validateWebhookInput(input); err != nil {
+		return nil, err
+	}
+	if input.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		input.Secret = secret
+	}
+	created, err := s.repo.CreateWebhook(ctx, &domain.WebhookSubscription{
+		URL:         input.URL,
+		Secret:      input.Secret,
+		Description: input.Description,
+		EventTypes:  input.EventTypes,
+		Active:      input.Active,
+	})
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *WebhookService) Get(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	w, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, errors.New("webhook not found")
+	}
+	return w, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return s.repo.ListWebhooks(ctx)
+}
+
+// Update replaces the subscription settings. An empty secret keeps the
+// current one.
+func (s *WebhookService) Update(ctx context.Context, id string, input WebhookInput) (*domain.WebhookSubscription, error) {
+	ctx = tracing.StartSpan(ctx, "WebhookService.Update", attribute.String("webhook.id", id))
+	defer tracing.EndSpan(ctx)
+
+	if err := validateWebhookInput(input); err != nil {
+		return nil, err
+	}
+	current, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current.URL = input.URL
+	current.Description = input.Description
+	current.EventTypes = input.EventTypes
+	current.Active = input.Active
+	if input.Secret != "" {
+		current.Secret = input.Secret
+	}
+	updated, err := s.repo.UpdateWebhook(ctx, current)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return errors.New("id is required")
+	}
+	return s.repo.DeleteWebhook(ctx, id)
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]domain.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.repo.ListWebhookDeliveries(ctx, subscriptionID, limit)
+}
+
+func (s *WebhookService) Delivery(ctx context.Context, id string) (*domain.WebhookDelivery, []domain.WebhookAttempt, error) {
+	d, attempts, err := s.repo.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d == nil {
+		return nil, nil, errors.New("delivery not found")
+	}
+	return d, attempts, nil
+}
+
+// Replay sends a delivery again, whatever its status, with a fresh attempt
+// budget.
+func (s *WebhookService) Replay(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	d, err := s.repo.ReplayWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.Wake()
+	return d, nil
+}
+
+// Sink fans outbox events out into deliveries for the subscriptions that
+// want them.
+func (s *WebhookService) Sink() outbox.Sink {
+	return webhookSink{s: s}
+}
+
+type webhookSink struct {
+	s *WebhookService
+}
+
+func (webhookSink) Name() string { return "webhooks" }
+
+func (k webhookSink) Deliver(ctx context.Context, e outbox.Event) error {
+	n, err := k.s.repo.EnqueueWebhookDeliveries(ctx, e)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		k.s.Wake()
+	}
+	return nil
+}
+
+// Wake makes a running dispatcher poll immediately. It never blocks.
+func (s *WebhookService) Wake() {
+	s.poller.Wake()
+}
+
+// Run delivers due webhooks until ctx is done.
+func (s *WebhookService) Run(ctx context.Context) {
+	s.poller.Run(ctx)
+}
+
+// ProcessDeliveries claims one batch of due deliveries and sends each of
+// them once. It returns the number of deliveries claimed.
+func (s *WebhookService) ProcessDeliveries(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.ClaimWebhookDeliveries(ctx, s.batchSize, s.lease)
+	if err != nil {
+		return 0, errors.Wrap(err, "claim webhook deliveries")
+	}
+	for _, d := range deliveries {
+		if err := ctx.Err(); err != nil {
+			return len(deliveries), err
+		}
+		if err := s.attempt(ctx, d); err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (s *WebhookService) attempt(ctx context.Context, d domain.WebhookDelivery) error {
+	res := s.sender.Send(ctx, webhook.Request{
+		URL:        d.URL,
+		Secret:     d.Secret,
+		Event:      d.EventType,
+		DeliveryID: d.ID,
+		Body:       d.Payload,
+	})
+	now := time.Now().UTC()
+	d.Attempts++
+	d.LastStatusCode = res.StatusCode
+	d.LastError = ""
+	a := domain.WebhookAttempt{
+		DeliveryID:      d.ID,
+		Attempt:         d.Attempts,
+		StatusCode:      res.StatusCode,
+		Latency:         res.Latency,
+		ResponseSnippet: res.Snippet,
+		CreatedAt:       now,
+	}
+	if !res.OK() {
+		d.LastError = "unexpected status"
+		if res.Err != nil {
+			d.LastError = res.Err.Error()
+		}
+		a.Error = d.LastError
+	}
+
+	fields := []zap.Field{
+		zap.String("delivery_id", d.ID),
+		zap.String("event_type", d.EventType),
+		zap.Int("attempt", d.Attempts),
+		zap.Int("status", res.StatusCode),
+		zap.Duration("latency", res.Latency),
+	}
+	switch {
+	case res.OK():
+		d.Status = domain.WebhookSucceeded
+		webhookAttemptsTotal.WithLabelValues("succeeded").Inc()
+	case d.Attempts >= s.maxAttempts:
+		d.Status = domain.WebhookFailed
+		webhookAttemptsTotal.WithLabelValues("failed").Inc()
+		webhookLog.ErrorCtx(ctx, "webhook delivery failed", append(fields, zap.String("error", d.LastError))...)
+	default:
+		d.Status = domain.WebhookPending
+		d.NextAttemptAt = now.Add(s.backoff.Delay(d.Attempts))
+		webhookAttemptsTotal.WithLabelValues("retry").Inc()
+		webhookLog.WarnCtx(ctx, "webhook delivery will be retried", append(fields, zap.String("error", d.LastError), zap.Time("next_attempt_at", d.NextAttemptAt))...)
+	}
+	return s.repo.RecordWebhookAttempt(ctx, d, a)
+}
+
+func validateWebhookInput(input WebhookInput) error {
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid webhook url")
+	}
+	for _, t := range input.EventTypes {
+		if !slices.Contains(domain.EventTypes, t) {
+			return errors.New("unknown event type")
+		}
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generate webhook secret")
+	}
+	return hex.EncodeToString(b), nil
+}
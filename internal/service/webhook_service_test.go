@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/internal/domain"
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/outbox"
+	"stockpilot/pkg/gonerve/webhook"
+)
+
+type webhookRepoMock struct {
+	domain.WebhookRepository
+	sub      domain.WebhookSubscription
+	delivery *domain.WebhookDelivery
+	attempts []domain.WebhookAttempt
+}
+
+func (m *webhookRepoMock) GetWebhook(_ context.Context, id string) (*domain.WebhookSubscription, error) {
+	if id != m.sub.ID {
+		return nil, nil
+	}
+	w := m.sub
+	return &w, nil
+}
+
+func (m *webhookRepoMock) EnqueueWebhookDeliveries(_ context.Context, e outbox.Event) (int, error) {
+	if m.delivery != nil || !m.sub.Wants(e.Type) {
+		return 0, nil
+	}
+	m.delivery = &domain.WebhookDelivery{
+		ID:             "d1",
+		SubscriptionID: m.sub.ID,
+		EventID:        e.ID,
+		EventType:      e.Type,
+		Payload:        e.Payload,
+		Status:         domain.WebhookPending,
+	}
+	return 1, nil
+}
+
+func (m *webhookRepoMock) ClaimWebhookDeliveries(_ context.Context, limit int, _ time.Duration) ([]domain.WebhookDelivery, error) {
+	d := m.delivery
+	if d == nil || d.Status != domain.WebhookPending || d.NextAttemptAt.After(time.Now()) {
+		return nil, nil
+	}
+	claimed := *d
+	claimed.URL, claimed.Secret = m.sub.URL, m.sub.Secret
+	return []domain.WebhookDelivery{claimed}, nil
+}
+
+func (m *webhookRepoMock) RecordWebhookAttempt(_ context.Context, d domain.WebhookDelivery, a domain.WebhookAttempt) error {
+	d.URL, d.Secret = "", ""
+	m.delivery = &d
+	m.attempts = append(m.attempts, a)
+	return nil
+}
+
+func (m *webhookRepoMock) ReplayWebhookDelivery(_ context.Context, id string) (*domain.WebhookDelivery, error) {
+	if m.delivery == nil || m.delivery.ID != id {
+		return nil, errors.New("delivery not found")
+	}
+	m.delivery.Status = domain.WebhookPending
+	m.delivery.Attempts = 0
+	m.delivery.NextAttemptAt = time.Now()
+	d := *m.delivery
+	return &d, nil
+}
+
+func TestWebhookDeliveryRetriesUntilFailedAndReplays(t *testing.T) {
+	var healthy atomic.Bool
+	var signatureErr atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify("s3cret", r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, time.Minute, time.Now()); err != nil {
+			signatureErr.Store(err)
+		}
+		if !healthy.Load() {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := &webhookRepoMock{sub: domain.WebhookSubscription{ID: "w1", URL: srv.URL, Secret: "s3cret", Active: true}}
+	svc := NewWebhookService(repo, WithWebhookRetries(2, time.Millisecond, time.Millisecond))
+	ctx := context.Background()
+
+	e, err := outbox.NewEvent(domain.EventStockAdjusted, domain.AggregateProduct, "p1", map[string]int{"delta": 1})
+	require.NoError(t, err)
+	require.NoError(t, svc.Sink().Deliver(ctx, e))
+	require.NotNil(t, repo.delivery)
+
+	n, err := svc.ProcessDeliveries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, domain.WebhookPending, repo.delivery.Status)
+	require.Equal(t, http.StatusBadGateway, repo.delivery.LastStatusCode)
+	require.True(t, repo.delivery.NextAttemptAt.After(repo.attempts[0].CreatedAt))
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = svc.ProcessDeliveries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, domain.WebhookFailed, repo.delivery.Status)
+	require.Len(t, repo.attempts, 2)
+	require.Equal(t, "boom\n", repo.attempts[1].ResponseSnippet)
+	require.NotEmpty(t, repo.attempts[1].Error)
+
+	n, err = svc.ProcessDeliveries(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "failed deliveries are not retried")
+
+	healthy.Store(true)
+	_, err = svc.Replay(ctx, "d1")
+	require.NoError(t, err)
+	_, err = svc.ProcessDeliveries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, domain.WebhookSucceeded, repo.delivery.Status)
+	require.Equal(t, 1, repo.delivery.Attempts)
+	require.Len(t, repo.attempts, 3)
+	require.Nil(t, signatureErr.Load())
+}
+
+func TestWebhookBackoffDoublesUpToMax(t *testing.T) {
+	svc := NewWebhookService(&webhookRepoMock{}, WithWebhookRetries(10, time.Second, 5*time.Second))
+	require.Equal(t, time.Second, svc.backoff.Delay(1))
+	require.Equal(t, 2*time.Second, svc.backoff.Delay(2))
+	require.Equal(t, 4*time.Second, svc.backoff.Delay(3))
+	require.Equal(t, 5*time.Second, svc.backoff.Delay(4))
+	require.Equal(t, 5*time.Second, svc.backoff.Delay(9))
+}
+
+func TestWebhookCreateValidates(t *testing.T) {
+	svc := NewWebhookService(&webhookRepoMock{})
+	_, err := svc.Create(context.Background(), WebhookInput{URL: "not a url"})
+	require.EqualError(t, err, "invalid webhook url")
+	_, err = svc.Create(context.Background(), WebhookInput{URL: "https://example.com/hook", EventTypes: []string{"nope"}})
+	require.EqualError(t, err, "unknown event type")
+}
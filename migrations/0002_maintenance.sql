@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS maintenance_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    locked BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,21 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_point INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS safety_stock INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS low_stock_alerts (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    severity TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    reorder_point INTEGER NOT NULL,
+    safety_stock INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by TEXT NOT NULL DEFAULT ''
+);
+
+-- At most one open alert per product; a new one can only be raised after the
+-- previous one is acknowledged.
+CREATE UNIQUE INDEX IF NOT EXISTS low_stock_alerts_open_idx ON low_stock_alerts (product_id)
+    WHERE acknowledged_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS low_stock_alerts_created_idx ON low_stock_alerts (created_at DESC);
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    contact_name TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    lead_time_days INTEGER NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id UUID PRIMARY KEY,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id),
+    status TEXT NOT NULL DEFAULT 'draft',
+    expected_date DATE,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS purchase_orders_status_idx ON purchase_orders (status, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    id UUID PRIMARY KEY,
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity >= 0 AND received_quantity <= quantity),
+    UNIQUE (purchase_order_id, product_id)
+);
+
+-- Every receiving records who received what and when; the received_quantity
+-- of a line is the sum of its receipt lines.
+CREATE TABLE IF NOT EXISTS purchase_receipts (
+    id UUID PRIMARY KEY,
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    received_by TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS purchase_receipts_order_idx ON purchase_receipts (purchase_order_id, received_at);
+
+CREATE TABLE IF NOT EXISTS purchase_receipt_lines (
+    receipt_id UUID NOT NULL REFERENCES purchase_receipts(id) ON DELETE CASCADE,
+    line_id UUID NOT NULL REFERENCES purchase_order_lines(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (receipt_id, line_id)
+);
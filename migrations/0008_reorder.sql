@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at);
+CREATE INDEX IF NOT EXISTS order_items_order_idx ON order_items (order_id);
+CREATE INDEX IF NOT EXISTS purchase_order_lines_product_idx ON purchase_order_lines (product_id);
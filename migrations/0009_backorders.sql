@@ -0,0 +1,18 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS allow_backorder BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS backorders (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL UNIQUE REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    filled_quantity INTEGER NOT NULL DEFAULT 0 CHECK (filled_quantity >= 0 AND filled_quantity <= quantity),
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ
+);
+
+-- The fill queue of a product, oldest first.
+CREATE INDEX IF NOT EXISTS backorders_open_idx ON backorders (product_id, created_at, id)
+    WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS backorders_order_idx ON backorders (order_id);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS returns (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'authorized',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS returns_order_idx ON returns (order_id, created_at);
+
+-- price is the unit price of the order item, so refunds do not follow later
+-- product price changes.
+CREATE TABLE IF NOT EXISTS return_lines (
+    id UUID PRIMARY KEY,
+    return_id UUID NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price NUMERIC(12,2) NOT NULL,
+    UNIQUE (return_id, order_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS return_lines_order_item_idx ON return_lines (order_item_id);
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id UUID PRIMARY KEY,
+    return_id UUID NOT NULL UNIQUE REFERENCES returns(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMPTZ NOT NULL
+);
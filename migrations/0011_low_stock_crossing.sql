@@ -0,0 +1,6 @@
+-- below_reorder_point is the state seen by the last low-stock check, so an
+-- alert is only raised when the stock crosses the reorder point downwards.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS below_reorder_point BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE products p SET below_reorder_point = true
+WHERE EXISTS (SELECT 1 FROM low_stock_alerts a WHERE a.product_id = p.id AND a.acknowledged_at IS NULL);
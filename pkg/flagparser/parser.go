@@ -25,6 +25,9 @@ func parseFields(v reflect.Value, fieldMap map[string]fieldValue) error {
 		field := t.Field(i)
 		fValue := v.Field(i)
 		flagName := field.Tag.Get("flag")
+		if flagName == "-" {
+			continue
+		}
 		defaultValue := field.Tag.Get("default")
 		usage := field.Tag.Get("usage")
 
@@ -2,7 +2,7 @@ package db
 
 import (
 	"fmt"
-	"strings"
+	"net/url"
 
 	"stockpilot/pkg/gonerve/errors"
 )
@@ -36,29 +36,28 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// ToConnString builds a URL connection string. Credentials and options are
+// escaped and options are sorted by key, so the result is stable.
 func (c Config) ToConnString() string {
-	var b strings.Builder
 	if c.Scheme == "" {
 		c.Scheme = "postgres"
 	}
-	b.WriteString(c.Scheme)
-	b.WriteString("://")
-	b.WriteString(c.Username)
+	u := url.URL{
+		Scheme: c.Scheme,
+		Host:   c.Endpoint,
+		Path:   "/" + c.Database,
+	}
 	if c.Password != "" {
-		b.WriteString(":")
-		b.WriteString(c.Password)
+		u.User = url.UserPassword(c.Username, c.Password)
+	} else {
+		u.User = url.User(c.Username)
 	}
-	b.WriteString("@")
-	b.WriteString(c.Endpoint)
-	b.WriteString("/")
-	b.WriteString(c.Database)
 	if len(c.Options) > 0 {
-		b.WriteString("?")
-		parts := make([]string, 0, len(c.Options))
+		values := url.Values{}
 		for k, v := range c.Options {
-			parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+			values.Set(k, fmt.Sprint(v))
 		}
-		b.WriteString(strings.Join(parts, "&"))
+		u.RawQuery = values.Encode()
 	}
-	return b.String()
+	return u.String()
 }
@@ -11,6 +11,10 @@ func New(msg string) error {
 	return errors.New(msg)
 }
 
+func Newf(format string, args ...any) error {
+	return fmt.Errorf(format, args...)
+}
+
 func Wrap(err error, msg string) error {
 	if err == nil {
 		return nil
@@ -28,3 +32,7 @@ func Wrapf(err error, format string, args ...any) error {
 func Is(err, target error) bool {
 	return errors.Is(err, target)
 }
+
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
@@ -0,0 +1,33 @@
+package features
+
+import "sync/atomic"
+
+var flags atomic.Pointer[map[string]bool]
+
+func Set(m map[string]bool) {
+	clone := make(map[string]bool, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	flags.Store(&clone)
+}
+
+func Enabled(name string) bool {
+	m := flags.Load()
+	if m == nil {
+		return false
+	}
+	return (*m)[name]
+}
+
+func All() map[string]bool {
+	m := flags.Load()
+	if m == nil {
+		return map[string]bool{}
+	}
+	clone := make(map[string]bool, len(*m))
+	for k, v := range *m {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCtxMethodsAttachRequestFields(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := newZapLogger(zap.New(core), newLoggerLevel(zapcore.DebugLevel, nil))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = WithRequestID(ctx, "req-1")
+	ctx = WithUserID(ctx, "u1")
+	ctx = WithFields(ctx, zap.String("order_id", "o1"))
+
+	l.InfoCtx(ctx, "order created", zap.Int("lines", 2))
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	require.Equal(t, map[string]any{
+		"request_id": "req-1",
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+		"user_id":    "u1",
+		"order_id":   "o1",
+		"lines":      int64(2),
+	}, entries[0].ContextMap())
+}
+
+func TestCtxMethodsWithoutRequestContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := newZapLogger(zap.New(core), newLoggerLevel(zapcore.DebugLevel, nil))
+
+	l.WarnCtx(context.Background(), "plain")
+
+	require.Len(t, logs.All(), 1)
+	require.Empty(t, logs.All()[0].ContextMap())
+}
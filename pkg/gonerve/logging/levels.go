@@ -0,0 +1,197 @@
+package logging
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerLevel is the level of the global logger or of a named sub-logger.
+// A named level without its own value follows its parent.
+type loggerLevel struct {
+	mu      sync.Mutex
+	level   zap.AtomicLevel
+	initial zapcore.Level
+	custom  atomic.Bool
+	parent  *loggerLevel
+	revert  *time.Timer
+}
+
+func newLoggerLevel(l zapcore.Level, parent *loggerLevel) *loggerLevel {
+	return &loggerLevel{level: zap.NewAtomicLevelAt(l), initial: l, parent: parent}
+}
+
+func (l *loggerLevel) Enabled(lvl zapcore.Level) bool {
+	if l.parent != nil && !l.custom.Load() {
+		return l.parent.Enabled(lvl)
+	}
+	return l.level.Enabled(lvl)
+}
+
+func (l *loggerLevel) String() string {
+	if l.parent != nil && !l.custom.Load() {
+		return l.parent.String()
+	}
+	return l.level.String()
+}
+
+func (l *loggerLevel) inherited() bool {
+	return l.parent != nil && !l.custom.Load()
+}
+
+// set changes the level. With a positive d the previous level is restored
+// once d elapses, unless the level is changed again in the meantime.
+func (l *loggerLevel) set(level string, d time.Duration) error {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopRevert()
+	prevCustom, prevLevel := l.custom.Load(), l.level.Level()
+	l.level.SetLevel(parsed)
+	l.custom.Store(true)
+
+	if d > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(d, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.revert != timer {
+				return
+			}
+			l.revert = nil
+			l.level.SetLevel(prevLevel)
+			l.custom.Store(prevCustom)
+		})
+		l.revert = timer
+	}
+	return nil
+}
+
+func (l *loggerLevel) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopRevert()
+	l.level.SetLevel(l.initial)
+	l.custom.Store(false)
+}
+
+func (l *loggerLevel) init(level zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopRevert()
+	l.initial = level
+	l.level.SetLevel(level)
+}
+
+func (l *loggerLevel) stopRevert() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+}
+
+type levelFilterCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c levelFilterCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl)
+}
+
+func (c levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelFilterCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c levelFilterCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(e.Level) {
+		return ce
+	}
+	return c.Core.Check(e, ce)
+}
+
+func withLevel(l *zap.Logger, enabler zapcore.LevelEnabler) *zap.Logger {
+	return l.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return levelFilterCore{Core: c, enabler: enabler}
+	}))
+}
+
+var (
+	rootLevel = newLoggerLevel(zapcore.InfoLevel, nil)
+	base      atomic.Pointer[zap.Logger]
+	namedMu   sync.Mutex
+	named     = map[string]*zapLogger{}
+)
+
+// Named returns the sub-logger with the given name, creating it on first use.
+// Sub-loggers follow the global level until a level is set for them and keep
+// working across Init calls.
+func Named(name string) Logger {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	if l, ok := named[name]; ok {
+		return l
+	}
+	l := &zapLogger{name: name, level: newLoggerLevel(zapcore.InfoLevel, rootLevel)}
+	l.log.Store(namedZap(base.Load(), name, l.level))
+	named[name] = l
+	return l
+}
+
+// Lookup returns the sub-logger with the given name if it was created.
+func Lookup(name string) (Logger, bool) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	l, ok := named[name]
+	if !ok {
+		return nil, false
+	}
+	return l, true
+}
+
+func namedZap(b *zap.Logger, name string, level *loggerLevel) *zap.Logger {
+	if b == nil {
+		return zap.NewNop()
+	}
+	return withLevel(b.Named(name), level)
+}
+
+func rebuildNamed(b *zap.Logger) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	for name, l := range named {
+		l.log.Store(namedZap(b, name, l.level))
+	}
+}
+
+type LevelInfo struct {
+	Name      string `json:"name"`
+	Level     string `json:"level"`
+	Inherited bool   `json:"inherited"`
+}
+
+func Levels() []LevelInfo {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	result := make([]LevelInfo, 0, len(named))
+	for name, l := range named {
+		result = append(result, LevelInfo{Name: name, Level: l.level.String(), Inherited: l.level.inherited()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNamedLevelFollowsParentUntilSet(t *testing.T) {
+	root := newLoggerLevel(zapcore.InfoLevel, nil)
+	query := newLoggerLevel(zapcore.InfoLevel, root)
+
+	require.False(t, query.Enabled(zapcore.DebugLevel))
+	require.NoError(t, root.set("warn", 0))
+	require.Equal(t, "warn", query.String())
+	require.True(t, query.inherited())
+
+	require.NoError(t, query.set("debug", 0))
+	require.True(t, query.Enabled(zapcore.DebugLevel))
+	require.False(t, root.Enabled(zapcore.InfoLevel))
+	require.False(t, query.inherited())
+
+	query.reset()
+	require.Equal(t, "warn", query.String())
+	require.True(t, query.inherited())
+}
+
+func TestTimedLevelReverts(t *testing.T) {
+	root := newLoggerLevel(zapcore.InfoLevel, nil)
+	query := newLoggerLevel(zapcore.InfoLevel, root)
+
+	require.NoError(t, query.set("debug", 20*time.Millisecond))
+	require.Equal(t, "debug", query.String())
+	require.Eventually(t, func() bool {
+		return query.inherited() && query.String() == "info"
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, root.set("debug", 20*time.Millisecond))
+	require.NoError(t, root.set("error", 0))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, "error", root.String())
+}
+
+func TestSetLevelRejectsUnknownLevel(t *testing.T) {
+	require.Error(t, newLoggerLevel(zapcore.InfoLevel, nil).set("loud", 0))
+}
+
+func TestLookupDoesNotCreateLoggers(t *testing.T) {
+	_, ok := Lookup("lookup-test")
+	require.False(t, ok)
+	created := Named("lookup-test")
+	l, ok := Lookup("lookup-test")
+	require.True(t, ok)
+	require.Same(t, created, l)
+}
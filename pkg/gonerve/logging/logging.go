@@ -2,8 +2,11 @@ package logging
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type Config struct {
@@ -14,6 +17,15 @@ type Config struct {
 	Encoding          string   `mapstructure:"encoding" json:"encoding" yaml:"encoding"`
 	UltraHuman        bool     `mapstructure:"ultra_human" json:"ultra_human" yaml:"ultra_human"`
 	LogHttpRequests   bool     `mapstructure:"log_http_requests" json:"log_http_requests" yaml:"log_http_requests"`
+	Sampling          Sampling `mapstructure:"sampling" json:"sampling" yaml:"sampling"`
+	RedactFields      []string `mapstructure:"redact_fields" json:"redact_fields" yaml:"redact_fields"`
+}
+
+// Sampling keeps the first Initial entries with the same level and message
+// every second and then every Thereafter-th one. Zero Initial disables it.
+type Sampling struct {
+	Initial    int `mapstructure:"initial" json:"initial" yaml:"initial"`
+	Thereafter int `mapstructure:"thereafter" json:"thereafter" yaml:"thereafter"`
 }
 
 type Logger interface {
@@ -25,24 +37,25 @@ type Logger interface {
 	Sync() error
 	Zap() *zap.Logger
 	SetLevel(level string) error
+	SetLevelFor(level string, d time.Duration) error
+	ResetLevel()
 	GetLevel() string
 }
 
 type zapLogger struct {
-	log   *zap.Logger
-	level zap.AtomicLevel
+	name  string
+	log   atomic.Pointer[zap.Logger]
+	level *loggerLevel
 }
 
 var global Logger
 
 func Init(tracerName string, cfg *Config) error {
-	level := zap.NewAtomicLevel()
+	level := zapcore.InfoLevel
 	if cfg != nil && cfg.Level != "" {
-		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
-			level.SetLevel(zap.InfoLevel)
+		if parsed, err := zapcore.ParseLevel(cfg.Level); err == nil {
+			level = parsed
 		}
-	} else {
-		level.SetLevel(zap.InfoLevel)
 	}
 	encoding := "json"
 	if cfg != nil && cfg.Encoding != "" {
@@ -52,24 +65,59 @@ func Init(tracerName string, cfg *Config) error {
 	if cfg != nil && len(cfg.OutputPaths) > 0 {
 		output = cfg.OutputPaths
 	}
+	encoderCfg := zap.NewProductionEncoderConfig()
+	if cfg != nil && cfg.UltraHuman {
+		encoding = "console"
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+		encoderCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
 	zapCfg := zap.Config{
-		Level:             level,
+		Level:             zap.NewAtomicLevelAt(zapcore.DebugLevel),
 		Development:       false,
 		Encoding:          encoding,
-		EncoderConfig:     zap.NewProductionEncoderConfig(),
+		EncoderConfig:     encoderCfg,
 		OutputPaths:       output,
 		ErrorOutputPaths:  output,
 		DisableCaller:     cfg != nil && cfg.DisableCaller,
 		DisableStacktrace: cfg != nil && cfg.DisableStacktrace,
 	}
-	l, err := zapCfg.Build()
+	var redactFields []string
+	var sampling Sampling
+	if cfg != nil {
+		redactFields = cfg.RedactFields
+		sampling = cfg.Sampling
+	}
+	opts := []zap.Option{zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return newRedactCore(c, redactFields)
+	})}
+	if sampling.Initial > 0 {
+		opts = append(opts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(c, time.Second, sampling.Initial, sampling.Thereafter)
+		}))
+	}
+	l, err := zapCfg.Build(opts...)
 	if err != nil {
 		return err
 	}
-	global = &zapLogger{log: l, level: level}
+	rootLevel.init(level)
+	base.Store(l)
+	rebuildNamed(l)
+	global = newZapLogger(withLevel(l, rootLevel), rootLevel)
 	return nil
 }
 
+func newZapLogger(l *zap.Logger, level *loggerLevel) *zapLogger {
+	zl := &zapLogger{level: level}
+	zl.log.Store(l)
+	return zl
+}
+
+func ValidateLevel(level string) error {
+	_, err := zapcore.ParseLevel(level)
+	return err
+}
+
 func Shutdown() error {
 	if global == nil {
 		return nil
@@ -82,25 +130,30 @@ func GlobalLogger() Logger {
 }
 
 func (l *zapLogger) InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.log.Info(msg, fields...)
+	l.log.Load().Info(msg, contextFields(ctx, fields)...)
 }
 func (l *zapLogger) WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.log.Warn(msg, fields...)
+	l.log.Load().Warn(msg, contextFields(ctx, fields)...)
 }
 func (l *zapLogger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.log.Error(msg, fields...)
+	l.log.Load().Error(msg, contextFields(ctx, fields)...)
 }
 func (l *zapLogger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.log.Debug(msg, fields...)
+	l.log.Load().Debug(msg, contextFields(ctx, fields)...)
 }
 func (l *zapLogger) FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	l.log.Fatal(msg, fields...)
+	l.log.Load().Fatal(msg, contextFields(ctx, fields)...)
 }
-func (l *zapLogger) Sync() error                 { return l.log.Sync() }
-func (l *zapLogger) Zap() *zap.Logger            { return l.log }
-func (l *zapLogger) SetLevel(level string) error { return l.level.UnmarshalText([]byte(level)) }
+func (l *zapLogger) Sync() error                 { return l.log.Load().Sync() }
+func (l *zapLogger) Zap() *zap.Logger            { return l.log.Load() }
+func (l *zapLogger) SetLevel(level string) error { return l.level.set(level, 0) }
+func (l *zapLogger) ResetLevel()                 { l.level.reset() }
 func (l *zapLogger) GetLevel() string            { return l.level.String() }
 
+func (l *zapLogger) SetLevelFor(level string, d time.Duration) error {
+	return l.level.set(level, d)
+}
+
 func Info(ctx context.Context, msg string, fields ...zap.Field) {
 	if global != nil {
 		global.InfoCtx(ctx, msg, fields...)
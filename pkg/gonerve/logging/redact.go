@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const Redacted = "[REDACTED]"
+
+var defaultRedactFields = []string{"password", "password_hash", "token", "authorization"}
+
+// redactCore masks the values of configured field keys before they reach the encoder.
+type redactCore struct {
+	zapcore.Core
+	keys map[string]struct{}
+}
+
+func newRedactCore(c zapcore.Core, fields []string) zapcore.Core {
+	keys := make(map[string]struct{}, len(fields)+len(defaultRedactFields))
+	for _, f := range append(defaultRedactFields, fields...) {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			keys[f] = struct{}{}
+		}
+	}
+	return redactCore{Core: c, keys: keys}
+}
+
+func (c redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactCore{Core: c.Core.With(c.redact(fields)), keys: c.keys}
+}
+
+func (c redactCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c redactCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(e, c.redact(fields))
+}
+
+func (c redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		if _, ok := c.keys[strings.ToLower(f.Key)]; !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = zap.String(f.Key, Redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactCoreMasksConfiguredFields(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newRedactCore(core, []string{"email", " "})).With(zap.String("Token", "secret"))
+
+	l.Info("user created", zap.String("email", "a@b.c"), zap.String("password_hash", "x"), zap.String("id", "u1"))
+
+	require.Equal(t, map[string]any{
+		"Token":         Redacted,
+		"email":         Redacted,
+		"password_hash": Redacted,
+		"id":            "u1",
+	}, logs.All()[0].ContextMap())
+}
+
+func TestInitSamplesRepeatedMessages(t *testing.T) {
+	path := t.TempDir() + "/log.json"
+	require.NoError(t, Init("test", &Config{
+		Level:       "info",
+		OutputPaths: []string{path},
+		Sampling:    Sampling{Initial: 2, Thereafter: 0},
+	}))
+	for i := 0; i < 10; i++ {
+		Info(context.Background(), "hot path")
+	}
+	require.NoError(t, Shutdown())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "hot path"))
+}
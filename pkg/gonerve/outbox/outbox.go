@@ -0,0 +1,61 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/genuuid"
+)
+
+// Channel is the LISTEN/NOTIFY channel stores notify after adding events, so
+// a relay can deliver them without waiting for the next poll.
+const Channel = "outbox"
+
+var ug = genuuid.New()
+
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// Attempts is the number of failed deliveries so far.
+	Attempts int `json:"-"`
+}
+
+// NewEvent marshals payload to JSON and gives the event a fresh id that sinks
+// can use to deduplicate redeliveries.
+func NewEvent(eventType, aggregateType, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, errors.Wrapf(err, "marshal %s payload", eventType)
+	}
+	return Event{
+		ID:            ug.V4(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
+
+// Store is the outbox table as seen by the relay.
+type Store interface {
+	// Claim returns up to limit due events and hides them from other claims
+	// for lease, so several relays can share one table.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error)
+	MarkDelivered(ctx context.Context, id string) error
+	// MarkFailed records a failed attempt and schedules the next one.
+	MarkFailed(ctx context.Context, id string, cause string, retryIn time.Duration) error
+	// MarkDead records the last failed attempt and stops retrying the event.
+	MarkDead(ctx context.Context, id string, cause string) error
+}
+
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, e Event) error
+}
@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+	"stockpilot/pkg/gonerve/poller"
+)
+
+var relayLog = logging.Named("outbox")
+
+var (
+	deliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_deliveries_total",
+		Help: "Outbox event deliveries by sink and result (delivered, failed).",
+	}, []string{"sink", "result"})
+	deadLettersTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_dead_letters_total",
+		Help: "Outbox events that ran out of delivery attempts.",
+	})
+)
+
+// Relay delivers outbox events to every sink at least once. An event counts
+// as delivered only when all sinks accept it, so a retry may repeat it on
+// sinks that already succeeded.
+type Relay struct {
+	store        Store
+	sinks        []Sink
+	batchSize    int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	backoff      poller.Backoff
+	poller       *poller.Poller
+}
+
+type RelayOption func(r *Relay)
+
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		if n > 0 {
+			r.batchSize = n
+		}
+	}
+}
+
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		if d > 0 {
+			r.pollInterval = d
+		}
+	}
+}
+
+// WithMaxAttempts sets how many failed deliveries dead-letter an event.
+func WithMaxAttempts(n int) RelayOption {
+	return func(r *Relay) {
+		if n > 0 {
+			r.maxAttempts = n
+		}
+	}
+}
+
+// WithBackoff sets the retry delay after the first failure; it doubles with
+// every further failure up to max.
+func WithBackoff(min, max time.Duration) RelayOption {
+	return func(r *Relay) {
+		if min > 0 {
+			r.backoff.Min = min
+		}
+		if max >= r.backoff.Min {
+			r.backoff.Max = max
+		}
+	}
+}
+
+// WithLease sets how long a claimed event stays hidden from other relays. It
+// should exceed the time a batch takes to deliver.
+func WithLease(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		if d > 0 {
+			r.lease = d
+		}
+	}
+}
+
+func NewRelay(store Store, sinks []Sink, opts ...RelayOption) *Relay {
+	r := &Relay{
+		store:        store,
+		sinks:        sinks,
+		batchSize:    100,
+		pollInterval: time.Second,
+		lease:        time.Minute,
+		maxAttempts:  10,
+		backoff:      poller.Backoff{Min: time.Second, Max: 5 * time.Minute},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.poller = poller.New("outbox", relayLog, r.ProcessBatch, r.batchSize, r.pollInterval)
+	return r
+}
+
+// Wake makes a running relay poll immediately. It never blocks.
+func (r *Relay) Wake() {
+	r.poller.Wake()
+}
+
+// Run delivers events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	r.poller.Run(ctx)
+}
+
+// ProcessBatch claims one batch of due events and tries to deliver each of
+// them. It returns the number of events claimed.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	events, err := r.store.Claim(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, errors.Wrap(err, "claim events")
+	}
+	for _, e := range events {
+		// Unprocessed events become due again once their lease expires.
+		if err := ctx.Err(); err != nil {
+			return len(events), err
+		}
+		if err := r.deliver(ctx, e); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *Relay) deliver(ctx context.Context, e Event) error {
+	var failure error
+	for _, s := range r.sinks {
+		if err := s.Deliver(ctx, e); err != nil {
+			deliveriesTotal.WithLabelValues(s.Name(), "failed").Inc()
+			failure = errors.Wrapf(err, "sink %s", s.Name())
+			break
+		}
+		deliveriesTotal.WithLabelValues(s.Name(), "delivered").Inc()
+	}
+	if failure == nil {
+		return errors.Wrap(r.store.MarkDelivered(ctx, e.ID), "mark delivered")
+	}
+
+	attempt := e.Attempts + 1
+	fields := []zap.Field{zap.String("event_id", e.ID), zap.String("event_type", e.Type), zap.Int("attempt", attempt), zap.Error(failure)}
+	if attempt >= r.maxAttempts {
+		deadLettersTotal.Inc()
+		relayLog.ErrorCtx(ctx, "outbox event dead-lettered", fields...)
+		return errors.Wrap(r.store.MarkDead(ctx, e.ID, failure.Error()), "mark dead")
+	}
+	retryIn := r.backoff.Delay(attempt)
+	relayLog.WarnCtx(ctx, "outbox delivery failed", append(fields, zap.Duration("retry_in", retryIn))...)
+	return errors.Wrap(r.store.MarkFailed(ctx, e.ID, failure.Error(), retryIn), "mark failed")
+}
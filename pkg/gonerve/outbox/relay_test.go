@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type storedEvent struct {
+	event     Event
+	delivered bool
+	dead      bool
+	retryIn   time.Duration
+	lastError string
+}
+
+type fakeStore struct {
+	mu     sync.Mutex
+	events []*storedEvent
+}
+
+func (s *fakeStore) add(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, &storedEvent{event: e})
+}
+
+func (s *fakeStore) get(id string) *storedEvent {
+	for _, se := range s.events {
+		if se.event.ID == id {
+			return se
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) Claim(_ context.Context, limit int, _ time.Duration) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []Event
+	for _, se := range s.events {
+		if !se.delivered && !se.dead && len(result) < limit {
+			result = append(result, se.event)
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeStore) MarkDelivered(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(id).delivered = true
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(_ context.Context, id string, cause string, retryIn time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	se := s.get(id)
+	se.event.Attempts++
+	se.retryIn = retryIn
+	se.lastError = cause
+	return nil
+}
+
+func (s *fakeStore) MarkDead(_ context.Context, id string, cause string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	se := s.get(id)
+	se.event.Attempts++
+	se.dead = true
+	se.lastError = cause
+	return nil
+}
+
+func newTestEvent(t *testing.T) Event {
+	t.Helper()
+	e, err := NewEvent("order.created", "order", "o1", map[string]string{"order_id": "o1"})
+	require.NoError(t, err)
+	return e
+}
+
+func TestRelayRetriesUntilDelivered(t *testing.T) {
+	store := &fakeStore{}
+	e := newTestEvent(t)
+	store.add(e)
+	sink := NewMemorySink()
+	sink.SetError(errors.New("broker down"))
+	relay := NewRelay(store, []Sink{sink}, WithBackoff(time.Second, 3*time.Second))
+
+	for i := 0; i < 3; i++ {
+		n, err := relay.ProcessBatch(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}
+	se := store.get(e.ID)
+	require.Equal(t, 3, se.event.Attempts)
+	require.Equal(t, 3*time.Second, se.retryIn)
+	require.Contains(t, se.lastError, "sink memory: broker down")
+
+	sink.SetError(nil)
+	_, err := relay.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	require.True(t, se.delivered)
+	require.Len(t, sink.Events(), 1)
+	require.Equal(t, e.ID, sink.Events()[0].ID)
+
+	n, err := relay.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestRelayDeadLettersAfterMaxAttempts(t *testing.T) {
+	store := &fakeStore{}
+	e := newTestEvent(t)
+	store.add(e)
+	sink := NewMemorySink()
+	sink.SetError(errors.New("rejected"))
+	relay := NewRelay(store, []Sink{sink}, WithMaxAttempts(2))
+
+	for i := 0; i < 3; i++ {
+		_, err := relay.ProcessBatch(context.Background())
+		require.NoError(t, err)
+	}
+	se := store.get(e.ID)
+	require.True(t, se.dead)
+	require.False(t, se.delivered)
+	require.Equal(t, 2, se.event.Attempts)
+}
+
+func TestRelayRunWakesUp(t *testing.T) {
+	store := &fakeStore{}
+	sink := NewMemorySink()
+	relay := NewRelay(store, []Sink{sink}, WithPollInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	store.add(newTestEvent(t))
+	relay.Wake()
+	require.Eventually(t, func() bool { return len(sink.Events()) == 1 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got Event
+	var headers http.Header
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, time.Second)
+	e := newTestEvent(t)
+	require.NoError(t, sink.Deliver(context.Background(), e))
+	require.Equal(t, e.ID, got.ID)
+	require.JSONEq(t, `{"order_id":"o1"}`, string(got.Payload))
+	require.Equal(t, e.ID, headers.Get("X-Event-ID"))
+	require.Equal(t, "order.created", headers.Get("X-Event-Type"))
+
+	status = http.StatusBadGateway
+	require.EqualError(t, sink.Deliver(context.Background(), e), "webhook returned status 502")
+}
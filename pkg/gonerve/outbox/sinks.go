@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+// WebhookSink POSTs every event as JSON to a fixed URL. Any status outside
+// 2xx is a failed delivery.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", e.ID)
+	req.Header.Set("X-Event-Type", e.Type)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "post event")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Newf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Publisher is implemented by message broker clients such as NATS or Kafka
+// producers.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// PublisherSink publishes every event as JSON on subjectPrefix + event type,
+// e.g. "stockpilot.order.created".
+type PublisherSink struct {
+	name          string
+	publisher     Publisher
+	subjectPrefix string
+}
+
+func NewPublisherSink(name string, p Publisher, subjectPrefix string) *PublisherSink {
+	return &PublisherSink{name: name, publisher: p, subjectPrefix: subjectPrefix}
+}
+
+func (s *PublisherSink) Name() string { return s.name }
+
+func (s *PublisherSink) Deliver(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+	return s.publisher.Publish(ctx, s.subjectPrefix+e.Type, data)
+}
+
+// WriterSink writes every event as a JSON line.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) Name() string { return "stdout" }
+
+func (s *WriterSink) Deliver(_ context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// MemorySink keeps delivered events in memory for tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Name() string { return "memory" }
+
+func (s *MemorySink) Deliver(_ context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+// SetError makes deliveries fail with err until it is set back to nil.
+func (s *MemorySink) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+}
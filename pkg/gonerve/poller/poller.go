@@ -0,0 +1,85 @@
+// Package poller runs claim-and-process batches in a loop, for workers that
+// lease due rows from a table and retry failures with a backoff.
+package poller
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/logging"
+)
+
+// Backoff is an exponential retry delay: Min after the first failure,
+// doubling with every further failure up to Max.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Min
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	return min(d, b.Max)
+}
+
+// Func claims one batch of due work under a lease, processes it and returns
+// the number of items claimed. Items it leaves unprocessed become due again
+// once their lease expires.
+type Func func(ctx context.Context) (int, error)
+
+// Poller runs a Func until ctx is done: again right away after a full batch,
+// otherwise after the poll interval or a Wake.
+type Poller struct {
+	name      string
+	log       logging.Logger
+	process   Func
+	batchSize int
+	interval  time.Duration
+	wake      chan struct{}
+}
+
+// New returns a poller for process. name prefixes the message logged when a
+// batch fails.
+func New(name string, log logging.Logger, process Func, batchSize int, interval time.Duration) *Poller {
+	return &Poller{
+		name:      name,
+		log:       log,
+		process:   process,
+		batchSize: batchSize,
+		interval:  interval,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Wake makes a running poller poll immediately. It never blocks.
+func (p *Poller) Wake() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes batches until ctx is done.
+func (p *Poller) Run(ctx context.Context) {
+	for {
+		n, err := p.process(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			p.log.WarnCtx(ctx, p.name+" batch failed", zap.Error(err))
+		} else if n == p.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-time.After(p.interval):
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package poller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/pkg/gonerve/logging"
+)
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 5 * time.Second}
+	require.Equal(t, time.Second, b.Delay(1))
+	require.Equal(t, 2*time.Second, b.Delay(2))
+	require.Equal(t, 4*time.Second, b.Delay(3))
+	require.Equal(t, 5*time.Second, b.Delay(4))
+	require.Equal(t, 5*time.Second, b.Delay(9))
+}
+
+func TestPollerDrainsFullBatchesAndWakesUp(t *testing.T) {
+	var pending, calls atomic.Int32
+	pending.Store(5)
+	p := New("test", logging.Named("poller-test"), func(context.Context) (int, error) {
+		calls.Add(1)
+		n := min(pending.Load(), 2)
+		pending.Add(-n)
+		return int(n), nil
+	}, 2, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	// Two full batches run back to back, the short third one waits.
+	require.Eventually(t, func() bool { return calls.Load() == 3 }, time.Second, time.Millisecond)
+	require.Zero(t, pending.Load())
+
+	p.Wake()
+	require.Eventually(t, func() bool { return calls.Load() == 4 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -1,10 +1,18 @@
 package postgresql
 
-import "stockpilot/pkg/gonerve/db"
+import (
+	"time"
+
+	"stockpilot/pkg/gonerve/db"
+)
 
 type Config struct {
 	db.Config
-	ListenNotifications bool `mapstructure:"listen_notifications" json:"listen_notifications" yaml:"listen_notifications"`
+	Pool                 PoolConfig    `mapstructure:"pool" json:"pool" yaml:"pool"`
+	ListenNotifications  bool          `mapstructure:"listen_notifications" json:"listen_notifications" yaml:"listen_notifications"`
+	Replicas             []string      `mapstructure:"replicas" json:"replicas" yaml:"replicas"`
+	ReplicaMaxLag        time.Duration `mapstructure:"replica_max_lag" json:"replica_max_lag" yaml:"replica_max_lag"`
+	ReplicaCheckInterval time.Duration `mapstructure:"replica_check_interval" json:"replica_check_interval" yaml:"replica_check_interval"`
 }
 
 func (c Config) Validate() error {
@@ -19,3 +27,19 @@ func (c Config) ToConnString() string {
 	}
 	return c.Config.ToConnString()
 }
+
+// ReplicaConfig uses the primary credentials and options for every replica
+// endpoint.
+func (c Config) ReplicaConfig() ReplicaConfig {
+	endpoints := make(map[string]string, len(c.Replicas))
+	for _, endpoint := range c.Replicas {
+		rc := c
+		rc.Endpoint = endpoint
+		endpoints[endpoint] = rc.ToConnString()
+	}
+	return ReplicaConfig{
+		Endpoints:     endpoints,
+		MaxLag:        c.ReplicaMaxLag,
+		CheckInterval: c.ReplicaCheckInterval,
+	}
+}
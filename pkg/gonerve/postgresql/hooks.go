@@ -0,0 +1,33 @@
+package postgresql
+
+import "context"
+
+type commitHooksKey struct{}
+
+type commitHooks struct {
+	fns []func()
+}
+
+// AfterCommit registers f to run once the transaction carried by ctx has
+// committed. Hooks of a rolled back or retried attempt are dropped. Outside
+// a transaction f runs immediately.
+func AfterCommit(ctx context.Context, f func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		f()
+		return
+	}
+	hooks.fns = append(hooks.fns, f)
+}
+
+// WithCommitHooks returns a context collecting AfterCommit hooks and a
+// function running them. Transaction runners call it once per attempt and
+// run the hooks after a successful commit.
+func WithCommitHooks(ctx context.Context) (context.Context, func()) {
+	hooks := &commitHooks{}
+	return context.WithValue(ctx, commitHooksKey{}, hooks), func() {
+		for _, f := range hooks.fns {
+			f()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+)
+
+var ErrLocked = errors.New("db is locked")
+
+// MaintenanceChannel is notified with the new state whenever maintenance
+// mode is changed through SetMaintenance with sync enabled.
+const MaintenanceChannel = "maintenance"
+
+type MaintenanceState struct {
+	Locked    bool      `json:"locked"`
+	Reason    string    `json:"reason"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WithMaintenanceSync shares maintenance mode between instances through the
+// maintenance_state table, re-reading it every interval. With a subscriber
+// the state is also reloaded as soon as MaintenanceChannel is notified and
+// whenever the subscriber reconnects, as notifications sent while it was
+// down are lost.
+func WithMaintenanceSync(interval time.Duration) Option {
+	return func(r *Repository) {
+		r.maintenanceSync = interval
+	}
+}
+
+func (r *Repository) Lock(reason string) {
+	r.setMaintenance(MaintenanceState{Locked: true, Reason: reason, UpdatedAt: time.Now().UTC()})
+}
+
+func (r *Repository) Unlock() {
+	r.setMaintenance(MaintenanceState{UpdatedAt: time.Now().UTC()})
+}
+
+func (r *Repository) Maintenance() MaintenanceState {
+	r.maintenanceMu.Lock()
+	defer r.maintenanceMu.Unlock()
+	return r.maintenance
+}
+
+func (r *Repository) setMaintenance(s MaintenanceState) {
+	r.maintenanceMu.Lock()
+	defer r.maintenanceMu.Unlock()
+
+	if r.maintenance.Locked != s.Locked {
+		logging.Warn(context.Background(), "maintenance mode changed", zap.Bool("locked", s.Locked), zap.String("reason", s.Reason))
+	}
+	r.maintenance = s
+	r.isLocked.Store(s.Locked)
+}
+
+const upsertMaintenanceQuery = `
+INSERT INTO maintenance_state (id, locked, reason, updated_at)
+VALUES (TRUE, $1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET locked = EXCLUDED.locked, reason = EXCLUDED.reason, updated_at = EXCLUDED.updated_at
+`
+
+// SetMaintenance turns maintenance mode on or off. When the state is shared,
+// through WithMaintenanceSync or a subscriber, it is stored in the database
+// and announced on MaintenanceChannel.
+func (r *Repository) SetMaintenance(ctx context.Context, locked bool, reason string) error {
+	s := MaintenanceState{Locked: locked, Reason: reason, UpdatedAt: time.Now().UTC()}
+	if r.maintenanceSync > 0 || r.subscriber != nil {
+		err := pgx.BeginFunc(ctx, r.Conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, upsertMaintenanceQuery, s.Locked, s.Reason, s.UpdatedAt); err != nil {
+				return errors.Wrap(err, "store maintenance state")
+			}
+			return Notify(ctx, tx, MaintenanceChannel, boolPayload(locked))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	r.setMaintenance(s)
+	return nil
+}
+
+const getMaintenanceQuery = `SELECT locked, reason, updated_at FROM maintenance_state WHERE id`
+
+// SyncMaintenance loads the shared maintenance state from the database.
+func (r *Repository) SyncMaintenance(ctx context.Context) error {
+	var s MaintenanceState
+	err := r.Conn.QueryRow(ctx, getMaintenanceQuery).Scan(&s.Locked, &s.Reason, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.setMaintenance(MaintenanceState{})
+			return nil
+		}
+		return errors.Wrap(err, "load maintenance state")
+	}
+	r.setMaintenance(s)
+	return nil
+}
+
+// RunMaintenanceSync keeps the shared maintenance state in sync until ctx is
+// done. It does nothing unless the state is shared.
+func (r *Repository) RunMaintenanceSync(ctx context.Context) {
+	if r.subscriber != nil {
+		r.subscriber.Subscribe(MaintenanceChannel, func(ctx context.Context, _ *pgconn.Notification) {
+			r.syncMaintenance(ctx)
+		})
+		r.subscriber.OnListen(r.syncMaintenance)
+	}
+	if r.maintenanceSync <= 0 && r.subscriber == nil {
+		return
+	}
+	r.syncMaintenance(ctx)
+	if r.maintenanceSync <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.maintenanceSync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.syncMaintenance(ctx)
+		}
+	}
+}
+
+func (r *Repository) syncMaintenance(ctx context.Context) {
+	if err := r.SyncMaintenance(ctx); err != nil && ctx.Err() == nil {
+		logging.Error(ctx, "maintenance sync", zap.Error(err))
+	}
+}
+
+func boolPayload(v bool) string {
+	if v {
+		return "locked"
+	}
+	return "unlocked"
+}
@@ -0,0 +1,81 @@
+package postgresql
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var txTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_transactions_total",
+	Help: "Transactions finished by WithTx, by result (commit, rollback, commit_error).",
+}, []string{"result"})
+
+var txRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_transaction_retries_total",
+	Help: "Transactions retried by WithTx, by SQLSTATE (40001 serialization failure, 40P01 deadlock).",
+}, []string{"code"})
+
+type poolStatter interface {
+	Stat() *pgxpool.Stat
+}
+
+type PoolCollector struct {
+	pool poolStatter
+
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	constructing    *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// NewPoolCollector exposes pgxpool statistics of the repository connection.
+// It returns nil when the connection is not backed by a pgxpool.
+func NewPoolCollector(r *Repository) *PoolCollector {
+	pool, ok := r.Conn.(poolStatter)
+	if !ok {
+		return nil
+	}
+	return &PoolCollector{
+		pool:            pool,
+		acquired:        prometheus.NewDesc("db_pool_acquired_conns", "Connections currently acquired from the pool.", nil, nil),
+		idle:            prometheus.NewDesc("db_pool_idle_conns", "Idle connections in the pool.", nil, nil),
+		total:           prometheus.NewDesc("db_pool_total_conns", "Total connections in the pool.", nil, nil),
+		max:             prometheus.NewDesc("db_pool_max_conns", "Maximum size of the pool.", nil, nil),
+		constructing:    prometheus.NewDesc("db_pool_constructing_conns", "Connections being established.", nil, nil),
+		acquireCount:    prometheus.NewDesc("db_pool_acquire_total", "Successful acquires from the pool.", nil, nil),
+		acquireDuration: prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Total time spent waiting for a connection.", nil, nil),
+		emptyAcquire:    prometheus.NewDesc("db_pool_empty_acquire_total", "Acquires that had to wait because the pool was empty.", nil, nil),
+		canceledAcquire: prometheus.NewDesc("db_pool_canceled_acquire_total", "Acquires canceled by context.", nil, nil),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.constructing
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquire
+	ch <- c.canceledAcquire
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructing, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
@@ -0,0 +1,200 @@
+package postgresql
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+)
+
+var notifyLog = logging.Named("notify")
+
+type NotificationHandler func(ctx context.Context, n *pgconn.Notification)
+
+type listenConn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Subscriber holds a dedicated connection LISTENing on every channel that
+// has handlers and reconnects with exponential backoff when it breaks.
+type Subscriber struct {
+	connect    func(ctx context.Context) (listenConn, error)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	handlers map[string][]NotificationHandler
+	onListen []func(ctx context.Context)
+	// wake holds at most one pending signal that a channel was added, so a
+	// Subscribe that happens before or between waits is not lost.
+	wake chan struct{}
+}
+
+func NewSubscriber(connString string) *Subscriber {
+	return newSubscriber(func(ctx context.Context) (listenConn, error) {
+		return pgx.Connect(ctx, connString)
+	})
+}
+
+func newSubscriber(connect func(ctx context.Context) (listenConn, error)) *Subscriber {
+	return &Subscriber{
+		connect:    connect,
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		handlers:   map[string][]NotificationHandler{},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registers h for channel. It may be called before or while Run
+// is running.
+func (s *Subscriber) Subscribe(channel string, h NotificationHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, known := s.handlers[channel]
+	s.handlers[channel] = append(s.handlers[channel], h)
+	if !known {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// OnListen registers f to run every time a new connection listens on the
+// channels subscribed so far. Notifications sent while the connection was
+// down are lost, so f should reload whatever they announce.
+func (s *Subscriber) OnListen(f func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onListen = append(s.onListen, f)
+}
+
+// Run listens until ctx is done.
+func (s *Subscriber) Run(ctx context.Context) {
+	backoff := s.minBackoff
+	for ctx.Err() == nil {
+		connected, err := s.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = s.minBackoff
+		}
+		notifyLog.WarnCtx(ctx, "listen connection lost, reconnecting", zap.Error(err), zap.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+func (s *Subscriber) listen(ctx context.Context) (bool, error) {
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "connect")
+	}
+	defer conn.Close(context.Background())
+
+	listening := map[string]bool{}
+	for first := true; ; first = false {
+		for _, channel := range s.channels() {
+			if listening[channel] {
+				continue
+			}
+			if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+				return len(listening) > 0, errors.Wrapf(err, "listen %s", channel)
+			}
+			listening[channel] = true
+		}
+		if first {
+			s.listened(ctx)
+		}
+
+		waitCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			select {
+			case <-s.wake:
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+
+		n, err := conn.WaitForNotification(waitCtx)
+		woken := waitCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+		// A signal taken after the wait ended is covered by the channels
+		// read at the top of the next round.
+		<-done
+		if err == nil {
+			s.dispatch(ctx, n)
+			continue
+		}
+		if !woken {
+			return true, errors.Wrap(err, "wait for notification")
+		}
+	}
+}
+
+func (s *Subscriber) listened(ctx context.Context) {
+	s.mu.Lock()
+	hooks := slices.Clone(s.onListen)
+	s.mu.Unlock()
+
+	for _, f := range hooks {
+		f(ctx)
+	}
+}
+
+func (s *Subscriber) channels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]string, 0, len(s.handlers))
+	for channel := range s.handlers {
+		result = append(result, channel)
+	}
+	return result
+}
+
+func (s *Subscriber) dispatch(ctx context.Context, n *pgconn.Notification) {
+	s.mu.Lock()
+	handlers := append([]NotificationHandler(nil), s.handlers[n.Channel]...)
+	s.mu.Unlock()
+
+	notifyLog.DebugCtx(ctx, "notification received", zap.String("channel", n.Channel), zap.Uint32("pid", n.PID))
+	for _, h := range handlers {
+		h(ctx, n)
+	}
+}
+
+// Notify publishes payload on channel as part of tx, so listeners only see
+// it once the transaction commits.
+func Notify(ctx context.Context, tx pgx.Tx, channel, payload string) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return errors.Wrapf(err, "notify %s", channel)
+	}
+	return nil
+}
+
+// Subscriber returns the repository subscriber, or nil unless the repository
+// was created WithListenNotifications(true).
+func (r *Repository) Subscriber() *Subscriber {
+	return r.subscriber
+}
@@ -0,0 +1,113 @@
+package postgresql
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type fakeListenConn struct {
+	mu      sync.Mutex
+	listens []string
+	notes   chan *pgconn.Notification
+	broken  chan struct{}
+	// gate, when set, holds a LISTEN for two receives: the first tells the
+	// test it started, closing gate lets it finish.
+	gate chan struct{}
+}
+
+func newFakeListenConn() *fakeListenConn {
+	return &fakeListenConn{notes: make(chan *pgconn.Notification, 10), broken: make(chan struct{})}
+}
+
+func (c *fakeListenConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	if c.gate != nil {
+		<-c.gate
+		<-c.gate
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listens = append(c.listens, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakeListenConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.broken:
+		return nil, errors.New("connection reset")
+	case n := <-c.notes:
+		return n, nil
+	}
+}
+
+func (c *fakeListenConn) Close(context.Context) error { return nil }
+
+func (c *fakeListenConn) Listens() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.listens...)
+}
+
+func TestSubscriberDispatchesPerChannelAndReconnects(t *testing.T) {
+	conns := make(chan *fakeListenConn, 2)
+	first, second := newFakeListenConn(), newFakeListenConn()
+	conns <- first
+	conns <- second
+
+	s := newSubscriber(func(ctx context.Context) (listenConn, error) {
+		return <-conns, nil
+	})
+	s.minBackoff = time.Millisecond
+
+	got := make(chan string, 10)
+	s.Subscribe("stock", func(_ context.Context, n *pgconn.Notification) { got <- "stock:" + n.Payload })
+	s.OnListen(func(context.Context) { got <- "listening" })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	require.Equal(t, "listening", <-got)
+	require.Equal(t, []string{`LISTEN "stock"`}, first.Listens())
+	first.notes <- &pgconn.Notification{Channel: "stock", Payload: "p1"}
+	require.Equal(t, "stock:p1", <-got)
+
+	s.Subscribe("maintenance", func(_ context.Context, n *pgconn.Notification) { got <- "maintenance:" + n.Payload })
+	require.Eventually(t, func() bool { return len(first.Listens()) == 2 }, time.Second, time.Millisecond)
+	first.notes <- &pgconn.Notification{Channel: "maintenance", Payload: "locked"}
+	require.Equal(t, "maintenance:locked", <-got)
+
+	close(first.broken)
+	require.Equal(t, "listening", <-got)
+	require.Len(t, second.Listens(), 2)
+	second.notes <- &pgconn.Notification{Channel: "stock", Payload: "p2"}
+	require.Equal(t, "stock:p2", <-got)
+	require.ElementsMatch(t, []string{`LISTEN "stock"`, `LISTEN "maintenance"`}, second.Listens())
+}
+
+func TestSubscriberListensOnChannelsAddedDuringListen(t *testing.T) {
+	conn := newFakeListenConn()
+	conn.gate = make(chan struct{})
+	s := newSubscriber(func(ctx context.Context) (listenConn, error) {
+		return conn, nil
+	})
+	s.Subscribe("stock", func(context.Context, *pgconn.Notification) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	// Run is in the first LISTEN when the second channel is added.
+	conn.gate <- struct{}{}
+	s.Subscribe("maintenance", func(context.Context, *pgconn.Notification) {})
+	close(conn.gate)
+	require.Eventually(t, func() bool { return len(conn.Listens()) == 2 }, time.Second, time.Millisecond)
+}
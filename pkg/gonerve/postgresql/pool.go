@@ -0,0 +1,41 @@
+package postgresql
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig overrides pgxpool defaults. Zero values keep the default.
+type PoolConfig struct {
+	MaxConns          int32         `mapstructure:"max_conns" json:"max_conns" yaml:"max_conns"`
+	MinConns          int32         `mapstructure:"min_conns" json:"min_conns" yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `mapstructure:"max_conn_lifetime" json:"max_conn_lifetime" yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `mapstructure:"max_conn_idle_time" json:"max_conn_idle_time" yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period" json:"health_check_period" yaml:"health_check_period"`
+}
+
+// WithPool applies pool settings to the primary and replica pools.
+func WithPool(cfg PoolConfig) Option {
+	return func(r *Repository) {
+		r.poolCfg = cfg
+	}
+}
+
+func (c PoolConfig) apply(config *pgxpool.Config) {
+	if c.MaxConns > 0 {
+		config.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		config.MinConns = c.MinConns
+	}
+	if c.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = c.HealthCheckPeriod
+	}
+}
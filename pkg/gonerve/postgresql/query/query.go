@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -21,8 +22,50 @@ type dbConn interface {
 
 var replacer = strings.NewReplacer("\n", " ", "\t", " ")
 
+var log = logging.Named("query")
+
+var (
+	sensitiveMu   sync.RWMutex
+	sensitiveArgs = map[string]map[int]struct{}{}
+)
+
+// RegisterSensitiveArgs marks 1-based argument positions ($1, $2...) of q whose
+// values must never be written to logs.
+func RegisterSensitiveArgs(q string, positions ...int) {
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+
+	set, ok := sensitiveArgs[q]
+	if !ok {
+		set = map[int]struct{}{}
+		sensitiveArgs[q] = set
+	}
+	for _, p := range positions {
+		set[p] = struct{}{}
+	}
+}
+
+func FormatArgs(q string, args []any) string {
+	sensitiveMu.RLock()
+	set := sensitiveArgs[q]
+	sensitiveMu.RUnlock()
+
+	if len(set) == 0 {
+		return fmt.Sprintf("%v", args)
+	}
+	masked := make([]any, len(args))
+	for i, a := range args {
+		if _, ok := set[i+1]; ok {
+			masked[i] = logging.Redacted
+			continue
+		}
+		masked[i] = a
+	}
+	return fmt.Sprintf("%v", masked)
+}
+
 func GetAll[T any](ctx context.Context, c dbConn, q string, args ...any) ([]T, error) {
-	logging.Debug(ctx, "executing: ", zap.String("query", replacer.Replace(q)), zap.String("args", fmt.Sprintf("%v", args)))
+	log.DebugCtx(ctx, "executing: ", zap.String("query", replacer.Replace(q)), zap.String("args", FormatArgs(q, args)))
 	return GetAllNoLog[T](ctx, c, q, args...)
 }
 
@@ -47,7 +90,7 @@ func GetOne[T any](ctx context.Context, c dbConn, q string, args ...any) (*T, er
 }
 
 func Exec(ctx context.Context, c dbConn, q string, args ...any) error {
-	logging.Debug(ctx, "executing: ", zap.String("query", q), zap.String("args", fmt.Sprintf("%v", args)))
+	log.DebugCtx(ctx, "executing: ", zap.String("query", replacer.Replace(q)), zap.String("args", FormatArgs(q, args)))
 	r, err := c.Exec(ctx, q, args...)
 	if err != nil {
 		return errors.Wrap(err, "database query failed")
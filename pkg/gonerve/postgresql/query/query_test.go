@@ -0,0 +1,15 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatArgsMasksSensitivePositions(t *testing.T) {
+	q := "INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3)"
+	RegisterSensitiveArgs(q, 2, 3)
+
+	require.Equal(t, "[u1 [REDACTED] [REDACTED]]", FormatArgs(q, []any{"u1", "a@b.c", "hash"}))
+	require.Equal(t, "[u1 a@b.c]", FormatArgs("SELECT $1, $2", []any{"u1", "a@b.c"}))
+}
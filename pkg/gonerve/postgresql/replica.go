@@ -0,0 +1,167 @@
+package postgresql
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"stockpilot/pkg/gonerve/errors"
+	"stockpilot/pkg/gonerve/logging"
+)
+
+const defaultReplicaCheckInterval = 5 * time.Second
+
+var (
+	replicaHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_replica_healthy",
+		Help: "Whether a read replica receives reads (1) or not (0).",
+	}, []string{"endpoint"})
+	replicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_replica_lag_seconds",
+		Help: "Replication lag of a read replica at the last health check.",
+	}, []string{"endpoint"})
+)
+
+type replica struct {
+	name    string
+	conn    Connection
+	healthy atomic.Bool
+}
+
+type replicaSet struct {
+	replicas      []*replica
+	healthy       atomic.Pointer[[]Connection]
+	next          atomic.Uint64
+	maxLag        time.Duration
+	checkInterval time.Duration
+}
+
+type ReplicaConfig struct {
+	// Endpoints maps a display name, usually host:port, to a connection string.
+	Endpoints     map[string]string
+	MaxLag        time.Duration
+	CheckInterval time.Duration
+}
+
+// WithReplicas sends reads made through ReadConn to the given replicas,
+// round-robin, skipping replicas that are down or lag more than MaxLag.
+func WithReplicas(cfg ReplicaConfig) Option {
+	return func(r *Repository) {
+		r.replicaCfg = cfg
+	}
+}
+
+func (r *Repository) openReplicas(ctx context.Context) error {
+	if len(r.replicaCfg.Endpoints) == 0 {
+		return nil
+	}
+	set := &replicaSet{maxLag: r.replicaCfg.MaxLag, checkInterval: r.replicaCfg.CheckInterval}
+	if set.checkInterval <= 0 {
+		set.checkInterval = defaultReplicaCheckInterval
+	}
+	names := make([]string, 0, len(r.replicaCfg.Endpoints))
+	for name := range r.replicaCfg.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		config, err := pgxpool.ParseConfig(r.replicaCfg.Endpoints[name])
+		if err != nil {
+			return errors.Wrapf(err, "parse replica %s config", name)
+		}
+		r.poolCfg.apply(config)
+		if r.queryTracer != nil {
+			config.ConnConfig.Tracer = r.queryTracer
+		}
+		// The pool connects lazily, so a replica that is down at startup
+		// only stays out of rotation until a health check succeeds.
+		pool, err := pgxpool.NewWithConfig(ctx, config)
+		if err != nil {
+			return errors.Wrapf(err, "open replica %s", name)
+		}
+		set.replicas = append(set.replicas, &replica{name: name, conn: pool})
+	}
+	r.replicas = set
+	r.checkReplicas(ctx)
+	return nil
+}
+
+// ReadConn returns a healthy replica for reads that tolerate replication
+// lag, or the primary when there is none.
+func (r *Repository) ReadConn() Connection {
+	if r.replicas == nil {
+		return r.Conn
+	}
+	healthy := r.replicas.healthy.Load()
+	if healthy == nil || len(*healthy) == 0 {
+		return r.Conn
+	}
+	n := r.replicas.next.Add(1)
+	return (*healthy)[n%uint64(len(*healthy))]
+}
+
+// RunReplicaChecks re-checks replica health until ctx is done.
+func (r *Repository) RunReplicaChecks(ctx context.Context) {
+	if r.replicas == nil {
+		return
+	}
+	ticker := time.NewTicker(r.replicas.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkReplicas(ctx)
+		}
+	}
+}
+
+const replicaLagQuery = `
+SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8
+`
+
+func (r *Repository) checkReplicas(ctx context.Context) {
+	var healthyConns []Connection
+	for _, rep := range r.replicas.replicas {
+		healthy := r.replicas.check(ctx, rep)
+		if rep.healthy.Swap(healthy) != healthy {
+			logging.Warn(ctx, "replica health changed", zap.String("endpoint", rep.name), zap.Bool("healthy", healthy))
+		}
+		gauge := 0.0
+		if healthy {
+			gauge = 1
+			healthyConns = append(healthyConns, rep.conn)
+		}
+		replicaHealthy.WithLabelValues(rep.name).Set(gauge)
+	}
+	r.replicas.healthy.Store(&healthyConns)
+}
+
+func (s *replicaSet) check(ctx context.Context, rep *replica) bool {
+	checkCtx, cancel := context.WithTimeout(ctx, s.checkInterval)
+	defer cancel()
+
+	var lag float64
+	if err := rep.conn.QueryRow(checkCtx, replicaLagQuery).Scan(&lag); err != nil {
+		logging.Debug(ctx, "replica check failed", zap.String("endpoint", rep.name), zap.Error(err))
+		return false
+	}
+	replicaLag.WithLabelValues(rep.name).Set(lag)
+	return s.maxLag <= 0 || time.Duration(lag*float64(time.Second)) <= s.maxLag
+}
+
+func (s *replicaSet) close() {
+	for _, rep := range s.replicas {
+		rep.conn.Close()
+	}
+}
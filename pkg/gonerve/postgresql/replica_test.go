@@ -0,0 +1,78 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type fakeRow struct {
+	lag float64
+	err error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*float64) = r.lag
+	return nil
+}
+
+type fakeReplicaConn struct {
+	Connection
+	row fakeRow
+}
+
+func (c *fakeReplicaConn) QueryRow(context.Context, string, ...any) pgx.Row {
+	return c.row
+}
+
+func newTestReplicaRepository(maxLag time.Duration, conns map[string]*fakeReplicaConn) *Repository {
+	set := &replicaSet{maxLag: maxLag, checkInterval: time.Second}
+	for _, name := range []string{"a", "b", "c"} {
+		if conn, ok := conns[name]; ok {
+			set.replicas = append(set.replicas, &replica{name: name, conn: conn})
+		}
+	}
+	r := &Repository{Conn: &fakeConn{}, replicas: set}
+	r.checkReplicas(context.Background())
+	return r
+}
+
+func TestReadConnRoundRobinsHealthyReplicas(t *testing.T) {
+	a, b, c := &fakeReplicaConn{}, &fakeReplicaConn{row: fakeRow{lag: 30}}, &fakeReplicaConn{row: fakeRow{lag: 1}}
+	r := newTestReplicaRepository(5*time.Second, map[string]*fakeReplicaConn{"a": a, "b": b, "c": c})
+
+	seen := map[Connection]int{}
+	for i := 0; i < 6; i++ {
+		seen[r.ReadConn()]++
+	}
+	require.Equal(t, map[Connection]int{a: 3, c: 3}, seen)
+}
+
+func TestReadConnFallsBackToPrimary(t *testing.T) {
+	a := &fakeReplicaConn{row: fakeRow{err: errors.New("connection refused")}}
+	r := newTestReplicaRepository(0, map[string]*fakeReplicaConn{"a": a})
+	require.Same(t, r.Conn, r.ReadConn())
+
+	a.row = fakeRow{}
+	r.checkReplicas(context.Background())
+	require.Same(t, a, r.ReadConn())
+
+	require.Same(t, r.Conn, (&Repository{Conn: r.Conn}).ReadConn())
+}
+
+func TestWithTxStaysOnPrimary(t *testing.T) {
+	a := &fakeReplicaConn{}
+	r := newTestReplicaRepository(0, map[string]*fakeReplicaConn{"a": a})
+
+	err := r.WithTx(context.Background(), func(context.Context, pgx.Tx) error { return nil }, ReadOnly())
+	require.NoError(t, err)
+	require.Equal(t, 1, r.Conn.(*fakeConn).commits)
+}
@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,18 +21,54 @@ func WithListenNotifications(v bool) Option {
 	}
 }
 
+// WithQueryTracer installs a pgx tracer on every pooled connection. When the
+// tracer also implements TxTracer, WithTx reports transactions through it.
+func WithQueryTracer(t pgx.QueryTracer) Option {
+	return func(r *Repository) {
+		r.queryTracer = t
+		if tt, ok := t.(TxTracer); ok {
+			r.txTracer = tt
+		}
+	}
+}
+
+type TxTracer interface {
+	TraceTxStart(ctx context.Context) context.Context
+	TraceTxEnd(ctx context.Context, err error)
+}
+
 type Repository struct {
-	Conn     Connection
-	isLocked atomic.Bool
-	TxConn   bool
-	listen   bool
+	Conn        Connection
+	isLocked    atomic.Bool
+	TxConn      bool
+	listen      bool
+	queryTracer pgx.QueryTracer
+	txTracer    TxTracer
+
+	maintenanceMu   sync.Mutex
+	maintenance     MaintenanceState
+	maintenanceSync time.Duration
+	subscriber      *Subscriber
+
+	poolCfg    PoolConfig
+	replicaCfg ReplicaConfig
+	replicas   *replicaSet
 }
 
 func NewRepository(ctx context.Context, connString string, opts ...Option) (*Repository, error) {
+	r := &Repository{}
+	for _, opt := range opts {
+		opt(r)
+	}
+
 	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, errors.Wrap(err, "parse config")
 	}
+	r.poolCfg.apply(config)
+	if r.queryTracer != nil {
+		config.ConnConfig.Tracer = r.queryTracer
+	}
 
 	connectCtx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -46,14 +83,14 @@ func NewRepository(ctx context.Context, connString string, opts ...Option) (*Rep
 	}
 	c.Release()
 
-	r := &Repository{
-		Conn: conn,
+	r.Conn = conn
+	if err := r.openReplicas(ctx); err != nil {
+		conn.Close()
+		return nil, err
 	}
-
-	for _, opt := range opts {
-		opt(r)
+	if r.listen {
+		r.subscriber = NewSubscriber(connString)
 	}
-
 	return r, nil
 }
 
@@ -65,16 +102,24 @@ type Connection interface {
 	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 
 	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 	Close()
 }
 
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.Conn.Ping(ctx)
+}
+
 func (r *Repository) Close() {
 	r.Conn.Close()
+	if r.replicas != nil {
+		r.replicas.close()
+	}
 }
 
 func (r *Repository) Locked() error {
-	if !r.isLocked.CompareAndSwap(false, false) {
-		return errors.New("db is locked")
+	if r.isLocked.Load() {
+		return ErrLocked
 	}
 	return nil
 }
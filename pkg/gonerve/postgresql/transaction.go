@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
@@ -10,33 +11,81 @@ import (
 	"stockpilot/pkg/gonerve/logging"
 )
 
-func (r *Repository) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error) (err error) {
-	if err = r.Locked(); err != nil {
-		return err
+var txLog = logging.Named("tx")
+
+// WithTx runs f in a transaction and commits it when f succeeds. Transactions
+// failing with a serialization failure or deadlock are retried with jittered
+// backoff, so f must be safe to run more than once.
+func (r *Repository) WithTx(ctx context.Context, f func(ctx context.Context, tx pgx.Tx) error, opts ...TxOption) error {
+	cfg := NewTxConfig(opts...)
+	for attempt := 0; ; attempt++ {
+		err := r.runTx(ctx, cfg, f)
+		code, retryable := retryableCode(err)
+		if !retryable || attempt >= cfg.Retries {
+			return err
+		}
+
+		delay := retryDelay(attempt)
+		txRetries.WithLabelValues(code).Inc()
+		txLog.WarnCtx(ctx, "retrying tx", zap.String("code", code), zap.Int("attempt", attempt+1), zap.Duration("delay", delay))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (r *Repository) runTx(ctx context.Context, cfg TxConfig, f func(ctx context.Context, tx pgx.Tx) error) (err error) {
+	if !cfg.ReadOnly() {
+		if err = r.Locked(); err != nil {
+			return err
+		}
+	}
+
+	if r.txTracer != nil {
+		ctx = r.txTracer.TraceTxStart(ctx)
+		defer func() {
+			r.txTracer.TraceTxEnd(ctx, err)
+		}()
 	}
 
+	ctx, runHooks := WithCommitHooks(ctx)
+
+	conn := r.Conn
+	if cfg.onReplica() {
+		conn = r.ReadConn()
+	}
 	var tx pgx.Tx
-	tx, err = r.Conn.Begin(ctx)
+	tx, err = conn.BeginTx(ctx, cfg.TxOptions)
 	if err != nil {
 		return errors.Wrap(err, "begin tx")
 	}
 
 	defer func() {
-		if err == nil {
+		if err == nil && !cfg.ReadOnly() {
 			err = r.Locked()
 		}
 
 		if err != nil {
+			txTotal.WithLabelValues("rollback").Inc()
 			if rbErr := tx.Rollback(ctx); rbErr != nil {
-				logging.Error(ctx, "rollback tx", zap.Error(rbErr))
+				txLog.ErrorCtx(ctx, "rollback tx", zap.Error(rbErr))
 			}
 			return
 		}
 
 		if cmErr := tx.Commit(ctx); cmErr != nil {
+			txTotal.WithLabelValues("commit_error").Inc()
 			err = errors.Wrap(cmErr, "commit tx")
+			return
 		}
+		txTotal.WithLabelValues("commit").Inc()
+		runHooks()
 	}()
 
+	if err = cfg.apply(ctx, tx); err != nil {
+		return err
+	}
 	return f(ctx, tx)
 }
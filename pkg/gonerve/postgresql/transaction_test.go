@@ -0,0 +1,151 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	conn *fakeConn
+}
+
+func (t fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	t.conn.execs = append(t.conn.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (t fakeTx) Commit(context.Context) error {
+	t.conn.commits++
+	return nil
+}
+
+func (t fakeTx) Rollback(context.Context) error {
+	t.conn.rollbacks++
+	return nil
+}
+
+type fakeConn struct {
+	Connection
+	options   []pgx.TxOptions
+	execs     []string
+	commits   int
+	rollbacks int
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	c.options = append(c.options, opts)
+	return fakeTx{conn: c}, nil
+}
+
+func TestWithTxRetriesDeadlocks(t *testing.T) {
+	conn := &fakeConn{}
+	r := &Repository{Conn: conn}
+
+	calls := 0
+	err := r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		if calls < 3 {
+			return errors.Wrap(&pgconn.PgError{Code: deadlockCode}, "update quantity")
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, 2, conn.rollbacks)
+	require.Equal(t, 1, conn.commits)
+}
+
+func TestWithTxGivesUpAfterRetries(t *testing.T) {
+	conn := &fakeConn{}
+	r := &Repository{Conn: conn}
+
+	calls := 0
+	err := r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: serializationCode}
+	}, WithRetries(1))
+
+	require.Error(t, err)
+	require.Equal(t, 2, calls)
+
+	calls = 0
+	err = r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		return errors.New("insufficient stock")
+	})
+	require.EqualError(t, err, "insufficient stock")
+	require.Equal(t, 1, calls)
+}
+
+func TestWithTxOptions(t *testing.T) {
+	conn := &fakeConn{}
+	r := &Repository{Conn: conn}
+	r.isLocked.Store(true)
+
+	err := r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error { return nil },
+		WithIsolation(pgx.Serializable), ReadOnly(), Deferrable(), WithStatementTimeout(1500*time.Millisecond), WithLockTimeout(200*time.Millisecond))
+
+	require.NoError(t, err)
+	require.Equal(t, []pgx.TxOptions{{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly, DeferrableMode: pgx.Deferrable}}, conn.options)
+	require.Len(t, conn.execs, 2)
+	require.Equal(t, "1500ms", durationSetting(1500*time.Millisecond))
+
+	err = r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error { return nil })
+	require.ErrorIs(t, err, ErrLocked)
+}
+
+func TestWithTxOnReplica(t *testing.T) {
+	primary, replicaConn := &fakeConn{}, &fakeConn{}
+	set := &replicaSet{}
+	set.healthy.Store(&[]Connection{replicaConn})
+	r := &Repository{Conn: primary, replicas: set}
+	noop := func(ctx context.Context, tx pgx.Tx) error { return nil }
+
+	require.NoError(t, r.WithTx(context.Background(), noop, ReadOnly(), OnReplica()))
+	require.Equal(t, 1, replicaConn.commits)
+
+	require.NoError(t, r.WithTx(context.Background(), noop, OnReplica()))
+	require.NoError(t, r.WithTx(context.Background(), noop, ReadOnly(), OnReplica(), WithIsolation(pgx.Serializable)))
+	require.Equal(t, 2, primary.commits)
+	require.Equal(t, 1, replicaConn.commits)
+}
+
+func TestWithTxRunsCommitHooksOfTheCommittedAttempt(t *testing.T) {
+	conn := &fakeConn{}
+	r := &Repository{Conn: conn}
+
+	var ran []int
+	calls := 0
+	err := r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		attempt := calls
+		AfterCommit(ctx, func() { ran = append(ran, attempt) })
+		if calls < 2 {
+			return &pgconn.PgError{Code: serializationCode}
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, ran)
+
+	ran = nil
+	err = r.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		AfterCommit(ctx, func() { ran = append(ran, 1) })
+		return errors.New("boom")
+	})
+	require.Error(t, err)
+	require.Empty(t, ran)
+
+	AfterCommit(context.Background(), func() { ran = append(ran, 3) })
+	require.Equal(t, []int{3}, ran)
+}
@@ -0,0 +1,137 @@
+package postgresql
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"stockpilot/pkg/gonerve/errors"
+)
+
+const (
+	defaultTxRetries  = 3
+	txRetryBaseDelay  = 10 * time.Millisecond
+	txRetryMaxDelay   = 500 * time.Millisecond
+	serializationCode = "40001"
+	deadlockCode      = "40P01"
+)
+
+type TxOption func(c *TxConfig)
+
+type TxConfig struct {
+	pgx.TxOptions
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+	Retries          int
+	Replica          bool
+}
+
+func NewTxConfig(opts ...TxOption) TxConfig {
+	c := TxConfig{Retries: defaultTxRetries}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c TxConfig) ReadOnly() bool {
+	return c.AccessMode == pgx.ReadOnly
+}
+
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(c *TxConfig) {
+		c.IsoLevel = level
+	}
+}
+
+// ReadOnly runs the transaction read-only. Read-only transactions are allowed
+// while the repository is locked for maintenance.
+func ReadOnly() TxOption {
+	return func(c *TxConfig) {
+		c.AccessMode = pgx.ReadOnly
+	}
+}
+
+// OnReplica runs a read-only transaction on a connection from ReadConn, so
+// it may see data that lags the primary. Serializable transactions stay on
+// the primary, as standbys do not support them.
+func OnReplica() TxOption {
+	return func(c *TxConfig) {
+		c.Replica = true
+	}
+}
+
+// Deferrable only has an effect for serializable read-only transactions.
+func Deferrable() TxOption {
+	return func(c *TxConfig) {
+		c.DeferrableMode = pgx.Deferrable
+	}
+}
+
+func WithStatementTimeout(d time.Duration) TxOption {
+	return func(c *TxConfig) {
+		c.StatementTimeout = d
+	}
+}
+
+func WithLockTimeout(d time.Duration) TxOption {
+	return func(c *TxConfig) {
+		c.LockTimeout = d
+	}
+}
+
+// WithRetries sets how many times a transaction failing with a serialization
+// failure or deadlock is retried. Zero disables retries.
+func WithRetries(n int) TxOption {
+	return func(c *TxConfig) {
+		c.Retries = n
+	}
+}
+
+func (c TxConfig) onReplica() bool {
+	return c.Replica && c.ReadOnly() && c.IsoLevel != pgx.Serializable
+}
+
+func (c TxConfig) apply(ctx context.Context, tx pgx.Tx) error {
+	if c.StatementTimeout > 0 {
+		if _, err := tx.Exec(ctx, "SELECT set_config('statement_timeout', $1, true)", durationSetting(c.StatementTimeout)); err != nil {
+			return errors.Wrap(err, "set statement_timeout")
+		}
+	}
+	if c.LockTimeout > 0 {
+		if _, err := tx.Exec(ctx, "SELECT set_config('lock_timeout', $1, true)", durationSetting(c.LockTimeout)); err != nil {
+			return errors.Wrap(err, "set lock_timeout")
+		}
+	}
+	return nil
+}
+
+func durationSetting(d time.Duration) string {
+	return strconv.FormatInt(max(d.Milliseconds(), 1), 10) + "ms"
+}
+
+// retryableCode returns the SQLSTATE of serialization failures and deadlocks.
+func retryableCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	switch pgErr.Code {
+	case serializationCode, deadlockCode:
+		return pgErr.Code, true
+	}
+	return "", false
+}
+
+// retryDelay is an exponential backoff with full jitter.
+func retryDelay(attempt int) time.Duration {
+	d := txRetryBaseDelay << attempt
+	if d <= 0 || d > txRetryMaxDelay {
+		d = txRetryMaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(d)) + 1)
+}
@@ -6,36 +6,117 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"stockpilot/pkg/gonerve/errors"
 	"stockpilot/pkg/gonerve/logging"
 )
 
 type Config struct {
 	DSN           string  `mapstructure:"dsn" json:"dsn" yaml:"dsn"`
 	ErrSampleRate float64 `mapstructure:"err_sample_rate" json:"err_sample_rate" yaml:"err_sample_rate"`
+	Environment   string  `mapstructure:"environment" json:"environment" yaml:"environment"`
+	Release       string  `mapstructure:"release" json:"release" yaml:"release"`
+
+	// Transport replaces the HTTP transport, e.g. with a fake one in tests.
+	Transport sentry.Transport `json:"-" yaml:"-"`
+}
+
+func Init(cfg Config) error {
+	return sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		SampleRate:       cfg.ErrSampleRate,
+		Environment:      cfg.Environment,
+		Release:          cfg.Release,
+		AttachStacktrace: true,
+		Transport:        cfg.Transport,
+	})
+}
+
+// HubMiddleware binds a per-request hub carrying the HTTP request to the
+// request context, so events captured later include it.
+func HubMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hub := sentry.CurrentHub().Clone()
+		hub.Scope().SetRequest(c.Request())
+		ctx := sentry.SetHubOnContext(c.Request().Context(), hub)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
 }
 
 func ErrEchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(ctx echo.Context) error {
-		err := next(ctx)
+	return func(c echo.Context) error {
+		err := next(c)
 		if err != nil {
-			sentry.CaptureException(err)
+			CaptureRequestError(c, err)
 		}
 		return err
 	}
 }
 
 func PanicEchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(ctx echo.Context) error {
-		defer recoverPanicTo500(ctx.Request().Context(), ctx.Response())
-		return next(ctx)
+	return func(c echo.Context) error {
+		defer recoverPanicTo500(c)
+		return next(c)
 	}
 }
 
-func recoverPanicTo500(ctx context.Context, r *echo.Response) {
-	if rec := recover(); rec != nil {
-		logging.Error(ctx, "panic caught", zap.Any("error", rec))
-		r.WriteHeader(http.StatusInternalServerError)
+func recoverPanicTo500(c echo.Context) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	ctx := c.Request().Context()
+	err, ok := rec.(error)
+	if !ok {
+		err = errors.Newf("panic: %v", rec)
+	}
+	logging.Error(ctx, "panic caught", zap.Error(err), zap.Stack("stack"))
+
+	hub := hubFromContext(ctx)
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(requestTags(c))
+		hub.RecoverWithContext(ctx, err)
+	})
+
+	if !c.Response().Committed {
+		_ = c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+}
+
+// CaptureRequestError reports err with the route, request, user and trace ids
+// of the current request.
+func CaptureRequestError(c echo.Context, err error) {
+	hub := hubFromContext(c.Request().Context())
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(requestTags(c))
+		hub.CaptureException(err)
+	})
+}
+
+func hubFromContext(ctx context.Context) *sentry.Hub {
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		return hub
+	}
+	return sentry.CurrentHub()
+}
+
+func requestTags(c echo.Context) map[string]string {
+	ctx := c.Request().Context()
+	tags := map[string]string{
+		"route":  c.Path(),
+		"method": c.Request().Method,
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		tags["request_id"] = id
+	}
+	if id := logging.UserID(ctx); id != "" {
+		tags["user_id"] = id
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		tags["trace_id"] = sc.TraceID().String()
 	}
+	return tags
 }
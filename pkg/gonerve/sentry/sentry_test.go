@@ -0,0 +1,76 @@
+package sentry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"stockpilot/pkg/gonerve/logging"
+)
+
+func newTestServer(t *testing.T) (*echo.Echo, *MemoryTransport) {
+	transport := NewMemoryTransport()
+	require.NoError(t, Init(Config{
+		DSN:           "https://key@sentry.local/1",
+		ErrSampleRate: 1,
+		Environment:   "test",
+		Release:       "stockpilot@test",
+		Transport:     transport,
+	}))
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(nil) })
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := logging.WithUserID(logging.WithRequestID(c.Request().Context(), "req-1"), "u1")
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	})
+	e.Use(HubMiddleware, PanicEchoMiddleware)
+	return e, transport
+}
+
+func TestPanicIsCapturedWithStack(t *testing.T) {
+	e, transport := newTestServer(t)
+	e.GET("/boom/:id", func(c echo.Context) error { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom/1", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	events := transport.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, sentry.LevelFatal, events[0].Level)
+	require.Equal(t, "panic: boom", events[0].Exception[0].Value)
+	require.NotEmpty(t, events[0].Exception[0].Stacktrace.Frames)
+	require.Equal(t, "/boom/:id", events[0].Tags["route"])
+	require.Equal(t, "req-1", events[0].Tags["request_id"])
+	require.Equal(t, "u1", events[0].Tags["user_id"])
+	require.Equal(t, "stockpilot@test", events[0].Release)
+	require.Equal(t, "test", events[0].Environment)
+	require.True(t, strings.HasSuffix(events[0].Request.URL, "/boom/1"))
+}
+
+func TestCaptureRequestError(t *testing.T) {
+	e, transport := newTestServer(t)
+	e.GET("/fail", func(c echo.Context) error {
+		CaptureRequestError(c, errors.New("database query failed"))
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	events := transport.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, "database query failed", events[0].Exception[0].Value)
+	require.Equal(t, "/fail", events[0].Tags["route"])
+	require.Equal(t, "GET", events[0].Tags["method"])
+}
@@ -0,0 +1,40 @@
+package sentry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// MemoryTransport keeps events in memory instead of sending them.
+type MemoryTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Configure(sentry.ClientOptions) {}
+
+func (t *MemoryTransport) Flush(time.Duration) bool { return true }
+
+func (t *MemoryTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *MemoryTransport) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*sentry.Event(nil), t.events...)
+}
+
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = nil
+}
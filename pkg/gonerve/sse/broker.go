@@ -0,0 +1,182 @@
+package sse
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	subscribersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sse_subscribers",
+		Help: "Open server-sent event subscriptions.",
+	})
+	droppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sse_dropped_subscribers_total",
+		Help: "Subscriptions closed because the client could not keep up.",
+	})
+)
+
+const (
+	defaultBufferSize       = 1024
+	defaultSubscriberBuffer = 64
+)
+
+// Broker fans events out to the subscriptions of one process and keeps the
+// latest of them in a ring buffer for Last-Event-ID resumes.
+type Broker struct {
+	mu               sync.Mutex
+	lastID           uint64
+	ring             []Event
+	next             int
+	subs             map[*Subscription]struct{}
+	subscriberBuffer int
+}
+
+type BrokerOption func(b *Broker)
+
+// WithBufferSize sets how many of the latest events can be replayed.
+func WithBufferSize(n int) BrokerOption {
+	return func(b *Broker) {
+		if n > 0 {
+			b.ring = make([]Event, 0, n)
+		}
+	}
+}
+
+// WithSubscriberBuffer sets how many events a subscription may lag behind
+// before it is dropped.
+func WithSubscriberBuffer(n int) BrokerOption {
+	return func(b *Broker) {
+		if n > 0 {
+			b.subscriberBuffer = n
+		}
+	}
+}
+
+func NewBroker(opts ...BrokerOption) *Broker {
+	b := &Broker{
+		// Starting from the clock keeps ids growing across restarts, so an id
+		// from a previous process is reported as missed instead of replaying
+		// unrelated events.
+		lastID:           uint64(time.Now().UnixMicro()),
+		ring:             make([]Event, 0, defaultBufferSize),
+		subs:             map[*Subscription]struct{}{},
+		subscriberBuffer: defaultSubscriberBuffer,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish assigns the next id to an event of topic and sends it to every
+// matching subscription. Subscriptions with a full buffer are closed.
+func (b *Broker) Publish(topic, eventType string, data []byte) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e := Event{ID: b.lastID, Topic: topic, Type: eventType, Data: data}
+	if len(b.ring) < cap(b.ring) {
+		b.ring = append(b.ring, e)
+	} else {
+		b.ring[b.next] = e
+		b.next = (b.next + 1) % len(b.ring)
+	}
+
+	for s := range b.subs {
+		if !s.wants(topic) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			droppedTotal.Inc()
+			b.remove(s)
+		}
+	}
+	return e
+}
+
+// Subscribe opens a subscription to topics; no topics means every topic.
+// With resume set, buffered events after lastID are replayed first, unless
+// some of them are no longer buffered, which the subscription reports as
+// Missed.
+func (b *Broker) Subscribe(topics []string, lastID uint64, resume bool) *Subscription {
+	s := &Subscription{broker: b}
+	if len(topics) > 0 {
+		s.topics = make(map[string]struct{}, len(topics))
+		for _, t := range topics {
+			s.topics[t] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	if resume && lastID != b.lastID {
+		// An id from the future comes from another process.
+		s.Missed = lastID > b.lastID || len(b.ring) == 0 || lastID+1 < b.ring[b.next].ID
+		if !s.Missed {
+			backlog = b.since(lastID, s)
+		}
+	}
+	s.ch = make(chan Event, max(b.subscriberBuffer, len(backlog)))
+	for _, e := range backlog {
+		s.ch <- e
+	}
+	s.C = s.ch
+	b.subs[s] = struct{}{}
+	subscribersGauge.Inc()
+	return s
+}
+
+// since returns the buffered events after lastID, oldest first. b.next is
+// the oldest slot once the ring is full and zero until then.
+func (b *Broker) since(lastID uint64, s *Subscription) []Event {
+	var events []Event
+	for i := range b.ring {
+		e := b.ring[(b.next+i)%len(b.ring)]
+		if e.ID > lastID && s.wants(e.Topic) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	close(s.ch)
+	subscribersGauge.Dec()
+}
+
+// Subscription receives events on C until it is closed. C is also closed
+// when the subscriber falls too far behind.
+type Subscription struct {
+	C      <-chan Event
+	Missed bool
+	ch     chan Event
+	topics map[string]struct{}
+	broker *Broker
+}
+
+func (s *Subscription) wants(topic string) bool {
+	if s.topics == nil {
+		return true
+	}
+	_, ok := s.topics[topic]
+	return ok
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
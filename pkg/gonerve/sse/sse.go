@@ -0,0 +1,64 @@
+// Package sse implements server-sent events: an in-process broker with a
+// replay buffer and the wire format.
+package sse
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type Event struct {
+	// ID is zero for events that should not move the client's Last-Event-ID,
+	// such as snapshots.
+	ID    uint64
+	Topic string
+	Type  string
+	Data  []byte
+}
+
+// SetHeaders prepares a response for an event stream.
+func SetHeaders(h http.Header) {
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+}
+
+// Write writes e in the event stream format.
+func Write(w io.Writer, e Event) error {
+	var buf bytes.Buffer
+	if e.ID != 0 {
+		buf.WriteString("id: " + strconv.FormatUint(e.ID, 10) + "\n")
+	}
+	if e.Type != "" {
+		buf.WriteString("event: " + e.Type + "\n")
+	}
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteComment writes a comment line, which clients ignore. It keeps idle
+// connections from being closed by proxies.
+func WriteComment(w io.Writer, text string) error {
+	_, err := io.WriteString(w, ": "+text+"\n\n")
+	return err
+}
+
+// ParseLastEventID parses the Last-Event-ID request header. ok is false
+// when the header is missing or was not produced by a Broker.
+func ParseLastEventID(h http.Header) (id uint64, ok bool) {
+	v := h.Get("Last-Event-ID")
+	if v == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,99 @@
+package sse
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func drain(s *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case e, ok := <-s.C:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestBrokerFansOutByTopic(t *testing.T) {
+	b := NewBroker()
+	all := b.Subscribe(nil, 0, false)
+	defer all.Close()
+	one := b.Subscribe([]string{"a"}, 0, false)
+	defer one.Close()
+
+	b.Publish("a", "stock", []byte("1"))
+	b.Publish("b", "stock", []byte("2"))
+
+	require.Len(t, drain(all), 2)
+	got := drain(one)
+	require.Len(t, got, 1)
+	require.Equal(t, "a", got[0].Topic)
+}
+
+func TestBrokerResumesFromRingBuffer(t *testing.T) {
+	b := NewBroker(WithBufferSize(3))
+	first := b.Publish("a", "stock", []byte("1"))
+	for i := 0; i < 3; i++ {
+		b.Publish("a", "stock", []byte("x"))
+	}
+
+	s := b.Subscribe([]string{"a"}, first.ID+1, true)
+	require.False(t, s.Missed)
+	got := drain(s)
+	require.Len(t, got, 2)
+	require.Equal(t, first.ID+2, got[0].ID)
+	s.Close()
+
+	s = b.Subscribe(nil, first.ID-1, true)
+	require.True(t, s.Missed, "evicted events cannot be replayed")
+	require.Empty(t, drain(s))
+	s.Close()
+
+	s = b.Subscribe(nil, first.ID+100, true)
+	require.True(t, s.Missed, "ids from another process are unknown")
+	s.Close()
+
+	s = b.Subscribe(nil, first.ID+3, true)
+	require.False(t, s.Missed)
+	require.Empty(t, drain(s))
+	s.Close()
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(WithSubscriberBuffer(1))
+	s := b.Subscribe(nil, 0, false)
+	b.Publish("a", "stock", nil)
+	b.Publish("a", "stock", nil)
+
+	require.Len(t, drain(s), 1)
+	_, ok := <-s.C
+	require.False(t, ok)
+	s.Close()
+}
+
+func TestWriteFormatsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, Event{ID: 7, Type: "stock", Data: []byte("a\nb")}))
+	require.Equal(t, "id: 7\nevent: stock\ndata: a\ndata: b\n\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, Event{Data: []byte("{}")}))
+	require.Equal(t, "data: {}\n\n", buf.String())
+
+	h := http.Header{}
+	_, ok := ParseLastEventID(h)
+	require.False(t, ok)
+	h.Set("Last-Event-ID", "42")
+	id, ok := ParseLastEventID(h)
+	require.True(t, ok)
+	require.Equal(t, uint64(42), id)
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEchoMiddlewarePropagatesTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	InitWithProvider("stockpilot-tests", tp)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		tracer = noopTracer{}
+		provider = nil
+	})
+
+	e := echo.New()
+	e.Use(EchoMiddleware)
+	e.GET("/api/v1/products/:id", func(c echo.Context) error {
+		ctx := StartSpan(c.Request().Context(), "ProductService.GetByID")
+		EndSpan(ctx)
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/products/p1", http.NoBody)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+
+	require.Equal(t, "GET /api/v1/products/:id", server.Name)
+	require.Equal(t, trace.SpanKindServer, server.SpanKind)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	require.True(t, server.Parent.IsRemote())
+	require.Equal(t, "/api/v1/products/:id", spanAttr(server, "http.route").AsString())
+	require.Equal(t, int64(http.StatusInternalServerError), spanAttr(server, "http.status_code").AsInt64())
+	require.Equal(t, codes.Error, server.Status.Code)
+
+	require.Equal(t, "ProductService.GetByID", child.Name)
+	require.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+	require.False(t, child.EndTime.IsZero())
+}
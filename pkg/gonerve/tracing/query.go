@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	queryTracerName   = "stockpilot/pgx"
+	maxStatementBytes = 2048
+)
+
+// QueryTracer implements pgx.QueryTracer, pgx.BatchTracer and pgx.CopyFromTracer,
+// creating a child span for every statement sent to PostgreSQL.
+type QueryTracer struct {
+	tracer trace.Tracer
+}
+
+type queryStartKey struct{}
+
+func NewQueryTracer() *QueryTracer {
+	return &QueryTracer{tracer: otel.Tracer(queryTracerName)}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, "db.query", data.SQL, attribute.Int("db.args", len(data.Args)))
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.end(ctx, data.Err, attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+func (t *QueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	size := 0
+	if data.Batch != nil {
+		size = data.Batch.Len()
+	}
+	ctx, _ = t.tracer.Start(ctx, "db.batch", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.Int("db.batch.size", size),
+	))
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (t *QueryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	attrs := []attribute.KeyValue{
+		attribute.String("db.statement", NormalizeStatement(data.SQL)),
+		attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	span.AddEvent("db.batch.query", trace.WithAttributes(attrs...))
+}
+
+func (t *QueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, data.Err)
+}
+
+func (t *QueryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return t.start(ctx, "db.copy_from", "COPY "+data.TableName.Sanitize()+" ("+strings.Join(data.ColumnNames, ", ")+") FROM STDIN")
+}
+
+func (t *QueryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	t.end(ctx, data.Err, attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+func (t *QueryTracer) TraceTxStart(ctx context.Context) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "db.transaction", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+	))
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (t *QueryTracer) TraceTxEnd(ctx context.Context, err error) {
+	t.end(ctx, err)
+}
+
+func (t *QueryTracer) start(ctx context.Context, name, sql string, attrs ...attribute.KeyValue) context.Context {
+	statement := NormalizeStatement(sql)
+	attrs = append(attrs,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation(statement)),
+		attribute.String("db.statement", statement),
+	)
+	ctx, _ = t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (t *QueryTracer) end(ctx context.Context, err error, attrs ...attribute.KeyValue) {
+	span := trace.SpanFromContext(ctx)
+	if start, ok := ctx.Value(queryStartKey{}).(time.Time); ok {
+		attrs = append(attrs, attribute.Float64("db.duration_ms", float64(time.Since(start).Microseconds())/1000))
+	}
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// NormalizeStatement collapses whitespace of a SQL statement and caps its length.
+func NormalizeStatement(sql string) string {
+	s := strings.Join(strings.Fields(sql), " ")
+	if len(s) > maxStatementBytes {
+		s = s[:maxStatementBytes] + "..."
+	}
+	return s
+}
+
+func operation(statement string) string {
+	op, _, _ := strings.Cut(statement, " ")
+	return strings.ToUpper(op)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestQueryTracer() (*QueryTracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return &QueryTracer{tracer: tp.Tracer(queryTracerName)}, exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestQueryTracerRecordsStatement(t *testing.T) {
+	qt, exporter := newTestQueryTracer()
+
+	ctx := qt.TraceTxStart(context.Background())
+	qctx := qt.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+		SQL:  "\nUPDATE products\n\tSET quantity = quantity + $2\nWHERE id = $1\n",
+		Args: []any{"p1", -1},
+	})
+	qt.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1")})
+	qt.TraceTxEnd(ctx, nil)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	query, tx := spans[0], spans[1]
+	require.Equal(t, "db.query", query.Name)
+	require.Equal(t, "db.transaction", tx.Name)
+	require.Equal(t, tx.SpanContext.SpanID(), query.Parent.SpanID())
+	require.Equal(t, "UPDATE products SET quantity = quantity + $2 WHERE id = $1", spanAttr(query, "db.statement").AsString())
+	require.Equal(t, "UPDATE", spanAttr(query, "db.operation").AsString())
+	require.Equal(t, int64(1), spanAttr(query, "db.rows_affected").AsInt64())
+	require.Equal(t, attribute.FLOAT64, spanAttr(query, "db.duration_ms").Type())
+}
+
+func TestQueryTracerRecordsError(t *testing.T) {
+	qt, exporter := newTestQueryTracer()
+
+	ctx := qt.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	qt.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Len(t, spans[0].Events, 1)
+}
@@ -2,8 +2,12 @@ package tracing
 
 import (
 	"context"
+	"net/http"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
@@ -19,6 +23,7 @@ type Config struct {
 	SampleRatio float64 `mapstructure:"sample_ratio" json:"sample_ratio" yaml:"sample_ratio"`
 	Endpoint    string  `mapstructure:"endpoint" json:"endpoint" yaml:"endpoint"`
 	Insecure    bool    `mapstructure:"insecure" json:"insecure" yaml:"insecure"`
+	Queries     bool    `mapstructure:"queries" json:"queries" yaml:"queries"`
 }
 
 type Tracer interface {
@@ -37,8 +42,38 @@ func (noopTracer) EndSpan(ctx context.Context)
 
 var tracer Tracer = noopTracer{}
 var provider *sdktrace.TracerProvider
+var sampler = newRatioSampler(1)
+
+type ratioSampler struct {
+	ratio atomic.Value
+}
+
+func newRatioSampler(ratio float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(ratio)
+	return s
+}
+
+func (s *ratioSampler) set(ratio float64) {
+	s.ratio.Store(sdktrace.TraceIDRatioBased(ratio))
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.ratio.Load().(sdktrace.Sampler).ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	return s.ratio.Load().(sdktrace.Sampler).Description()
+}
+
+func SetSampleRatio(ratio float64) {
+	sampler.set(ratio)
+}
 
 func Init(cfg *Config) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
 	if cfg == nil || cfg.Endpoint == "" {
 		tracer = noopTracer{}
 		return nil
@@ -59,7 +94,7 @@ func Init(cfg *Config) error {
 		return err
 	}
 
-	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))
+	sampler.set(cfg.SampleRatio)
 	res, err := resource.New(
 		context.Background(),
 		resource.WithAttributes(
@@ -72,19 +107,27 @@ func Init(cfg *Config) error {
 	}
 
 	provider = sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sampler),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
 		sdktrace.WithResource(res),
 		sdktrace.WithBatcher(exporter),
 	)
 	otel.SetTracerProvider(provider)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{}, propagation.Baggage{},
-	))
 
 	tracer = otelTracer{tracer: provider.Tracer(cfg.Name)}
 	return nil
 }
 
+// InitWithProvider installs an already configured provider, e.g. one backed
+// by an in-memory exporter in tests.
+func InitWithProvider(name string, tp *sdktrace.TracerProvider) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	otel.SetTracerProvider(tp)
+	provider = tp
+	tracer = otelTracer{tracer: tp.Tracer(name)}
+}
+
 func Shutdown(ctx context.Context) error {
 	if provider == nil {
 		return nil
@@ -106,15 +149,34 @@ func Get() Tracer {
 
 func WithLoggerErrorHandler(logger interface{}) {}
 
-type queryTracer struct{}
+func (o otelTracer) StartSpan(ctx context.Context, name string, args ...any) context.Context {
+	attrs := make([]attribute.KeyValue, 0, len(args))
+	for _, arg := range args {
+		if kv, ok := arg.(attribute.KeyValue); ok {
+			attrs = append(attrs, kv)
+		}
+	}
+	ctx, _ = o.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx
+}
+
+func (otelTracer) EndSpan(ctx context.Context) {
+	trace.SpanFromContext(ctx).End()
+}
 
-func NewQueryTracer(t Tracer) any {
-	return queryTracer{}
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
 }
 
-func (o otelTracer) StartSpan(ctx context.Context, name string, args ...any) context.Context {
-	ctx, _ = o.tracer.Start(ctx, name)
-	return ctx
+func RecordError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
 }
 
-func (otelTracer) EndSpan(ctx context.Context) {}
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultSnippetSize = 512
+
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Result describes one delivery attempt. StatusCode is 0 when no response
+// was received.
+type Result struct {
+	StatusCode int
+	Latency    time.Duration
+	Snippet    string
+	Err        error
+}
+
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode <= 299
+}
+
+type Sender struct {
+	client      *http.Client
+	snippetSize int
+	now         func() time.Time
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client:      &http.Client{Timeout: timeout},
+		snippetSize: defaultSnippetSize,
+		now:         time.Now,
+	}
+}
+
+// Send POSTs a signed request and keeps the start of the response body.
+func (s *Sender) Send(ctx context.Context, req Request) Result {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	ts := s.now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "stockpilot-webhooks")
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, ts, req.Body))
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, req.DeliveryID)
+
+	start := time.Now()
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return Result{Latency: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+	snippet, err := io.ReadAll(io.LimitReader(resp.Body, int64(s.snippetSize)))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return Result{
+		StatusCode: resp.StatusCode,
+		Latency:    time.Since(start),
+		Snippet:    validUTF8(snippet),
+		Err:        err,
+	}
+}
+
+// validUTF8 drops a rune cut in half by the snippet limit, so the snippet
+// can be stored in a text column.
+func validUTF8(b []byte) string {
+	for len(b) > 0 && !utf8.Valid(b) {
+		b = b[:len(b)-1]
+	}
+	return string(b)
+}
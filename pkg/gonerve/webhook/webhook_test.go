@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"order.created"}`)
+	now := time.Unix(1700000000, 0)
+	sig := Sign("s3cret", now.Unix(), body)
+	require.True(t, strings.HasPrefix(sig, "sha256="))
+
+	require.NoError(t, Verify("s3cret", "1700000000", sig, body, 5*time.Minute, now.Add(time.Minute)))
+	require.ErrorIs(t, Verify("other", "1700000000", sig, body, 0, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("s3cret", "1700000001", sig, body, 0, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("s3cret", "1700000000", sig, []byte(`{}`), 0, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("s3cret", "1700000000", sig, body, 5*time.Minute, now.Add(time.Hour)), ErrStaleTimestamp)
+}
+
+func TestSenderSignsRequest(t *testing.T) {
+	body := []byte(`{"id":"e1"}`)
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		got, _ := io.ReadAll(r.Body)
+		err := Verify("s3cret", r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), got, time.Minute, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(strings.Repeat("ok", 400)))
+	}))
+	defer srv.Close()
+
+	sender := NewSender(time.Second)
+	res := sender.Send(context.Background(), Request{URL: srv.URL, Secret: "s3cret", Event: "order.created", DeliveryID: "d1", Body: body})
+	require.True(t, res.OK())
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	require.Equal(t, "order.created", headers.Get(HeaderEvent))
+	require.Equal(t, "d1", headers.Get(HeaderDelivery))
+	require.Len(t, res.Snippet, defaultSnippetSize)
+	require.Positive(t, res.Latency)
+
+	res = sender.Send(context.Background(), Request{URL: srv.URL, Secret: "wrong", Body: body})
+	require.False(t, res.OK())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}